@@ -5,7 +5,13 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/admin"
 	"github.com/cebuh/simpleHolidayPlaner/service/invite"
+	"github.com/cebuh/simpleHolidayPlaner/service/mail"
+	"github.com/cebuh/simpleHolidayPlaner/service/oidc"
+	"github.com/cebuh/simpleHolidayPlaner/service/profile"
+	"github.com/cebuh/simpleHolidayPlaner/service/storage"
 	"github.com/cebuh/simpleHolidayPlaner/service/team"
 	"github.com/cebuh/simpleHolidayPlaner/service/user"
 	"github.com/cebuh/simpleHolidayPlaner/service/vacation"
@@ -28,22 +34,46 @@ func (s *Server) Run() error {
 	router := mux.NewRouter()
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
+	mailer := mail.NewMailer()
+
 	userStore := user.NewStore(s.db)
-	userHandler := user.NewHandler(userStore)
+	teamStore := team.NewStore(s.db)
+	vacationStore := vacation.NewStore(s.db)
+	adminStore := admin.NewStore(s.db)
+	inviteStore := invite.NewStore(s.db)
+	userHandler := user.NewHandler(s.db, userStore, teamStore, vacationStore, adminStore, mailer)
 	userHandler.RegisterRoutes(subrouter)
 
-	teamStore := team.NewStore(s.db)
-	teamHandler := team.NewHandler(s.db, teamStore, userStore)
+	teamHandler := team.NewHandler(s.db, teamStore, userStore, vacationStore, inviteStore)
 	teamHandler.RegisterRoutes(subrouter)
 
-	inviteStore := invite.NewStore(s.db)
-	inviteHandler := invite.NewHandler(s.db, inviteStore, userStore, teamStore)
+	inviteHandler := invite.NewHandler(s.db, inviteStore, userStore, teamStore, adminStore, mailer)
 	inviteHandler.RegisterRoutes(subrouter)
 
-	vacationStore := vacation.NewStore(s.db)
-	vacationHandler := vacation.NewHandler(s.db, userStore, teamStore, vacationStore)
+	profileStore := profile.NewStore(s.db)
+	vacationHandler := vacation.NewHandler(s.db, userStore, teamStore, vacationStore, profileStore, mailer)
 	vacationHandler.RegisterRoutes(subrouter)
 
+	profileHandler := profile.NewHandler(s.db, userStore, teamStore, vacationStore, inviteStore, profileStore, mailer, storage.NewLocalStorage(config.Envs.AvatarStorageDir))
+	profileHandler.RegisterRoutes(subrouter)
+
+	admin.PromoteInitialSuperadmin(s.db, userStore, config.Envs.InitialSuperadminEmail)
+	adminHandler := admin.NewHandler(s.db, adminStore, adminStore, userStore, teamStore, mailer)
+	adminHandler.RegisterRoutes(subrouter)
+
+	// single sign-on is only offered if a provider is configured, local logins keep working
+	if config.Envs.OIDCIssuer != "" {
+		groupMappings, err := oidc.ParseGroupMappings(config.Envs.OIDCGroupMappings)
+		if err != nil {
+			return err
+		}
+
+		provider := oidc.NewProvider(config.Envs.OIDCIssuer, config.Envs.OIDCClientId, config.Envs.OIDCClientSecret,
+			config.Envs.OIDCRedirectUrl, config.Envs.OIDCGroupsClaim)
+		oidcHandler := oidc.NewHandler(s.db, provider, userStore, teamStore, groupMappings)
+		oidcHandler.RegisterRoutes(subrouter)
+	}
+
 	log.Println("Listen on ", s.address)
 	return http.ListenAndServe(s.address, router)
 }
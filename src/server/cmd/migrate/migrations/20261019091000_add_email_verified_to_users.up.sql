@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN emailVerifiedAt TIMESTAMP NULL;
+-- accounts which existed before the verification was introduced stay usable
+UPDATE users SET emailVerifiedAt = createdAt;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN failedLoginAttempts int NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN lockedUntil TIMESTAMP NULL;
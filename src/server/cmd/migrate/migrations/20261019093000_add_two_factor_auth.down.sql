@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS recovery_codes;
+ALTER TABLE users DROP COLUMN totpEnabled;
+ALTER TABLE users DROP COLUMN totpSecret;
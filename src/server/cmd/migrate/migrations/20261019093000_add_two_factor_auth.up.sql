@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN totpSecret varchar(64) NULL;
+ALTER TABLE users ADD COLUMN totpEnabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id UUID NOT NULL,
+    codeHash varchar(64) NOT NULL,
+    usedAt TIMESTAMP NULL,
+    CONSTRAINT recovery_codes_user foreign key (user_id) references users(id),
+    CONSTRAINT recovery_codes_unique UNIQUE (user_id, codeHash)
+);
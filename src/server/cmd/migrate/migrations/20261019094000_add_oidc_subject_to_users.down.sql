@@ -0,0 +1,2 @@
+ALTER TABLE users DROP CONSTRAINT users_oidc_subject_unique;
+ALTER TABLE users DROP COLUMN oidcSubject;
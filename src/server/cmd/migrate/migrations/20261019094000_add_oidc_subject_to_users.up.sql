@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN oidcSubject varchar(255) NULL;
+ALTER TABLE users ADD CONSTRAINT users_oidc_subject_unique UNIQUE (oidcSubject);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id UUID NOT NULL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name varchar(255) NOT NULL,
+    tokenHash varchar(64) NOT NULL,
+    scopes varchar(255) NOT NULL,
+    expiresAt TIMESTAMP NULL,
+    lastUsedAt TIMESTAMP NULL,
+    createdAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    CONSTRAINT access_tokens_user foreign key (user_id) references users(id),
+    CONSTRAINT access_tokens_hash_unique UNIQUE (tokenHash)
+);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN avatarKey varchar(255) NULL;
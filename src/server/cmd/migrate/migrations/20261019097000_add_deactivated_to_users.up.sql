@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN deactivatedAt TIMESTAMP NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id UUID NOT NULL PRIMARY KEY,
+    timezone varchar(64) NOT NULL DEFAULT 'UTC',
+    locale varchar(35) NOT NULL DEFAULT 'en',
+    firstDayOfWeek int NOT NULL DEFAULT 1,
+    holidayRegion varchar(16) NULL,
+    notifications TEXT NOT NULL,
+    changedAt TIMESTAMP NOT NULL DEFAULT UTC_TIMESTAMP,
+    CONSTRAINT user_preferences_user foreign key (user_id) references users(id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID NOT NULL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    userAgent varchar(512) NOT NULL DEFAULT '',
+    ip varchar(45) NOT NULL DEFAULT '',
+    createdAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    lastSeenAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    revokedAt TIMESTAMP NULL,
+    CONSTRAINT sessions_user foreign key (user_id) references users(id)
+);
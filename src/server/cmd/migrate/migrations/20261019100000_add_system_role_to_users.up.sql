@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN systemRole varchar(16) NOT NULL DEFAULT 'user';
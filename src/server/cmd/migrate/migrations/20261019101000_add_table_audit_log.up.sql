@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID NOT NULL PRIMARY KEY,
+    actor_id UUID NOT NULL,
+    action varchar(64) NOT NULL,
+    target_id UUID NULL,
+    details TEXT NOT NULL,
+    createdAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    CONSTRAINT audit_log_actor foreign key (actor_id) references users(id),
+    INDEX audit_log_created (createdAt)
+);
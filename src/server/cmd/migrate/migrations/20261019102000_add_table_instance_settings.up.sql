@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS instance_settings (
+    id INT NOT NULL PRIMARY KEY,
+    registrationEnabled BOOLEAN NOT NULL DEFAULT TRUE,
+    allowedEmailDomains varchar(1024) NOT NULL DEFAULT '',
+    changedAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS team_history (
+    id UUID NOT NULL PRIMARY KEY,
+    team_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    action varchar(64) NOT NULL,
+    user_id UUID NULL,
+    details TEXT NOT NULL,
+    createdAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    CONSTRAINT team_history_team foreign key (team_id) references teams(id),
+    CONSTRAINT team_history_actor foreign key (actor_id) references users(id),
+    INDEX team_history_team_created (team_id, createdAt)
+);
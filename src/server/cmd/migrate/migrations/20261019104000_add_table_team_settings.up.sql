@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS team_settings (
+    team_id UUID NOT NULL PRIMARY KEY,
+    document TEXT NOT NULL,
+    changedAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    CONSTRAINT team_settings_team foreign key (team_id) references teams(id)
+);
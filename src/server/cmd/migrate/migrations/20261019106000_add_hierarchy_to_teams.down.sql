@@ -0,0 +1,4 @@
+ALTER TABLE teams
+    DROP FOREIGN KEY teams_parent,
+    DROP COLUMN unitType,
+    DROP COLUMN parentId;
@@ -0,0 +1,4 @@
+ALTER TABLE teams
+    ADD COLUMN parentId UUID NULL,
+    ADD COLUMN unitType varchar(16) NOT NULL DEFAULT 'team',
+    ADD CONSTRAINT teams_parent foreign key (parentId) references teams(id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS team_settings_versions;
+
+ALTER TABLE team_settings
+    DROP COLUMN changedBy,
+    DROP COLUMN version;
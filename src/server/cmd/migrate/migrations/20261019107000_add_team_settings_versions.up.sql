@@ -0,0 +1,13 @@
+ALTER TABLE team_settings
+    ADD COLUMN version int NOT NULL DEFAULT 1,
+    ADD COLUMN changedBy UUID NULL;
+
+CREATE TABLE IF NOT EXISTS team_settings_versions (
+    team_id UUID NOT NULL,
+    version int NOT NULL,
+    document TEXT NOT NULL,
+    changedBy UUID NULL,
+    createdAt TIMESTAMP not null DEFAULT UTC_TIMESTAMP,
+    PRIMARY KEY (team_id, version),
+    CONSTRAINT team_settings_versions_team foreign key (team_id) references teams(id)
+);
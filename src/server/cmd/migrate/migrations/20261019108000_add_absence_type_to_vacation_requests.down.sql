@@ -0,0 +1 @@
+ALTER TABLE vacation_requests DROP COLUMN absenceType;
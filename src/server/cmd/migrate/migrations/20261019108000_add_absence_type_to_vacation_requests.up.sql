@@ -0,0 +1 @@
+ALTER TABLE vacation_requests ADD COLUMN absenceType varchar(16) not null default 'vacation';
@@ -0,0 +1,4 @@
+ALTER TABLE invites DROP INDEX invites_open_unique;
+ALTER TABLE invites DROP COLUMN openKey;
+ALTER TABLE invites DROP COLUMN expiresAt;
+ALTER TABLE invites ADD CONSTRAINT invites_unique UNIQUE (fromUserId, toUserId, teamId);
@@ -0,0 +1,13 @@
+ALTER TABLE invites ADD COLUMN expiresAt TIMESTAMP NULL;
+ALTER TABLE invites DROP INDEX invites_unique;
+
+-- only the newest open invite of a user to a team stays open
+UPDATE invites i
+    INNER JOIN invites newer ON newer.toUserId = i.toUserId AND newer.teamId = i.teamId AND newer.status = 0 AND newer.createdAt > i.createdAt
+    SET i.status = 3, i.changedAt = UTC_TIMESTAMP
+    WHERE i.status = 0;
+UPDATE invites SET expiresAt = UTC_TIMESTAMP + INTERVAL 14 DAY WHERE status = 0;
+
+-- closed invites have no key, so any number of them can exist next to the open one
+ALTER TABLE invites ADD COLUMN openKey varchar(80) AS (IF(status = 0, CONCAT(toUserId, ':', teamId), NULL)) PERSISTENT;
+ALTER TABLE invites ADD CONSTRAINT invites_open_unique UNIQUE (openKey);
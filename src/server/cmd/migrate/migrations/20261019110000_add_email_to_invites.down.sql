@@ -0,0 +1,7 @@
+DELETE FROM invites WHERE toUserId IS NULL;
+ALTER TABLE invites DROP INDEX invites_open_unique;
+ALTER TABLE invites DROP COLUMN openKey;
+ALTER TABLE invites DROP COLUMN email;
+ALTER TABLE invites MODIFY toUserId UUID NOT NULL;
+ALTER TABLE invites ADD COLUMN openKey varchar(80) AS (IF(status = 0, CONCAT(toUserId, ':', teamId), NULL)) PERSISTENT;
+ALTER TABLE invites ADD CONSTRAINT invites_open_unique UNIQUE (openKey);
@@ -0,0 +1,8 @@
+ALTER TABLE invites DROP INDEX invites_open_unique;
+ALTER TABLE invites DROP COLUMN openKey;
+ALTER TABLE invites MODIFY toUserId UUID NULL;
+ALTER TABLE invites ADD COLUMN email varchar(255) NULL;
+
+-- people without an account are invited by their email address until they register
+ALTER TABLE invites ADD COLUMN openKey varchar(300) AS (IF(status = 0, CONCAT(COALESCE(toUserId, email), ':', teamId), NULL)) PERSISTENT;
+ALTER TABLE invites ADD CONSTRAINT invites_open_unique UNIQUE (openKey);
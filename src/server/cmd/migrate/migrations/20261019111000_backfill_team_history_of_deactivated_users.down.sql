@@ -0,0 +1,3 @@
+DELETE FROM team_history WHERE source = 'backfill';
+
+ALTER TABLE team_history DROP COLUMN source;
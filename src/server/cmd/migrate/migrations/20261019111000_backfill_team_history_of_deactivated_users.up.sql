@@ -0,0 +1,9 @@
+ALTER TABLE team_history ADD COLUMN source varchar(16) NULL;
+
+-- deactivated users were removed from their teams without a history entry, the teams
+-- of their vacation requests are the only record of their former memberships. The
+-- entries are marked as backfilled, nobody knows who removed the users.
+INSERT INTO team_history (id, team_id, actor_id, action, user_id, details, createdAt, source)
+SELECT UUID(), former.teamId, former.requestedFrom, 'member_removed', former.requestedFrom, 'null', former.deactivatedAt, 'backfill'
+FROM (SELECT DISTINCT vr.teamId, vr.requestedFrom, u.deactivatedAt FROM vacation_requests vr
+      JOIN users u ON u.id = vr.requestedFrom WHERE u.deactivatedAt IS NOT NULL) former;
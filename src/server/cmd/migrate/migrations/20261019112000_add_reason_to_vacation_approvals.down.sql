@@ -0,0 +1 @@
+ALTER TABLE vacation_approvals DROP COLUMN reason;
@@ -0,0 +1 @@
+ALTER TABLE vacation_approvals ADD COLUMN reason varchar(255) NULL;
@@ -6,6 +6,7 @@ import (
 	"log"
 	"math/rand"
 	"strconv"
+	"time"
 
 	"github.com/cebuh/simpleHolidayPlaner/config"
 	"github.com/cebuh/simpleHolidayPlaner/db"
@@ -59,6 +60,7 @@ func createUsersAndTeams(db *sql.DB) {
 	generatedUsers := make([]types.User, 0)
 	generatedTeams := make([]types.Team, 0)
 
+	verifiedAt := time.Now().UTC()
 	for i := 0; i < 20; i++ {
 		testpw, err := auth.HashPassword("password" + strconv.Itoa(i))
 		if err != nil {
@@ -66,12 +68,13 @@ func createUsersAndTeams(db *sql.DB) {
 		}
 
 		user := types.User{
-			Id:       uuid.NewString(),
-			Name:     "user" + strconv.Itoa(i),
-			Email:    "user" + strconv.Itoa(i) + "@seed.com",
-			Password: testpw,
+			Id:              uuid.NewString(),
+			Name:            "user" + strconv.Itoa(i),
+			Email:           "user" + strconv.Itoa(i) + "@seed.com",
+			Password:        testpw,
+			EmailVerifiedAt: &verifiedAt,
 		}
-		if err := userStore.CreateUser(user); err != nil {
+		if err := userStore.CreateUser(db, user); err != nil {
 			panic(err)
 		}
 
@@ -97,7 +100,7 @@ func createUsersAndTeams(db *sql.DB) {
 			Id:   uuid.NewString(),
 			Name: name,
 		}
-		if err := teamStore.CreateTeam(team); err != nil {
+		if err := teamStore.CreateTeam(db, team); err != nil {
 			panic(err)
 		}
 
@@ -6,16 +6,54 @@ import (
 )
 
 type Config struct {
-	JWTExpireTimeInSeconds int64
-	JWTSecret              string
+	JWTExpireTimeInSeconds               int64
+	JWTSecret                            string
+	AppBaseUrl                           string
+	EmailVerificationExpireTimeInSeconds int64
+	SMTPHost                             string
+	SMTPPort                             int64
+	SMTPUser                             string
+	SMTPPassword                         string
+	MailFrom                             string
+	OIDCIssuer                           string
+	OIDCClientId                         string
+	OIDCClientSecret                     string
+	OIDCRedirectUrl                      string
+	OIDCGroupsClaim                      string
+	OIDCGroupMappings                    string
+	PasswordMinLength                    int64
+	PasswordBreachedListFile             string
+	PasswordHashAlgorithm                string
+	AvatarStorageDir                     string
+	SessionCacheSeconds                  int64
+	InitialSuperadminEmail               string
 }
 
 var Envs = initConfig()
 
 func initConfig() Config {
 	return Config{
-		JWTSecret:              getEnv("JWT_SECRET", "XAXAXAXA"),
-		JWTExpireTimeInSeconds: getEnvAsInt("JWT_EXPIRE_TIME_IN_SECONDS", 3600*24*7),
+		JWTSecret:                            getEnv("JWT_SECRET", "XAXAXAXA"),
+		JWTExpireTimeInSeconds:               getEnvAsInt("JWT_EXPIRE_TIME_IN_SECONDS", 3600*24*7),
+		AppBaseUrl:                           getEnv("APP_BASE_URL", "http://localhost:8080"),
+		EmailVerificationExpireTimeInSeconds: getEnvAsInt("EMAIL_VERIFICATION_EXPIRE_TIME_IN_SECONDS", 3600*24),
+		SMTPHost:                             getEnv("SMTP_HOST", ""),
+		SMTPPort:                             getEnvAsInt("SMTP_PORT", 587),
+		SMTPUser:                             getEnv("SMTP_USER", ""),
+		SMTPPassword:                         getEnv("SMTP_PASSWORD", ""),
+		MailFrom:                             getEnv("MAIL_FROM", "noreply@simpleholidayplaner.local"),
+		OIDCIssuer:                           getEnv("OIDC_ISSUER", ""),
+		OIDCClientId:                         getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:                     getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectUrl:                      getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/oidc/callback"),
+		OIDCGroupsClaim:                      getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCGroupMappings:                    getEnv("OIDC_GROUP_MAPPINGS", ""),
+		PasswordMinLength:                    getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordBreachedListFile:             getEnv("PASSWORD_BREACHED_LIST_FILE", ""),
+		PasswordHashAlgorithm:                getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		AvatarStorageDir:                     getEnv("AVATAR_STORAGE_DIR", "./data/avatars"),
+		SessionCacheSeconds:                  getEnvAsInt("SESSION_CACHE_SECONDS", 30),
+		InitialSuperadminEmail:               getEnv("INITIAL_SUPERADMIN_EMAIL", ""),
 	}
 }
 
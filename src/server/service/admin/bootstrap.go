@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// PromoteInitialSuperadmin gives the configured user the superadmin role, so a new
+// instance can be administrated. Further superadmins are appointed through the API.
+func PromoteInitialSuperadmin(db *sql.DB, userStore types.UserStore, email string) {
+	if email == "" {
+		return
+	}
+
+	u, err := userStore.GetUserByEmail(email)
+	if err != nil {
+		log.Printf("initial superadmin %s is not registered yet", email)
+		return
+	}
+
+	if u.SystemRole == types.SystemRoleSuperadmin {
+		return
+	}
+
+	// otherwise anybody could register with the address and become superadmin
+	if u.EmailVerifiedAt == nil {
+		log.Printf("initial superadmin %s has not verified the email address yet", email)
+		return
+	}
+
+	if err := userStore.SetSystemRole(db, u.Id, types.SystemRoleSuperadmin); err != nil {
+		log.Printf("error while promoting %s to superadmin: %v", email, err)
+		return
+	}
+
+	log.Printf("promoted %s to superadmin", email)
+}
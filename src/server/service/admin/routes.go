@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/mail"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const pageSize = 50
+
+// Handler serves the administration of the whole instance, which is only
+// available for superadmins. All changes are written to the audit log.
+type Handler struct {
+	db            *sql.DB
+	auditStore    types.AuditStore
+	settingsStore types.SettingsStore
+	userStore     types.UserStore
+	teamStore     types.TeamStore
+	mailer        types.Mailer
+}
+
+func NewHandler(db *sql.DB, auditStore types.AuditStore, settingsStore types.SettingsStore, userStore types.UserStore,
+	teamStore types.TeamStore, mailer types.Mailer) *Handler {
+	return &Handler{db: db, auditStore: auditStore, settingsStore: settingsStore, userStore: userStore, teamStore: teamStore, mailer: mailer}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/users", auth.RequireSuperadmin(h.handleListUsers, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/users/{userId}", auth.RequireSuperadmin(h.handleUpdateUser, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/admin/users/{userId}/impersonate", auth.RequireSuperadmin(h.handleImpersonate, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/teams", auth.RequireSuperadmin(h.handleListTeams, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/teams", auth.RequireSuperadmin(h.handleCreateTeam, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/teams/{teamId}", auth.RequireSuperadmin(h.handleRenameTeam, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/admin/teams/{teamId}/members/{userId}", auth.RequireSuperadmin(h.handleAddTeamMember, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/admin/teams/{teamId}/members/{userId}", auth.RequireSuperadmin(h.handleRemoveTeamMember, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/admin/settings", auth.RequireSuperadmin(h.handleGetSettings, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/settings", auth.RequireSuperadmin(h.handleSaveSettings, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/admin/audit", auth.RequireSuperadmin(h.handleGetAuditLog, h.userStore)).Methods(http.MethodGet)
+}
+
+func (h *Handler) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := h.userStore.ListUsers(r.URL.Query().Get("query"), pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, map[string]any{"users": users, "page": page})
+}
+
+func (h *Handler) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := pathId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	var payload types.AdminUpdateUserPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	adminId := auth.GetUserIdFromContext(r.Context())
+	// this way there is always at least one superadmin left
+	if userId == adminId && payload.SystemRole != nil && *payload.SystemRole != types.SystemRoleSuperadmin {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("superadmins can't remove their own role"))
+		return
+	}
+
+	u, err := h.userStore.GetUserById(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	emailChanged := payload.Email != nil && !strings.EqualFold(*payload.Email, u.Email)
+	if emailChanged {
+		if _, err := h.userStore.GetUserByEmail(*payload.Email); err == nil {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", *payload.Email))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if payload.Name != nil {
+			if err := h.userStore.UpdateName(tx, u.Id, *payload.Name); err != nil {
+				return err
+			}
+			u.Name = *payload.Name
+		}
+
+		if payload.SystemRole != nil {
+			if err := h.userStore.SetSystemRole(tx, u.Id, *payload.SystemRole); err != nil {
+				return err
+			}
+			u.SystemRole = *payload.SystemRole
+		}
+
+		if emailChanged {
+			if err := h.userStore.UpdateEmail(tx, u.Id, *payload.Email); err != nil {
+				return err
+			}
+			u.Email = *payload.Email
+			u.EmailVerifiedAt = nil
+		}
+
+		if err := h.audit(tx, adminId, types.AuditUserUpdated, &u.Id, payload); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, types.NewPublicUser(u))
+		return nil
+	})
+
+	// the user can request another mail, if this one doesn't arrive
+	if committed && emailChanged {
+		if err := mail.SendVerificationMail(h.mailer, u); err != nil {
+			log.Printf("error while sending verification mail: %v", err)
+		}
+	}
+}
+
+// handleImpersonate hands out a read only token of the user, to see what the user sees
+func (h *Handler) handleImpersonate(w http.ResponseWriter, r *http.Request) {
+	userId, ok := pathId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	u, err := h.userStore.GetUserById(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if u.DeactivatedAt != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("deactivated users can't be impersonated"))
+		return
+	}
+
+	if u.SystemRole == types.SystemRoleSuperadmin {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("superadmins can't be impersonated"))
+		return
+	}
+
+	adminId := auth.GetUserIdFromContext(r.Context())
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		token, err := auth.StartImpersonation(h.userStore, tx, r, adminId, u.Id)
+		if err != nil {
+			return err
+		}
+
+		details := map[string]string{"ip": utils.ClientIP(r), "userAgent": r.UserAgent()}
+		if err := h.audit(tx, adminId, types.AuditUserImpersonated, &u.Id, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, map[string]string{"token": token})
+		return nil
+	})
+}
+
+func (h *Handler) audit(execable interface{}, actorId, action string, targetId *string, details any) error {
+	marshalled, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return h.auditStore.RecordAudit(execable, types.AuditEntry{
+		Id:       uuid.NewString(),
+		ActorId:  actorId,
+		Action:   action,
+		TargetId: targetId,
+		Details:  string(marshalled),
+	})
+}
+
+func pathId(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	id, ok := mux.Vars(r)[name]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing %s", name))
+		return "", false
+	}
+
+	if !utils.IsValidUUID(id) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return "", false
+	}
+
+	return id, true
+}
+
+func parsePage(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("page")
+	if value == "" {
+		return 1, true
+	}
+
+	page, err := strconv.Atoi(value)
+	if err != nil || page < 1 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("page must be a positive number"))
+		return 0, false
+	}
+
+	return page, true
+}
@@ -0,0 +1,676 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, handler *Handler, callerId, method, path string, body any) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	req, err := http.NewRequest(method, path, bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userStore := handler.userStore.(*mockUser)
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: callerId}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), callerId, uuid.NewString())
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(testHttp, req)
+	return testHttp
+}
+
+func usersWithRoles(users ...*types.User) *mockUser {
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		for _, u := range users {
+			if u.Id == id {
+				return u, nil
+			}
+		}
+		return nil, fmt.Errorf("user not found")
+	}
+	return userStore
+}
+
+func Test_AdminRoutes_Should_Require_Superadmin(t *testing.T) {
+	member := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleUser}
+	superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	userStore := usersWithRoles(member, superadmin)
+	userStore.ListUsersMock = func(query string, limit, offset int) ([]types.User, error) {
+		return []types.User{*member, *superadmin}, nil
+	}
+	handler := NewHandler(nil, &mockAdminStore{}, &mockAdminStore{}, userStore, &mockTeam{}, &mockMailer{})
+
+	require.Equal(t, http.StatusForbidden, serve(t, handler, member.Id, http.MethodGet, "/admin/users", nil).Code)
+	require.Equal(t, http.StatusOK, serve(t, handler, superadmin.Id, http.MethodGet, "/admin/users", nil).Code)
+}
+
+func Test_Impersonate(t *testing.T) {
+	superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	otherSuperadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	member := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleUser}
+	deactivatedAt := time.Now()
+	former := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleUser, DeactivatedAt: &deactivatedAt}
+
+	tests := []struct {
+		name   string
+		target *types.User
+		status int
+	}{
+		{"should hand out a token and write the audit log", member, http.StatusOK},
+		{"should fail for superadmins", otherSuperadmin, http.StatusForbidden},
+		{"should fail for deactivated users", former, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			var session *types.Session
+			var entries []types.AuditEntry
+			userStore := usersWithRoles(superadmin, otherSuperadmin, member, former)
+			userStore.CreateSessionMock = func(execable interface{}, s types.Session) error {
+				session = &s
+				return nil
+			}
+			auditStore := &mockAdminStore{}
+			auditStore.RecordAuditMock = func(execable interface{}, entry types.AuditEntry) error {
+				entries = append(entries, entry)
+				return nil
+			}
+			handler := NewHandler(db, auditStore, auditStore, userStore, &mockTeam{}, &mockMailer{})
+
+			testHttp := serve(t, handler, superadmin.Id, http.MethodPost, "/admin/users/"+tt.target.Id+"/impersonate", nil)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status != http.StatusOK {
+				require.Nil(t, session)
+				require.Empty(t, entries)
+				return
+			}
+
+			require.Equal(t, tt.target.Id, session.UserId)
+			require.Len(t, entries, 1)
+			require.Equal(t, types.AuditUserImpersonated, entries[0].Action)
+			require.Equal(t, superadmin.Id, entries[0].ActorId)
+			require.Equal(t, tt.target.Id, *entries[0].TargetId)
+		})
+	}
+}
+
+func Test_UpdateUser_Should_Fail_IfSuperadminRemovesOwnRole(t *testing.T) {
+	superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	handler := NewHandler(nil, &mockAdminStore{}, &mockAdminStore{}, usersWithRoles(superadmin), &mockTeam{}, &mockMailer{})
+
+	role := types.SystemRoleUser
+	testHttp := serve(t, handler, superadmin.Id, http.MethodPatch, "/admin/users/"+superadmin.Id, types.AdminUpdateUserPayload{SystemRole: &role})
+
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+}
+
+func Test_UpdateUser_Should_Return_PublicUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	member := &types.User{Id: uuid.NewString(), Name: "Chris", Email: "chris@email.com", Password: "$argon2id$secret-hash",
+		SystemRole: types.SystemRoleUser}
+	userStore := usersWithRoles(superadmin, member)
+	userStore.UpdateNameMock = func(execable interface{}, userId, name string) error { return nil }
+	auditStore := &mockAdminStore{}
+	auditStore.RecordAuditMock = func(execable interface{}, entry types.AuditEntry) error { return nil }
+	handler := NewHandler(db, auditStore, auditStore, userStore, &mockTeam{}, &mockMailer{})
+
+	name := "Alex"
+	testHttp := serve(t, handler, superadmin.Id, http.MethodPatch, "/admin/users/"+member.Id, types.AdminUpdateUserPayload{Name: &name})
+
+	require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	var updated map[string]any
+	require.NoError(t, json.NewDecoder(testHttp.Body).Decode(&updated))
+	require.Equal(t, map[string]any{"id": member.Id, "name": "Alex", "email": "chris@email.com"}, updated)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_CreateTeam_Should_AddAdministrator_AndWriteAuditLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	teamLead := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleUser}
+	var created types.Team
+	var administrator string
+	teamStore := &mockTeam{}
+	teamStore.GetTeamByNameMock = func(name string) (*types.Team, error) { return nil, fmt.Errorf("team not found") }
+	teamStore.CreateTeamMock = func(execable interface{}, team types.Team) error {
+		created = team
+		return nil
+	}
+	teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error {
+		require.Equal(t, types.Administrator, role)
+		require.Equal(t, created.Id, teamId)
+		administrator = userId
+		return nil
+	}
+	auditStore := &mockAdminStore{}
+	auditStore.RecordAuditMock = func(execable interface{}, entry types.AuditEntry) error {
+		require.Equal(t, types.AuditTeamCreated, entry.Action)
+		return nil
+	}
+	handler := NewHandler(db, auditStore, auditStore, usersWithRoles(superadmin, teamLead), teamStore, &mockMailer{})
+
+	testHttp := serve(t, handler, superadmin.Id, http.MethodPost, "/admin/teams",
+		types.AdminCreateTeamPayload{Name: "Support", AdministratorId: teamLead.Id})
+
+	require.Equal(t, http.StatusCreated, testHttp.Code, testHttp.Body.String())
+	require.Equal(t, "Support", created.Name)
+	require.Equal(t, teamLead.Id, administrator)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_AddTeamMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]types.UserRole
+		status  int
+	}{
+		{"should add the member with the role", map[string]types.UserRole{"userRole": types.Administrator}, http.StatusOK},
+		{"should fail without a role instead of adding an administrator", map[string]types.UserRole{}, http.StatusBadRequest},
+		{"should fail if the role is missing under its name", map[string]types.UserRole{"roleType": types.Member}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+			member := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleUser}
+			teamId := uuid.NewString()
+			var added *types.UserRole
+			teamStore := &mockTeam{}
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) {
+				return types.Member, fmt.Errorf("user is not a member of the team")
+			}
+			teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error {
+				added = &role
+				return nil
+			}
+			auditStore := &mockAdminStore{}
+			auditStore.RecordAuditMock = func(execable interface{}, entry types.AuditEntry) error { return nil }
+			handler := NewHandler(db, auditStore, auditStore, usersWithRoles(superadmin, member), teamStore, &mockMailer{})
+
+			testHttp := serve(t, handler, superadmin.Id, http.MethodPut, "/admin/teams/"+teamId+"/members/"+member.Id, tt.payload)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			if tt.status == http.StatusOK {
+				require.Equal(t, tt.payload["userRole"], *added)
+			} else {
+				require.Nil(t, added)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_RemoveTeamMember_Should_Fail_ForTheLastAdministrator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	superadmin := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleSuperadmin}
+	administrator := &types.User{Id: uuid.NewString(), SystemRole: types.SystemRoleUser}
+	teamId := uuid.NewString()
+	teamStore := &mockTeam{}
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) { return types.Administrator, nil }
+	teamStore.LockTeamAdministratorsMock = func(execable interface{}, teamId string) ([]string, error) {
+		return []string{administrator.Id}, nil
+	}
+	teamStore.RemoveUserFromTeamMock = func(execable interface{}, userId, teamId string) error {
+		t.Fatal("the last administrator must not be removed")
+		return nil
+	}
+	handler := NewHandler(db, &mockAdminStore{}, &mockAdminStore{}, usersWithRoles(superadmin, administrator), teamStore, &mockMailer{})
+
+	testHttp := serve(t, handler, superadmin.Id, http.MethodDelete, "/admin/teams/"+teamId+"/members/"+administrator.Id, nil)
+
+	require.Equal(t, http.StatusConflict, testHttp.Code, testHttp.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	superadmin := &types.User{Id: uuid.NewString(), Name: "Chris", Email: "chris@email.com", Password: "$argon2id$secret-hash",
+		SystemRole: types.SystemRoleSuperadmin}
+	userStore := usersWithRoles(superadmin)
+	userStore.ListUsersMock = func(query string, limit, offset int) ([]types.User, error) { return []types.User{*superadmin}, nil }
+	teamStore := &mockTeam{}
+	teamStore.GetAllTeamsMock = func(includeArchived bool) ([]types.Team, error) {
+		return []types.Team{{Id: uuid.NewString(), Name: "Support"}}, nil
+	}
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id, Name: "Support"}, nil }
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) {
+		return types.Member, fmt.Errorf("user is not a member of the team")
+	}
+	adminStore := &mockAdminStore{}
+	adminStore.GetInstanceSettingsMock = func() (*types.InstanceSettings, error) { return types.DefaultInstanceSettings(), nil }
+	adminStore.GetAuditLogMock = func(limit, offset int) ([]types.AuditEntry, error) { return []types.AuditEntry{}, nil }
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	handler := NewHandler(db, adminStore, adminStore, userStore, teamStore, &mockMailer{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: superadmin.Id}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), superadmin.Id, uuid.NewString())
+	require.NoError(t, err)
+
+	routecheck.CheckResponses(t, router, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+type mockAdminStore struct {
+	RecordAuditMock          func(execable interface{}, entry types.AuditEntry) error
+	GetAuditLogMock          func(limit, offset int) ([]types.AuditEntry, error)
+	GetInstanceSettingsMock  func() (*types.InstanceSettings, error)
+	SaveInstanceSettingsMock func(execable interface{}, settings types.InstanceSettings) error
+}
+
+func (m *mockAdminStore) RecordAudit(execable interface{}, entry types.AuditEntry) error {
+	return m.RecordAuditMock(execable, entry)
+}
+
+func (m *mockAdminStore) GetAuditLog(limit, offset int) ([]types.AuditEntry, error) {
+	return m.GetAuditLogMock(limit, offset)
+}
+
+func (m *mockAdminStore) GetInstanceSettings() (*types.InstanceSettings, error) {
+	return m.GetInstanceSettingsMock()
+}
+
+func (m *mockAdminStore) SaveInstanceSettings(execable interface{}, settings types.InstanceSettings) error {
+	return m.SaveInstanceSettingsMock(execable, settings)
+}
+
+type mockMailer struct {
+	sentTo []string
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.sentTo = append(m.sentTo, to)
+	return nil
+}
+
+type mockTeam struct {
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
+}
+
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
+}
+
+func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
+	return m.GetTeamByIdMock(id)
+}
+
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
+}
+
+func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
+	return m.GetTeamByNameMock(name)
+}
+
+func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.AddUserToTeamMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
+}
+
+func (m *mockTeam) RenameTeam(name, teamId string) error {
+	return nil
+}
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
+type mockUser struct {
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
+}
+
+func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
+	return m.GetUserByEmailMock(email)
+}
+func (m *mockUser) GetUserById(id string) (*types.User, error) {
+	return m.GetUserByIdMock(id)
+}
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
+}
+
+func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
+	return m.GetUsersFromTeamMock(teamId)
+}
+
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
+}
+
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
+}
+
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
+}
+
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
+}
+
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
+}
+
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
+}
+
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
+}
+
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+func (h *Handler) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.settingsStore.GetInstanceSettings()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, settings)
+}
+
+func (h *Handler) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
+	var settings types.InstanceSettings
+	if err := utils.ParseJson(r, &settings); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, settings) {
+		return
+	}
+
+	if settings.AllowedEmailDomains == nil {
+		settings.AllowedEmailDomains = []string{}
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.settingsStore.SaveInstanceSettings(tx, settings); err != nil {
+			return err
+		}
+
+		if err := h.audit(tx, auth.GetUserIdFromContext(r.Context()), types.AuditSettingsChanged, nil, settings); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, settings)
+		return nil
+	})
+}
+
+func (h *Handler) handleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.auditStore.GetAuditLog(pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, map[string]any{"entries": entries, "page": page})
+}
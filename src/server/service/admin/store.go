@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+// the instance settings are a single row
+const settingsId = 1
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) RecordAudit(execable interface{}, entry types.AuditEntry) error {
+	_, err := utils.Exec(execable, "INSERT INTO audit_log (id, actor_id, action, target_id, details) VALUES (?, ?, ?, ?, ?)",
+		entry.Id, entry.ActorId, entry.Action, entry.TargetId, entry.Details)
+	return err
+}
+
+func (s *Store) GetAuditLog(limit, offset int) ([]types.AuditEntry, error) {
+	rows, err := s.db.Query(`SELECT id, actor_id, action, target_id, details, createdAt FROM audit_log
+							ORDER BY createdAt DESC, id LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := make([]types.AuditEntry, 0)
+	for rows.Next() {
+		entry := types.AuditEntry{}
+		if err := rows.Scan(&entry.Id, &entry.ActorId, &entry.Action, &entry.TargetId, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetInstanceSettings returns the defaults until a superadmin saved the settings
+func (s *Store) GetInstanceSettings() (*types.InstanceSettings, error) {
+	rows, err := s.db.Query("SELECT registrationEnabled, allowedEmailDomains FROM instance_settings WHERE id = ?", settingsId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := types.DefaultInstanceSettings()
+	if !rows.Next() {
+		return settings, nil
+	}
+
+	var domains string
+	if err := rows.Scan(&settings.RegistrationEnabled, &domains); err != nil {
+		return nil, err
+	}
+
+	if domains != "" {
+		settings.AllowedEmailDomains = strings.Split(domains, ",")
+	}
+
+	return settings, nil
+}
+
+func (s *Store) SaveInstanceSettings(execable interface{}, settings types.InstanceSettings) error {
+	_, err := utils.Exec(execable, `INSERT INTO instance_settings (id, registrationEnabled, allowedEmailDomains) VALUES (?, ?, ?)
+						ON DUPLICATE KEY UPDATE registrationEnabled = VALUES(registrationEnabled),
+						allowedEmailDomains = VALUES(allowedEmailDomains), changedAt = UTC_TIMESTAMP`,
+		settingsId, settings.RegistrationEnabled, strings.Join(settings.AllowedEmailDomains, ","))
+	return err
+}
@@ -0,0 +1,213 @@
+package admin
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/team"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) handleListTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := h.teamStore.GetAllTeams(true)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, teams)
+}
+
+// handleCreateTeam creates a team for somebody else, who becomes its administrator
+func (h *Handler) handleCreateTeam(w http.ResponseWriter, r *http.Request) {
+	var payload types.AdminCreateTeamPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	if _, err := h.teamStore.GetTeamByName(payload.Name); err == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("team with name %s already exists", payload.Name))
+		return
+	}
+
+	if _, err := h.userStore.GetUserById(payload.AdministratorId); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user with id %s does not exists", payload.AdministratorId))
+		return
+	}
+
+	created := types.Team{Id: uuid.NewString(), Name: payload.Name, UnitType: types.UnitTeam}
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.teamStore.CreateTeam(tx, created); err != nil {
+			return err
+		}
+
+		if err := h.teamStore.AddUserToTeam(tx, payload.AdministratorId, created.Id, types.Administrator); err != nil {
+			return err
+		}
+
+		if err := h.audit(tx, auth.GetUserIdFromContext(r.Context()), types.AuditTeamCreated, &created.Id, payload); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusCreated, created)
+		return nil
+	})
+}
+
+func (h *Handler) handleRenameTeam(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := pathId(w, r, "teamId")
+	if !ok {
+		return
+	}
+
+	var payload types.RenameTeamPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	renamed, ok := team.WritableTeam(h.teamStore, w, teamId)
+	if !ok {
+		return
+	}
+
+	if err := h.teamStore.RenameTeam(payload.Name, renamed.Id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	details := map[string]string{"from": renamed.Name, "to": payload.Name}
+	if err := h.audit(h.db, auth.GetUserIdFromContext(r.Context()), types.AuditTeamRenamed, &renamed.Id, details); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleAddTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := pathId(w, r, "teamId")
+	if !ok {
+		return
+	}
+
+	userId, ok := pathId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	var payload types.AdminTeamMemberPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	if _, ok := team.WritableTeam(h.teamStore, w, teamId); !ok {
+		return
+	}
+
+	u, err := h.userStore.GetUserById(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if u.DeactivatedAt != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("deactivated users can't join teams"))
+		return
+	}
+
+	if _, err := h.teamStore.GetUserRoleInTeam(userId, teamId); err == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user is already a part of the team"))
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.teamStore.AddUserToTeam(tx, userId, teamId, *payload.RoleType); err != nil {
+			return err
+		}
+
+		details := map[string]any{"teamId": teamId, "roleType": *payload.RoleType}
+		if err := h.audit(tx, auth.GetUserIdFromContext(r.Context()), types.AuditTeamMemberAdded, &userId, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+func (h *Handler) handleRemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := pathId(w, r, "teamId")
+	if !ok {
+		return
+	}
+
+	userId, ok := pathId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	if _, ok := team.WritableTeam(h.teamStore, w, teamId); !ok {
+		return
+	}
+
+	role, err := h.teamStore.GetUserRoleInTeam(userId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	actorId := auth.GetUserIdFromContext(r.Context())
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if role == types.Administrator {
+			if err := team.KeepAnAdministrator(h.teamStore, tx, teamId, userId); err != nil {
+				return err
+			}
+		}
+
+		if err := h.teamStore.RemoveUserFromTeam(tx, userId, teamId); err != nil {
+			return err
+		}
+
+		err := h.teamStore.RecordTeamHistory(tx, types.TeamHistoryEntry{
+			Id:      uuid.NewString(),
+			TeamId:  teamId,
+			ActorId: actorId,
+			Action:  types.TeamHistoryMemberRemoved,
+			UserId:  &userId,
+			Details: "null",
+		})
+		if err != nil {
+			return err
+		}
+
+		details := map[string]string{"teamId": teamId}
+		if err := h.audit(tx, actorId, types.AuditTeamMemberRemoved, &userId, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
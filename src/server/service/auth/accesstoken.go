@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+const accessTokenPrefix = "shp_"
+
+// the last usage is only written again after this time, so busy scripts don't cause a write per request
+const accessTokenTouchInterval = time.Minute
+
+// GenerateAccessToken returns a new personal access token and the hash which is stored
+func GenerateAccessToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	token := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashAccessToken(token), nil
+}
+
+// HashAccessToken uses a fast hash, the tokens are random and long enough that
+// they can't be guessed from the hash.
+func HashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix)
+}
+
+func authenticateAccessToken(store types.UserStore, tokenString string, scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("route does not accept personal access tokens")
+	}
+
+	token, err := store.GetAccessTokenByHash(HashAccessToken(tokenString))
+	if err != nil {
+		return "", err
+	}
+
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return "", fmt.Errorf("token %s is expired", token.Id)
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(token.Scopes, scope) {
+			return "", fmt.Errorf("token %s is missing scope %s", token.Id, scope)
+		}
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > accessTokenTouchInterval {
+		if err := store.TouchAccessToken(token.Id); err != nil {
+			log.Printf("failed to update last usage of token %s: %v", token.Id, err)
+		}
+	}
+
+	return token.UserId, nil
+}
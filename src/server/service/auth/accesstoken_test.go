@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/google/uuid"
+)
+
+func TestRequireWithAccessToken(t *testing.T) {
+	token, hash, err := GenerateAccessToken()
+	if err != nil {
+		t.Fatalf("error creating access token: %v", err)
+	}
+
+	user := &types.User{Id: uuid.NewString()}
+	store := &mockAccessTokenStore{user: user, token: &types.PersonalAccessToken{
+		Id:        uuid.NewString(),
+		UserId:    user.Id,
+		TokenHash: hash,
+		Scopes:    []string{types.ScopeWriteVacations},
+	}}
+
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	request := func(h http.HandlerFunc) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	t.Run("should accept token with required scope", func(t *testing.T) {
+		if code := request(Require(handler, store, types.ScopeWriteVacations)); code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if !store.touched {
+			t.Error("expected last usage to be updated")
+		}
+	})
+
+	t.Run("should reject token without required scope", func(t *testing.T) {
+		if code := request(Require(handler, store, types.ScopeAdminTeams)); code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, code)
+		}
+	})
+
+	t.Run("should reject token on route without scopes", func(t *testing.T) {
+		if code := request(Require(handler, store)); code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, code)
+		}
+	})
+
+	t.Run("should reject token of deactivated user", func(t *testing.T) {
+		deactivatedAt := time.Now()
+		user.DeactivatedAt = &deactivatedAt
+		defer func() { user.DeactivatedAt = nil }()
+
+		if code := request(Require(handler, store, types.ScopeWriteVacations)); code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, code)
+		}
+	})
+}
+
+type mockAccessTokenStore struct {
+	types.UserStore
+	user    *types.User
+	token   *types.PersonalAccessToken
+	touched bool
+}
+
+func (m *mockAccessTokenStore) GetUserById(id string) (*types.User, error) {
+	if m.user == nil || m.user.Id != id {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return m.user, nil
+}
+
+func (m *mockAccessTokenStore) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	if m.token == nil || m.token.TokenHash != hash {
+		return nil, fmt.Errorf("token not found")
+	}
+
+	return m.token, nil
+}
+
+func (m *mockAccessTokenStore) TouchAccessToken(id string) error {
+	m.touched = true
+	return nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashAlgorithmBcrypt   string = "bcrypt"
+	HashAlgorithmArgon2id string = "argon2id"
+)
+
+// PasswordHasher creates and checks password hashes of one algorithm. The hashes
+// contain the algorithm and its parameters, so older hashes can still be checked
+// after the configuration was changed and are upgraded on the next login.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hashed string, plain []byte) bool
+	// Handles reports whether the hash was created by this algorithm
+	Handles(hashed string) bool
+	// NeedsRehash reports whether the hash was created with other parameters
+	NeedsRehash(hashed string) bool
+}
+
+func NewPasswordHasher(algorithm string) (PasswordHasher, error) {
+	switch algorithm {
+	case HashAlgorithmBcrypt:
+		return &bcryptHasher{cost: bcrypt.DefaultCost}, nil
+	case HashAlgorithmArgon2id:
+		return &argon2idHasher{memory: 64 * 1024, iterations: 3, parallelism: 2, saltLength: 16, keyLength: 32}, nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %s", algorithm)
+	}
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Compare(hashed string, plain []byte) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hashed), plain) == nil
+}
+
+func (h *bcryptHasher) Handles(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2")
+}
+
+func (h *bcryptHasher) NeedsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != h.cost
+}
+
+// argon2idHasher stores the hashes in the common PHC format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  int
+	keyLength   uint32
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, h.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *argon2idHasher) Compare(hashed string, plain []byte) bool {
+	params, salt, key, err := decodeArgon2idHash(hashed)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey(plain, salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (h *argon2idHasher) Handles(hashed string) bool {
+	return strings.HasPrefix(hashed, "$argon2id$")
+}
+
+func (h *argon2idHasher) NeedsRehash(hashed string) bool {
+	params, salt, key, err := decodeArgon2idHash(hashed)
+	if err != nil {
+		return true
+	}
+
+	return params.memory != h.memory || params.iterations != h.iterations || params.parallelism != h.parallelism ||
+		len(salt) != h.saltLength || uint32(len(key)) != h.keyLength
+}
+
+func decodeArgon2idHash(hashed string) (*argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+
+	params := &argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return params, salt, key, nil
+}
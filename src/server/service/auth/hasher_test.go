@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	for _, algorithm := range []string{HashAlgorithmBcrypt, HashAlgorithmArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewPasswordHasher(algorithm)
+			if err != nil {
+				t.Fatalf("error creating hasher: %v", err)
+			}
+
+			hash, err := hasher.Hash("password")
+			if err != nil {
+				t.Fatalf("error hashing password: %v", err)
+			}
+
+			if !hasher.Handles(hash) {
+				t.Errorf("expected hasher to handle its own hash %s", hash)
+			}
+			if !hasher.Compare(hash, []byte("password")) {
+				t.Error("expected password to match hash")
+			}
+			if hasher.Compare(hash, []byte("notpassword")) {
+				t.Error("expected password to not match hash")
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Error("expected hash with current parameters to not need a rehash")
+			}
+			if !ComparePasswords(hash, []byte("password")) {
+				t.Error("expected hash to be accepted independent of the configured algorithm")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHasher, _ := NewPasswordHasher(HashAlgorithmBcrypt)
+	bcryptHash, _ := bcryptHasher.Hash("password")
+	if !NeedsRehash(bcryptHash) {
+		t.Error("expected bcrypt hash to need a rehash, argon2id is configured")
+	}
+
+	hash, _ := HashPassword("password")
+	if NeedsRehash(hash) {
+		t.Error("expected current hash to not need a rehash")
+	}
+
+	weaker := strings.Replace(hash, "t=3", "t=1", 1)
+	if !NeedsRehash(weaker) {
+		t.Error("expected hash with other parameters to need a rehash")
+	}
+}
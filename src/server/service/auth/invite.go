@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// CreateInviteToken signs the invite together with the invited address. The token
+// expires with the invite, a resent invite gets a new token.
+func CreateInviteToken(secret []byte, inviteId, email string, expiresAt time.Time) (string, error) {
+	return createJWT(secret, PurposeInvite, time.Until(expiresAt), jwt.MapClaims{
+		"inviteID": inviteId,
+		"email":    email,
+	})
+}
+
+func ParseInviteToken(tokenString string) (string, string, error) {
+	claims, err := parseJWTWithPurpose(tokenString, PurposeInvite)
+	if err != nil {
+		return "", "", err
+	}
+
+	inviteId, _ := claims["inviteID"].(string)
+	email, _ := claims["email"].(string)
+	if inviteId == "" || email == "" {
+		return "", "", fmt.Errorf("invalid token")
+	}
+
+	return inviteId, email, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/cebuh/simpleHolidayPlaner/config"
@@ -15,26 +17,94 @@ import (
 
 const UserKey string = "userId"
 
-func CreateJWT(secret []byte, userId string) (string, error) {
+// Purposes a token can be issued for. Only access tokens are accepted by Require,
+// all other tokens are restricted to the routes which explicitly allow them.
+const (
+	PurposeAccess         string = "access"
+	PurposePasswordChange string = "password_change"
+	PurposeVerifyEmail    string = "verify_email"
+	PurposeTwoFactor      string = "two_factor"
+	PurposeInvite         string = "invite"
+)
 
-	expiration := time.Second * time.Duration(config.Envs.JWTExpireTimeInSeconds)
+// passwordChangeTTL limits how long a temporary password can be swapped for a new one
+// after the login
+const passwordChangeTTL = 15 * time.Minute
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    userId,
-		"expiredAt": time.Now().Add(expiration).Unix(),
-	})
+// CreatePasswordChangeToken issues the token for users who have to change their
+// password before they get an access token
+func CreatePasswordChangeToken(secret []byte, userId string) (string, error) {
+	return createJWT(secret, PurposePasswordChange, passwordChangeTTL, jwt.MapClaims{"userID": userId})
+}
+
+// createJWT issues a token for a single purpose, which expires after the given
+// duration. The "exp" claim is checked by the jwt library while parsing.
+func createJWT(secret []byte, purpose string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
 
-	tokenString, err := token.SignedString(secret)
+// accessTokenTTL is the configured lifetime of access tokens
+func accessTokenTTL() time.Duration {
+	return time.Second * time.Duration(config.Envs.JWTExpireTimeInSeconds)
+}
+
+func parseJWTWithPurpose(tokenString, purpose string) (jwt.MapClaims, error) {
+	token, err := validateToken(tokenString)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if purposeFromClaims(claims) != purpose {
+		return nil, fmt.Errorf("token was not issued for %s", purpose)
 	}
 
-	return tokenString, nil
+	return claims, nil
+}
+
+// Require authenticates the request with an access token. Personal access tokens
+// are only accepted if the route names the scopes it needs and the token has all of them.
+func Require(handlerFunc http.HandlerFunc, store types.UserStore, scopes ...string) http.HandlerFunc {
+	return requirePurpose(handlerFunc, store, scopes, PurposeAccess)
 }
 
-func Require(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
-	return func (w http.ResponseWriter, r *http.Request)  {
+// RequirePasswordChange accepts access tokens and the restricted tokens which
+// are handed out to users who have to change their password first.
+func RequirePasswordChange(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return requirePurpose(handlerFunc, store, nil, PurposeAccess, PurposePasswordChange)
+}
+
+func requirePurpose(handlerFunc http.HandlerFunc, store types.UserStore, scopes []string, allowed ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		tokenString := extractTokenFromRequest(r)
+		if IsAccessToken(tokenString) {
+			userId, err := authenticateAccessToken(store, tokenString, scopes)
+			if err != nil {
+				log.Printf("failed to validate personal access token: %v", err)
+				permissionDenied(w)
+				return
+			}
+
+			u, err := activeUser(store, userId)
+			if err != nil {
+				log.Printf("failed to authenticate personal access token: %v", err)
+				permissionDenied(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserKey, u.Id)
+			handlerFunc(w, r.WithContext(ctx))
+			return
+		}
+
 		token, err := validateToken(tokenString)
 
 		if err != nil {
@@ -50,22 +120,80 @@ func Require(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFu
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
-		userId := claims["userID"].(string)
-		u, err := store.GetUserById(userId)
+		purpose := purposeFromClaims(claims)
+		if !slices.Contains(allowed, purpose) {
+			log.Println("token is not allowed for this route")
+			permissionDenied(w)
+			return
+		}
+
+		userId, _ := claims["userID"].(string)
+		u, err := activeUser(store, userId)
 		if err != nil {
-			log.Printf("failed to get user by id: %v", err)
+			log.Printf("failed to authenticate token: %v", err)
+			permissionDenied(w)
+			return
+		}
+
+		// access tokens can only be revoked through their session
+		sessionId, _ := claims["sid"].(string)
+		if sessionId == "" && purpose == PurposeAccess {
+			log.Println("access token is not bound to a session")
+			permissionDenied(w)
+			return
+		}
+
+		if sessionId != "" {
+			if err := checkSession(store, sessionId, u.Id); err != nil {
+				log.Printf("failed to validate session: %v", err)
+				permissionDenied(w)
+				return
+			}
+		}
+
+		// impersonation is read only, changes would look like they were made by the user
+		impersonatorId, _ := claims["act"].(string)
+		if impersonatorId != "" && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			log.Printf("superadmin %s tried to change data while impersonating %s", impersonatorId, u.Id)
 			permissionDenied(w)
 			return
 		}
 
 		ctx := r.Context()
-		ctx  = context.WithValue(ctx, UserKey, u.Id)
+		ctx = context.WithValue(ctx, UserKey, u.Id)
+		ctx = context.WithValue(ctx, SessionKey, sessionId)
+		ctx = context.WithValue(ctx, ImpersonatorKey, impersonatorId)
 		r = r.WithContext(ctx)
 
-		handlerFunc(w,r)
+		handlerFunc(w, r)
 	}
 }
 
+// activeUser loads the owner of a token, deactivated users are rejected with
+// every kind of token
+func activeUser(store types.UserStore, userId string) (*types.User, error) {
+	u, err := store.GetUserById(userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %v", err)
+	}
+
+	if u.DeactivatedAt != nil {
+		return nil, fmt.Errorf("user %s is deactivated", u.Id)
+	}
+
+	return u, nil
+}
+
+// tokens issued before purposes were introduced are access tokens
+func purposeFromClaims(claims jwt.MapClaims) string {
+	purpose, ok := claims["purpose"].(string)
+	if !ok || purpose == "" {
+		return PurposeAccess
+	}
+
+	return purpose
+}
+
 func permissionDenied(w http.ResponseWriter){
 	utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
 }
@@ -73,7 +201,7 @@ func permissionDenied(w http.ResponseWriter){
 func extractTokenFromRequest(r *http.Request) (string) {
 	token := r.Header.Get("Authorization")
 	if token != "" {
-		return token
+		return strings.TrimPrefix(token, "Bearer ")
 	}
 
 	return ""
@@ -1,15 +1,22 @@
 package auth
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+
+	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 )
 
-func TestCreateJWT(t *testing.T) {
+func TestCreateSessionJWT(t *testing.T) {
 	secret := []byte("secret")
 
-	token, err := CreateJWT(secret, uuid.NewString())
+	token, err := CreateSessionJWT(secret, uuid.NewString(), uuid.NewString())
 	if err != nil {
 		t.Errorf("error creating JWT: %v", err)
 	}
@@ -18,3 +25,105 @@ func TestCreateJWT(t *testing.T) {
 		t.Error("expected token to be not empty")
 	}
 }
+
+func TestAccessTokenExpires(t *testing.T) {
+	token, err := CreateSessionJWT([]byte(config.Envs.JWTSecret), uuid.NewString(), uuid.NewString())
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	claims, err := parseJWTWithPurpose(token, PurposeAccess)
+	if err != nil {
+		t.Fatalf("error parsing JWT: %v", err)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		t.Error("expected the access token to expire")
+	}
+}
+
+func TestRequireRejectsAccessTokenWithoutSession(t *testing.T) {
+	userId := uuid.NewString()
+	store := &mockUserStore{user: &types.User{Id: userId}}
+	token, err := createJWT([]byte(config.Envs.JWTSecret), PurposeAccess, accessTokenTTL(), jwt.MapClaims{"userID": userId})
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	Require(handler, store)(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected access to be denied, got %d", rec.Code)
+	}
+}
+
+func TestRequireRejectsPasswordChangeToken(t *testing.T) {
+	userId := uuid.NewString()
+	store := &mockUserStore{user: &types.User{Id: userId}}
+	token, err := CreatePasswordChangeToken([]byte(config.Envs.JWTSecret), userId)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	claims, err := parseJWTWithPurpose(token, PurposePasswordChange)
+	if err != nil {
+		t.Fatalf("error parsing JWT: %v", err)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		t.Error("expected the password change token to expire")
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	Require(handler, store)(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected access to be denied, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	RequirePasswordChange(handler, store)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected password change route to accept the token, got %d", rec.Code)
+	}
+}
+
+type mockUserStore struct {
+	types.UserStore
+	user *types.User
+}
+
+func (m *mockUserStore) GetUserById(id string) (*types.User, error) {
+	return m.user, nil
+}
+
+func TestRequireRejectsDeactivatedUser(t *testing.T) {
+	userId := uuid.NewString()
+	deactivatedAt := time.Now()
+	sessionId := uuid.NewString()
+	store := &mockSessionStore{
+		mockUserStore: mockUserStore{user: &types.User{Id: userId, DeactivatedAt: &deactivatedAt}},
+		session:       &types.Session{Id: sessionId, UserId: userId},
+	}
+	token, err := CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, sessionId)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	Require(handler, store)(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected access to be denied, got %d", rec.Code)
+	}
+}
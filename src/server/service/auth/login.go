@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// LoginResponse is the answer to a login whose first factor was checked, by password
+// or by the identity provider. Users with two-factor authentication get a challenge
+// for their code instead of a token.
+func LoginResponse(store types.UserStore, execable interface{}, r *http.Request, u *types.User) (map[string]any, error) {
+	if u.TotpEnabled {
+		challengeToken, err := CreateTwoFactorChallengeToken([]byte(config.Envs.JWTSecret), u.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"challengeToken": challengeToken, "twoFactorRequired": true}, nil
+	}
+
+	return LoginTokenResponse(store, execable, r, u)
+}
+
+// LoginTokenResponse is the answer to a completed login. Users who have to change
+// their password only get a token which is restricted to the password change.
+func LoginTokenResponse(store types.UserStore, execable interface{}, r *http.Request, u *types.User) (map[string]any, error) {
+	if u.MustChangePassword {
+		token, err := CreatePasswordChangeToken([]byte(config.Envs.JWTSecret), u.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"token": token, "mustChangePassword": true}, nil
+	}
+
+	token, err := StartSession(store, execable, r, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{"token": token}, nil
+}
@@ -1,17 +1,99 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"log"
+	"math/big"
+	"sync"
 
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
+	"github.com/cebuh/simpleHolidayPlaner/config"
+)
+
+const passwordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var (
+	currentHasher     PasswordHasher
+	currentHasherOnce sync.Once
+)
+
+// passwordHasher returns the hasher for new hashes, which is chosen by the configuration
+func passwordHasher() PasswordHasher {
+	currentHasherOnce.Do(func() {
+		hasher, err := NewPasswordHasher(config.Envs.PasswordHashAlgorithm)
+		if err != nil {
+			log.Printf("%v, falling back to %s", err, HashAlgorithmArgon2id)
+			hasher, _ = NewPasswordHasher(HashAlgorithmArgon2id)
+		}
+		currentHasher = hasher
+	})
+
+	return currentHasher
+}
+
+// hasherFor finds the hasher which created the hash, so hashes stay valid after
+// the configured algorithm was changed
+func hasherFor(hashed string) PasswordHasher {
+	if current := passwordHasher(); current.Handles(hashed) {
+		return current
+	}
+
+	for _, algorithm := range []string{HashAlgorithmBcrypt, HashAlgorithmArgon2id} {
+		hasher, _ := NewPasswordHasher(algorithm)
+		if hasher.Handles(hashed) {
+			return hasher
+		}
 	}
 
-	return string(hash), nil
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	return passwordHasher().Hash(password)
 }
 
 func ComparePasswords(hashed string, plain []byte) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), plain)
-	return err == nil
+	hasher := hasherFor(hashed)
+	if hasher == nil {
+		return false
+	}
+
+	return hasher.Compare(hashed, plain)
+}
+
+// NeedsRehash reports whether the hash was not created with the configured algorithm
+// and parameters. It should be replaced after the next successful login.
+func NeedsRehash(hashed string) bool {
+	current := passwordHasher()
+	return !current.Handles(hashed) || current.NeedsRehash(hashed)
+}
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// CompareDummyPassword takes as long as ComparePasswords. It is used when no user
+// was found, so the response time doesn't reveal whether an account exists.
+func CompareDummyPassword(plain []byte) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("dummy password")
+	})
+
+	ComparePasswords(dummyHash, plain)
+}
+
+// GeneratePassword creates a random password, e.g. for accounts which are
+// created by an administrator. Similar looking characters are left out.
+func GeneratePassword(length int) (string, error) {
+	password := make([]byte, length)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+
+	return string(password), nil
 }
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+)
+
+//go:embed breached_passwords.txt
+var embeddedBreachedPasswords string
+
+// PasswordPolicy decides which passwords users may choose. Generated passwords
+// don't go through the policy.
+type PasswordPolicy struct {
+	MinLength int
+	breached  map[string]struct{}
+}
+
+// NewPasswordPolicy creates a policy with the embedded list of common passwords
+// and the passwords of the optional list file, one password per line.
+func NewPasswordPolicy(minLength int, breachedListFile string) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: minLength, breached: map[string]struct{}{}}
+	_ = policy.addBreached(strings.NewReader(embeddedBreachedPasswords))
+
+	if breachedListFile != "" {
+		file, err := os.Open(breachedListFile)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading breached password list: %w", err)
+		}
+		defer file.Close()
+
+		if err := policy.addBreached(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+func (p *PasswordPolicy) addBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Validate checks the password of the account with the given email address
+func (p *PasswordPolicy) Validate(password, email string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := p.breached[lower]; ok {
+		return fmt.Errorf("password is too common or appeared in a data breach")
+	}
+
+	email = strings.ToLower(email)
+	localPart, _, _ := strings.Cut(email, "@")
+	if email != "" && (strings.Contains(lower, email) || len(localPart) >= 3 && strings.Contains(lower, localPart)) {
+		return fmt.Errorf("password must not contain the email address")
+	}
+
+	return nil
+}
+
+var (
+	defaultPolicy     *PasswordPolicy
+	defaultPolicyOnce sync.Once
+)
+
+// ValidatePassword checks the password against the configured policy
+func ValidatePassword(password, email string) error {
+	defaultPolicyOnce.Do(func() {
+		policy, err := NewPasswordPolicy(int(config.Envs.PasswordMinLength), config.Envs.PasswordBreachedListFile)
+		if err != nil {
+			log.Printf("%v, using the embedded list only", err)
+			policy, _ = NewPasswordPolicy(int(config.Envs.PasswordMinLength), "")
+		}
+		defaultPolicy = policy
+	})
+
+	return defaultPolicy.Validate(password, email)
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(listFile, []byte("correcthorsebattery\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := NewPasswordPolicy(10, listFile)
+	if err != nil {
+		t.Fatalf("error creating policy: %v", err)
+	}
+
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"short", false},
+		{"Password123", false},
+		{"CorrectHorseBattery", false},
+		{"chris.miller-2026", false},
+		{"blue-tractor-evening", true},
+	}
+
+	for _, tt := range tests {
+		err := policy.Validate(tt.password, "Chris.Miller@example.com")
+		if tt.valid && err != nil {
+			t.Errorf("expected %q to be valid, got %v", tt.password, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("expected %q to be rejected", tt.password)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const SessionKey string = "sessionId"
+
+// StartSession records a new login of the user and returns an access token which
+// is bound to it
+func StartSession(store types.UserStore, execable interface{}, r *http.Request, userId string) (string, error) {
+	sessionId, err := createSession(store, execable, r, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, sessionId)
+}
+
+func createSession(store types.UserStore, execable interface{}, r *http.Request, userId string) (string, error) {
+	session := types.Session{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		UserAgent: truncate(r.UserAgent(), 512),
+		IP:        utils.ClientIP(r),
+	}
+
+	return session.Id, store.CreateSession(execable, session)
+}
+
+func CreateSessionJWT(secret []byte, userId, sessionId string) (string, error) {
+	return createJWT(secret, PurposeAccess, accessTokenTTL(), jwt.MapClaims{"userID": userId, "sid": sessionId})
+}
+
+func GetSessionIdFromContext(ctx context.Context) string {
+	sessionId, ok := ctx.Value(SessionKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return sessionId
+}
+
+// sessionCache remembers sessions which were found valid, so Require doesn't need to
+// load the session on every request. Revocations from other instances take effect
+// once the entry is expired.
+type sessionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedSession
+}
+
+type cachedSession struct {
+	userId    string
+	checkedAt time.Time
+}
+
+var sessions = newSessionCache(time.Second * time.Duration(config.Envs.SessionCacheSeconds))
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{ttl: ttl, entries: map[string]cachedSession{}}
+}
+
+func (c *sessionCache) valid(sessionId, userId string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[sessionId]
+	if !ok || now.Sub(entry.checkedAt) >= c.ttl {
+		delete(c.entries, sessionId)
+		return false
+	}
+
+	return entry.userId == userId
+}
+
+func (c *sessionCache) remember(sessionId, userId string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[sessionId] = cachedSession{userId: userId, checkedAt: now}
+}
+
+func (c *sessionCache) forget(sessionId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, sessionId)
+}
+
+func (c *sessionCache) forgetUser(userId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for sessionId, entry := range c.entries {
+		if entry.userId == userId {
+			delete(c.entries, sessionId)
+		}
+	}
+}
+
+// ForgetSession has to be called after a session was revoked, so this instance
+// rejects its tokens right away
+func ForgetSession(sessionId string) {
+	sessions.forget(sessionId)
+}
+
+func ForgetSessionsOfUser(userId string) {
+	sessions.forgetUser(userId)
+}
+
+// checkSession makes sure the session of a token was not revoked. The last activity
+// is written when the session is loaded, so at most once per cache period.
+func checkSession(store types.UserStore, sessionId, userId string) error {
+	now := time.Now()
+	if sessions.valid(sessionId, userId, now) {
+		return nil
+	}
+
+	session, err := store.GetSession(sessionId)
+	if err != nil {
+		return err
+	}
+
+	if session.UserId != userId {
+		return fmt.Errorf("session %s belongs to another user", sessionId)
+	}
+
+	if session.RevokedAt != nil {
+		return fmt.Errorf("session %s is revoked", sessionId)
+	}
+
+	if err := store.TouchSession(sessionId); err != nil {
+		log.Printf("failed to update last activity of session %s: %v", sessionId, err)
+	}
+
+	sessions.remember(sessionId, userId, now)
+	return nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length]
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+
+	"github.com/google/uuid"
+)
+
+type mockSessionStore struct {
+	mockUserStore
+	session *types.Session
+	loaded  int
+	touched int
+}
+
+func (m *mockSessionStore) GetSession(id string) (*types.Session, error) {
+	m.loaded++
+	return m.session, nil
+}
+
+func (m *mockSessionStore) TouchSession(id string) error {
+	m.touched++
+	return nil
+}
+
+func serveWithSession(store types.UserStore, token string) (int, string) {
+	sessionId := ""
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		sessionId = GetSessionIdFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	Require(handler, store)(rec, req)
+	return rec.Code, sessionId
+}
+
+func TestRequireCachesSessions(t *testing.T) {
+	userId := uuid.NewString()
+	session := &types.Session{Id: uuid.NewString(), UserId: userId}
+	store := &mockSessionStore{mockUserStore: mockUserStore{user: &types.User{Id: userId}}, session: session}
+	token, err := CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, session.Id)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		code, sessionId := serveWithSession(store, token)
+		if code != http.StatusOK {
+			t.Fatalf("expected access to be granted, got %d", code)
+		}
+		if sessionId != session.Id {
+			t.Errorf("expected session %s in the context, got %q", session.Id, sessionId)
+		}
+	}
+
+	if store.loaded != 1 || store.touched != 1 {
+		t.Errorf("expected the session to be loaded and touched once, got %d and %d", store.loaded, store.touched)
+	}
+}
+
+func TestRequireRejectsRevokedSession(t *testing.T) {
+	userId := uuid.NewString()
+	session := &types.Session{Id: uuid.NewString(), UserId: userId}
+	store := &mockSessionStore{mockUserStore: mockUserStore{user: &types.User{Id: userId}}, session: session}
+	token, err := CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, session.Id)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	if code, _ := serveWithSession(store, token); code != http.StatusOK {
+		t.Fatalf("expected access to be granted, got %d", code)
+	}
+
+	revokedAt := time.Now()
+	session.RevokedAt = &revokedAt
+	ForgetSession(session.Id)
+	if code, _ := serveWithSession(store, token); code != http.StatusForbidden {
+		t.Errorf("expected access to be denied after the revocation, got %d", code)
+	}
+}
+
+func TestRequireRejectsSessionOfOtherUser(t *testing.T) {
+	userId := uuid.NewString()
+	session := &types.Session{Id: uuid.NewString(), UserId: uuid.NewString()}
+	store := &mockSessionStore{mockUserStore: mockUserStore{user: &types.User{Id: userId}}, session: session}
+	token, err := CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, session.Id)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	if code, _ := serveWithSession(store, token); code != http.StatusForbidden {
+		t.Errorf("expected access to be denied, got %d", code)
+	}
+}
+
+func TestSessionCacheExpires(t *testing.T) {
+	cache := newSessionCache(time.Minute)
+	now := time.Now()
+	cache.remember("session", "user", now)
+
+	if !cache.valid("session", "user", now.Add(30*time.Second)) {
+		t.Error("expected the session to be cached")
+	}
+
+	if cache.valid("session", "other", now) {
+		t.Error("expected the session to be rejected for another user")
+	}
+
+	if cache.valid("session", "user", now.Add(time.Minute)) {
+		t.Error("expected the cache entry to be expired")
+	}
+}
+
+func (m *mockSessionStore) CreateSession(execable interface{}, session types.Session) error {
+	m.session = &session
+	return nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/golang-jwt/jwt"
+)
+
+const ImpersonatorKey string = "impersonatorId"
+
+// impersonation tokens are only needed to look into a problem
+const impersonationTTL = time.Hour
+
+// RequireSuperadmin allows only users with the superadmin role. Personal access
+// tokens need the instance admin scope.
+func RequireSuperadmin(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return Require(func(w http.ResponseWriter, r *http.Request) {
+		if !IsSuperadmin(store, GetUserIdFromContext(r.Context())) {
+			permissionDenied(w)
+			return
+		}
+
+		handlerFunc(w, r)
+	}, store, types.ScopeAdminInstance)
+}
+
+func IsSuperadmin(store types.UserStore, userId string) bool {
+	u, err := store.GetUserById(userId)
+	return err == nil && u.DeactivatedAt == nil && u.SystemRole == types.SystemRoleSuperadmin
+}
+
+// StartImpersonation creates a session of the user for the superadmin. The session
+// is listed for the user and can be revoked like any other.
+func StartImpersonation(store types.UserStore, execable interface{}, r *http.Request, superadminId, userId string) (string, error) {
+	sessionId, err := createSession(store, execable, r, userId)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"userID": userId,
+		"sid":    sessionId,
+		"act":    superadminId,
+	}
+	return createJWT([]byte(config.Envs.JWTSecret), PurposeAccess, impersonationTTL, claims)
+}
+
+// GetImpersonatorFromContext returns the superadmin who acts as the user, if any
+func GetImpersonatorFromContext(ctx context.Context) string {
+	impersonatorId, ok := ctx.Value(ImpersonatorKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return impersonatorId
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+
+	"github.com/google/uuid"
+)
+
+func TestImpersonationIsReadOnly(t *testing.T) {
+	userId := uuid.NewString()
+	superadminId := uuid.NewString()
+	store := &mockSessionStore{mockUserStore: mockUserStore{user: &types.User{Id: userId}}}
+	token, err := StartImpersonation(store, nil, httptest.NewRequest(http.MethodPost, "/", nil), superadminId, userId)
+	if err != nil {
+		t.Fatalf("error starting impersonation: %v", err)
+	}
+
+	impersonatorId := ""
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		impersonatorId = GetImpersonatorFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for method, expected := range map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusForbidden, http.MethodDelete: http.StatusForbidden} {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		Require(handler, store)(rec, req)
+		if rec.Code != expected {
+			t.Errorf("expected %d for %s, got %d", expected, method, rec.Code)
+		}
+	}
+
+	if impersonatorId != superadminId {
+		t.Errorf("expected impersonator %s in the context, got %q", superadminId, impersonatorId)
+	}
+}
+
+func TestRequireSuperadmin(t *testing.T) {
+	userId := uuid.NewString()
+	sessionId := uuid.NewString()
+	token, err := CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, sessionId)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	for role, expected := range map[types.SystemRole]int{types.SystemRoleUser: http.StatusForbidden, types.SystemRoleSuperadmin: http.StatusOK} {
+		store := &mockSessionStore{
+			mockUserStore: mockUserStore{user: &types.User{Id: userId, SystemRole: role}},
+			session:       &types.Session{Id: sessionId, UserId: userId},
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		RequireSuperadmin(handler, store)(rec, req)
+		if rec.Code != expected {
+			t.Errorf("expected %d for role %s, got %d", expected, role, rec.Code)
+		}
+	}
+}
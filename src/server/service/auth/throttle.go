@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff describes how long a login has to wait after consecutive failed
+// attempts. The first FreeAttempts failures are not punished, afterwards the
+// wait time doubles with every failure until Max is reached.
+type Backoff struct {
+	FreeAttempts int
+	Base         time.Duration
+	Max          time.Duration
+}
+
+var (
+	AccountBackoff = Backoff{FreeAttempts: 3, Base: 30 * time.Second, Max: 15 * time.Minute}
+	// clients can share an address (NAT, proxies) so they get more attempts
+	ClientBackoff = Backoff{FreeAttempts: 10, Base: 30 * time.Second, Max: 15 * time.Minute}
+)
+
+func (b Backoff) Duration(failedAttempts int) time.Duration {
+	exceeded := failedAttempts - b.FreeAttempts
+	if exceeded <= 0 {
+		return 0
+	}
+
+	wait := b.Base
+	for i := 1; i < exceeded; i++ {
+		wait *= 2
+		if wait >= b.Max {
+			return b.Max
+		}
+	}
+
+	return wait
+}
+
+type throttleEntry struct {
+	failures     int
+	blockedUntil time.Time
+	lastFailure  time.Time
+}
+
+// LoginThrottle tracks failed logins per key (e.g. the client address) in memory.
+type LoginThrottle struct {
+	mu      sync.Mutex
+	backoff Backoff
+	entries map[string]*throttleEntry
+}
+
+// entries without a failure for this long are forgotten
+const throttleMemory = 24 * time.Hour
+
+func NewLoginThrottle(backoff Backoff) *LoginThrottle {
+	return &LoginThrottle{
+		backoff: backoff,
+		entries: make(map[string]*throttleEntry),
+	}
+}
+
+// RetryAfter returns how long the key is still blocked, zero if it is not blocked.
+func (t *LoginThrottle) RetryAfter(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		return 0
+	}
+
+	wait := time.Until(entry.blockedUntil)
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+func (t *LoginThrottle) Fail(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range t.entries {
+		if now.Sub(e.lastFailure) > throttleMemory {
+			delete(t.entries, k)
+		}
+	}
+
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &throttleEntry{}
+		t.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+	entry.blockedUntil = now.Add(t.backoff.Duration(entry.failures))
+}
+
+func (t *LoginThrottle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, key)
+}
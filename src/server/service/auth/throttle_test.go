@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	backoff := Backoff{FreeAttempts: 3, Base: 30 * time.Second, Max: 5 * time.Minute}
+	expected := map[int]time.Duration{
+		0:  0,
+		3:  0,
+		4:  30 * time.Second,
+		5:  time.Minute,
+		6:  2 * time.Minute,
+		7:  4 * time.Minute,
+		8:  5 * time.Minute,
+		20: 5 * time.Minute,
+	}
+
+	for attempts, wait := range expected {
+		if got := backoff.Duration(attempts); got != wait {
+			t.Errorf("expected %v after %d failed attempts, got %v", wait, attempts, got)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	throttle := NewLoginThrottle(Backoff{FreeAttempts: 1, Base: time.Minute, Max: time.Hour})
+
+	throttle.Fail("127.0.0.1")
+	if throttle.RetryAfter("127.0.0.1") != 0 {
+		t.Error("expected the first failure to be free")
+	}
+
+	throttle.Fail("127.0.0.1")
+	if throttle.RetryAfter("127.0.0.1") <= 0 {
+		t.Error("expected the key to be blocked")
+	}
+
+	if throttle.RetryAfter("10.0.0.1") != 0 {
+		t.Error("expected other keys to not be blocked")
+	}
+
+	throttle.Reset("127.0.0.1")
+	if throttle.RetryAfter("127.0.0.1") != 0 {
+		t.Error("expected the key to be unblocked after a reset")
+	}
+}
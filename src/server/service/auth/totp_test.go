@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test vectors from RFC 6238 appendix B, truncated to six digits
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	expected := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, code := range expected {
+		got, err := TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("error creating code: %v", err)
+		}
+
+		if got != code {
+			t.Errorf("expected code %s at %d, got %s", code, unix, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("error creating code: %v", err)
+	}
+
+	if !ValidateTOTP(secret, code, now.Add(totpPeriod*time.Second)) {
+		t.Error("expected code of the previous period to be accepted")
+	}
+
+	if ValidateTOTP(secret, code, now.Add(5*totpPeriod*time.Second)) {
+		t.Error("expected outdated code to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("simpleHolidayPlaner", "chris@email.com", "SECRET")
+	if !strings.HasPrefix(uri, "otpauth://totp/simpleHolidayPlaner:chris@email.com?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+
+	if !strings.Contains(uri, "secret=SECRET") {
+		t.Errorf("expected secret in uri %s", uri)
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(2)
+	if err != nil {
+		t.Fatalf("error generating codes: %v", err)
+	}
+
+	if codes[0] == codes[1] {
+		t.Error("expected different codes")
+	}
+
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))) {
+		t.Error("expected hash to ignore formatting")
+	}
+}
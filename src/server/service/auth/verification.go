@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/golang-jwt/jwt"
+)
+
+// CreateEmailVerificationToken signs the email address together with the user id,
+// so a link becomes useless as soon as the user changes the address.
+func CreateEmailVerificationToken(secret []byte, userId, email string) (string, error) {
+	expiration := time.Second * time.Duration(config.Envs.EmailVerificationExpireTimeInSeconds)
+
+	return createJWT(secret, PurposeVerifyEmail, expiration, jwt.MapClaims{
+		"userID": userId,
+		"email":  email,
+	})
+}
+
+func ParseEmailVerificationToken(tokenString string) (string, string, error) {
+	claims, err := parseJWTWithPurpose(tokenString, PurposeVerifyEmail)
+	if err != nil {
+		return "", "", err
+	}
+
+	userId, _ := claims["userID"].(string)
+	email, _ := claims["email"].(string)
+	if userId == "" || email == "" {
+		return "", "", fmt.Errorf("invalid token")
+	}
+
+	return userId, email, nil
+}
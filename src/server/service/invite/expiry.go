@@ -0,0 +1,139 @@
+package invite
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/gorilla/mux"
+)
+
+// RevokeInvite withdraws an open invite, only its sender can do this
+func (h *Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.sentInvite(w, r)
+	if !ok {
+		return
+	}
+
+	if !inv.IsOpen(time.Now()) {
+		utils.WriteError(w, http.StatusConflict, errInviteNotOpen)
+		return
+	}
+
+	revoked, err := h.store.CloseInvite(h.db, inv.Id, types.INVITE_REVOKED)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !revoked {
+		utils.WriteError(w, http.StatusConflict, errInviteNotOpen)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+// ResendInvite extends an open invite or opens an expired one again, with the
+// expiry the team currently uses
+func (h *Handler) ResendInvite(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.sentInvite(w, r)
+	if !ok {
+		return
+	}
+
+	if inv.Status != types.INVITE_OPEN && inv.Status != types.INVITE_EXPIRED {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("only open and expired invites can be sent again"))
+		return
+	}
+
+	team, err := h.teamStore.GetTeamById(inv.TeamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if team.IsArchived() {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("nobody can be invited to an archived team"))
+		return
+	}
+
+	expiresAt, err := h.inviteExpiry(team.Id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.ExpireOverdueInvites(tx, inv.Invitee(), inv.TeamId); err != nil {
+			return err
+		}
+
+		// an invite which expired may have been replaced by a new one meanwhile
+		if !inv.IsOpen(time.Now()) {
+			open, err := h.store.HasOpenInvite(tx, inv.Invitee(), inv.TeamId)
+			if err != nil {
+				return err
+			}
+
+			if open {
+				return utils.NewStatusError(http.StatusConflict, errInviteAlreadyOpen)
+			}
+		}
+
+		if err := h.store.RenewInvite(tx, inv.Id, expiresAt); err != nil {
+			return err
+		}
+
+		inv.Status = types.INVITE_OPEN
+		inv.ExpiresAt = &expiresAt
+		utils.WriteJson(w, http.StatusOK, inv)
+		return nil
+	})
+
+	// the link of an earlier mail stays valid until the former expiry, the new one
+	// until the renewed expiry. Both only work as long as the invite is open.
+	if committed && inv.Email != nil {
+		if inviter, err := h.userStore.GetUserById(inv.FromUserId); err == nil {
+			h.sendInviteMail(inv, inviter.Name, team.Name)
+		}
+	}
+}
+
+// sentInvite loads the invite of the path and answers with forbidden, unless the
+// caller sent it
+func (h *Handler) sentInvite(w http.ResponseWriter, r *http.Request) (*types.Invite, bool) {
+	id := mux.Vars(r)["id"]
+	if !utils.IsValidUUID(id) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return nil, false
+	}
+
+	inv, err := h.store.GetInvite(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	if inv.FromUserId != auth.GetUserIdFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only the sender can change the invite"))
+		return nil, false
+	}
+
+	return inv, true
+}
+
+// inviteExpiry returns when an invite sent now to the team expires
+func (h *Handler) inviteExpiry(teamId string) (time.Time, error) {
+	settings, err := h.teamStore.GetTeamSettings(teamId)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().UTC().AddDate(0, 0, settings.InviteExpiryDays), nil
+}
@@ -0,0 +1,117 @@
+package invite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/mail"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+)
+
+var errInvalidInviteLink = fmt.Errorf("invalid or expired invite link")
+
+// RegisterWithInvite creates the account of somebody who was invited by email and
+// accepts the invite in the same transaction. The registration settings of the
+// instance were checked when the invite was sent, and the link proves the address.
+func (h *Handler) RegisterWithInvite(w http.ResponseWriter, r *http.Request) {
+	var payload types.RegisterWithInvitePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	inviteId, email, err := auth.ParseInviteToken(payload.Token)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errInvalidInviteLink)
+		return
+	}
+
+	inv, err := h.store.GetInvite(inviteId)
+	if err != nil || inv.Email == nil || !strings.EqualFold(*inv.Email, email) {
+		utils.WriteError(w, http.StatusBadRequest, errInvalidInviteLink)
+		return
+	}
+
+	if !inv.IsOpen(time.Now()) {
+		utils.WriteError(w, http.StatusConflict, errInviteNotOpen)
+		return
+	}
+
+	team, err := h.teamStore.GetTeamById(inv.TeamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if team.IsArchived() {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("nobody can join an archived team"))
+		return
+	}
+
+	if _, err := h.userStore.GetUserByEmail(email); err == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", email))
+		return
+	}
+
+	if err := auth.ValidatePassword(payload.Password, email); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	verifiedAt := time.Now().UTC()
+	user := types.User{
+		Id:              uuid.NewString(),
+		Name:            payload.Name,
+		Email:           email,
+		Password:        hashedPassword,
+		EmailVerifiedAt: &verifiedAt,
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.userStore.CreateUser(tx, user); err != nil {
+			return err
+		}
+
+		accepted, err := h.store.AcceptInvite(tx, inv.Id, user.Id)
+		if err != nil {
+			return err
+		}
+
+		if !accepted {
+			return utils.NewStatusError(http.StatusConflict, errInviteNotOpen)
+		}
+
+		if err := h.teamStore.AddUserToTeam(tx, user.Id, inv.TeamId, types.Member); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusCreated, nil)
+		return nil
+	})
+}
+
+// sendInviteMail mails the registration link, the sender can resend the invite
+// if the mail didn't arrive
+func (h *Handler) sendInviteMail(inv *types.Invite, inviterName, teamName string) {
+	if err := mail.SendInviteMail(h.mailer, inv, inviterName, teamName); err != nil {
+		log.Printf("error while sending invite mail: %v", err)
+	}
+}
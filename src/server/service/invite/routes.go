@@ -4,30 +4,44 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/team"
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
+var (
+	errInviteNotOpen     = fmt.Errorf("the invite is not open anymore")
+	errInviteAlreadyOpen = fmt.Errorf("the user already has an open invite to the team")
+)
+
 type Handler struct {
-	db        *sql.DB
-	store     types.InviteStore
-	userStore types.UserStore
-	teamStore types.TeamStore
+	db            *sql.DB
+	store         types.InviteStore
+	userStore     types.UserStore
+	teamStore     types.TeamStore
+	settingsStore types.SettingsStore
+	mailer        types.Mailer
 }
 
-func NewHandler(db *sql.DB, store types.InviteStore, userStore types.UserStore, teamStore types.TeamStore) *Handler {
-	return &Handler{db: db, store: store, teamStore: teamStore, userStore: userStore}
+func NewHandler(db *sql.DB, store types.InviteStore, userStore types.UserStore, teamStore types.TeamStore, settingsStore types.SettingsStore, mailer types.Mailer) *Handler {
+	return &Handler{db: db, store: store, teamStore: teamStore, userStore: userStore, settingsStore: settingsStore, mailer: mailer}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/invites/from/{userId}", h.GetInvitesFromUser).Methods(http.MethodGet)
 	router.HandleFunc("/invites/to/{userId}", h.GetInvitesToUser).Methods(http.MethodGet)
-	router.HandleFunc("/invites", h.CreateInvite).Methods(http.MethodPost)
+	router.HandleFunc("/invites", auth.Require(h.CreateInvite, h.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/invites/{id}/approve", h.ApproveInvite).Methods(http.MethodPost)
 	router.HandleFunc("/invites/{id}/decline", h.DeclineInvite).Methods(http.MethodPost)
+	router.HandleFunc("/invites/{id}/revoke", auth.Require(h.RevokeInvite, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/invites/{id}/resend", auth.Require(h.ResendInvite, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/invites/register", h.RegisterWithInvite).Methods(http.MethodPost)
 }
 
 func (h *Handler) DeclineInvite(w http.ResponseWriter, r *http.Request) {
@@ -44,19 +58,29 @@ func (h *Handler) DeclineInvite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := h.store.GetInvite(id)
+	inv, err := h.store.GetInvite(id)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	if !inv.IsOpen(time.Now()) {
+		utils.WriteError(w, http.StatusConflict, errInviteNotOpen)
+		return
+	}
+
 	ctx := r.Context()
 	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
 
-		if err := h.store.UpdateInviteStatus(tx, id, types.INVITE_DECLINED); err != nil {
+		closed, err := h.store.CloseInvite(tx, id, types.INVITE_DECLINED)
+		if err != nil {
 			return err
 		}
 
+		if !closed {
+			return utils.NewStatusError(http.StatusConflict, errInviteNotOpen)
+		}
+
 		// TODO - delete invite when declined? its useful to hold it to show the user the state
 		// if err := h.store.DeleteInvite(tx, id); err != nil {
 		// 	return err
@@ -87,6 +111,17 @@ func (h *Handler) ApproveInvite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// invites of archived teams are cancelled, the ones nobody answered in time expire
+	if !inv.IsOpen(time.Now()) {
+		utils.WriteError(w, http.StatusConflict, errInviteNotOpen)
+		return
+	}
+
+	if inv.ToUserId == "" {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("invites by email are accepted by registering with the link of the mail"))
+		return
+	}
+
 	users, err := h.userStore.GetUsersFromTeam(inv.TeamId)
 
 	if err != nil {
@@ -109,10 +144,15 @@ func (h *Handler) ApproveInvite(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
-		if err := h.store.UpdateInviteStatus(tx, id, types.INVITE_ACCEPTED); err != nil {
+		closed, err := h.store.CloseInvite(tx, id, types.INVITE_ACCEPTED)
+		if err != nil {
 			return err
 		}
 
+		if !closed {
+			return utils.NewStatusError(http.StatusConflict, errInviteNotOpen)
+		}
+
 		if err := h.teamStore.AddUserToTeam(tx, inv.ToUserId, inv.TeamId, types.Member); err != nil {
 			return err
 		}
@@ -171,42 +211,103 @@ func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
 	var payload types.CreateInvitePayload
 	if err := utils.ParseJson(r, &payload); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
 
+	// addresses are stored like at the registration, so the lookup finds existing accounts
+	payload.Email = strings.ToLower(strings.TrimSpace(payload.Email))
 	if !utils.ValidatePayload(w, payload) {
 		return
 	}
 
-	if _, err := h.teamStore.GetTeamById(payload.TeamId); err != nil {
+	inviterId := auth.GetUserIdFromContext(r.Context())
+	if !team.IsAdministrator(h.teamStore, h.userStore, inviterId, payload.TeamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can invite"))
+		return
+	}
+
+	invitedTeam, err := h.teamStore.GetTeamById(payload.TeamId)
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("team does not exists"))
 		return
 	}
 
-	if _, err := h.userStore.GetUserById(payload.FromUserId); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("from user does not exists"))
+	if invitedTeam.IsArchived() {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("nobody can be invited to an archived team"))
+		return
+	}
+
+	inviter, err := h.userStore.GetUserById(inviterId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	if _, err := h.userStore.GetUserById(payload.ToUserId); err != nil {
+	toUserId := payload.ToUserId
+	var email *string
+	if payload.Email != "" {
+		// people who have an account already get a normal invite
+		if u, err := h.userStore.GetUserByEmail(payload.Email); err == nil {
+			toUserId = u.Id
+		} else {
+			settings, err := h.settingsStore.GetInstanceSettings()
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			if !settings.AllowsRegistration(payload.Email) {
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("registration is not open for this email address"))
+				return
+			}
+			email = &payload.Email
+		}
+	} else if _, err := h.userStore.GetUserById(payload.ToUserId); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("to user does not exists"))
 		return
 	}
 
+	expiresAt, err := h.inviteExpiry(invitedTeam.Id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	invite := types.Invite{
 		Id:         uuid.NewString(),
 		InviteType: types.Group_Invite,
-		FromUserId: payload.FromUserId,
-		ToUserId:   payload.ToUserId,
+		FromUserId: inviterId,
+		ToUserId:   toUserId,
+		Email:      email,
 		TeamId:     payload.TeamId,
 		Status:     types.INVITE_OPEN,
+		ExpiresAt:  &expiresAt,
 	}
 
-	err := h.store.CreateInvite(invite)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.ExpireOverdueInvites(tx, invite.Invitee(), invite.TeamId); err != nil {
+			return err
+		}
+
+		open, err := h.store.HasOpenInvite(tx, invite.Invitee(), invite.TeamId)
+		if err != nil {
+			return err
+		}
+
+		if open {
+			return utils.NewStatusError(http.StatusConflict, errInviteAlreadyOpen)
+		}
 
-	utils.WriteJson(w, http.StatusCreated, invite)
+		if err := h.store.CreateInvite(tx, invite); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusCreated, invite)
+		return nil
+	})
 
+	if committed && invite.Email != nil {
+		h.sendInviteMail(&invite, inviter.Name, invitedTeam.Name)
+	}
 }
@@ -2,83 +2,344 @@ package invite
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
 	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
 
-func Test_CreateInvite_Should_Fail_IfUserDontExists(t *testing.T) {
-	db, _, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
-	teamStore := &mockTeam{}
-	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{}, nil }
-	userStore := &mockUser{}
-	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return nil, fmt.Errorf("user does not exists") }
-	inviteStore := &mockInvite{}
-	inviteStore.CreateInviteMock = func(inv types.Invite) error { return nil }
-	handler := NewHandler(db, inviteStore, userStore, teamStore)
-	payload := types.CreateInvitePayload{
-		FromUserId: uuid.NewString(),
-		ToUserId:   uuid.NewString(),
-		TeamId:     uuid.NewString(),
-		InviteType: types.Team_Invite,
-		Status:     types.INVITE_OPEN,
+// administratorOf makes the caller administrator of every team and nothing else
+func administratorOf(teamStore *mockTeam, callerId string) {
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) {
+		if userId == callerId {
+			return types.Administrator, nil
+		}
+		return types.Member, fmt.Errorf("user is not a member of the team")
 	}
+	teamStore.GetAncestorIdsMock = func(teamId string) ([]string, error) { return []string{}, nil }
+}
+
+func openRegistration(domains ...string) *mockSettings {
+	settingsStore := &mockSettings{}
+	settingsStore.GetInstanceSettingsMock = func() (*types.InstanceSettings, error) {
+		return &types.InstanceSettings{RegistrationEnabled: true, AllowedEmailDomains: domains}, nil
+	}
+	return settingsStore
+}
 
+func postInvite(t *testing.T, handler *Handler, callerId string, payload types.CreateInvitePayload) *httptest.ResponseRecorder {
 	marshalled, _ := json.Marshal(payload)
 	req, err := http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(marshalled))
 	if err != nil {
 		t.Fatal(err)
 	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, callerId))
 
 	testHttp := httptest.NewRecorder()
 	router := mux.NewRouter()
 	router.HandleFunc("/invites", handler.CreateInvite).Methods(http.MethodPost)
 	router.ServeHTTP(testHttp, req)
-
-	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+	return testHttp
 }
 
-func Test_CreateInvite_Should_Pass_IfInviteIsCreated(t *testing.T) {
+func Test_CreateInvite_Should_Fail_IfUserDontExists(t *testing.T) {
 	db, _, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
+	callerId := uuid.NewString()
 	teamStore := &mockTeam{}
+	administratorOf(teamStore, callerId)
 	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{}, nil }
 	userStore := &mockUser{}
-	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{}, nil }
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		if id == callerId {
+			return &types.User{Id: id}, nil
+		}
+		return nil, fmt.Errorf("user does not exists")
+	}
 	inviteStore := &mockInvite{}
-	inviteStore.CreateInviteMock = func(inv types.Invite) error { return nil }
-	handler := NewHandler(db, inviteStore, userStore, teamStore)
+	inviteStore.CreateInviteMock = func(execable interface{}, inv types.Invite) error { return nil }
+	handler := NewHandler(db, inviteStore, userStore, teamStore, openRegistration(), &mockMailer{})
 	payload := types.CreateInvitePayload{
-		FromUserId: uuid.NewString(),
 		ToUserId:   uuid.NewString(),
 		TeamId:     uuid.NewString(),
 		InviteType: types.Team_Invite,
 		Status:     types.INVITE_OPEN,
 	}
 
-	marshalled, _ := json.Marshal(payload)
-	req, err := http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(marshalled))
-	if err != nil {
-		t.Fatal(err)
+	testHttp := postInvite(t, handler, callerId, payload)
+
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+}
+
+func Test_CreateInvite(t *testing.T) {
+	callerId := uuid.NewString()
+	tests := []struct {
+		name     string
+		callerId string
+		open     bool
+		status   int
+	}{
+		{"should create the invite with the expiry of the team", callerId, false, http.StatusCreated},
+		{"should fail if the user already has an open invite", callerId, true, http.StatusConflict},
+		{"should fail if the caller is no administrator of the team", uuid.NewString(), false, http.StatusForbidden},
 	}
 
-	testHttp := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/invites", handler.CreateInvite).Methods(http.MethodPost)
-	router.ServeHTTP(testHttp, req)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			switch tt.status {
+			case http.StatusCreated:
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			case http.StatusConflict:
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			}
+
+			var created *types.Invite
+			teamStore := &mockTeam{}
+			administratorOf(teamStore, callerId)
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{}, nil }
+			teamStore.GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) {
+				settings := types.DefaultTeamSettings()
+				settings.InviteExpiryDays = 3
+				return settings, nil
+			}
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{}, nil }
+			inviteStore := &mockInvite{}
+			inviteStore.ExpireOverdueInvitesMock = func(execable interface{}, toUserId, teamId string) error { return nil }
+			inviteStore.HasOpenInviteMock = func(execable interface{}, toUserId, teamId string) (bool, error) { return tt.open, nil }
+			inviteStore.CreateInviteMock = func(execable interface{}, inv types.Invite) error {
+				created = &inv
+				return nil
+			}
+			handler := NewHandler(db, inviteStore, userStore, teamStore, openRegistration(), &mockMailer{})
+			payload := types.CreateInvitePayload{
+				ToUserId:   uuid.NewString(),
+				TeamId:     uuid.NewString(),
+				InviteType: types.Team_Invite,
+				Status:     types.INVITE_OPEN,
+			}
+
+			testHttp := postInvite(t, handler, tt.callerId, payload)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status != http.StatusCreated {
+				require.Nil(t, created)
+				return
+			}
+
+			require.Equal(t, callerId, created.FromUserId)
+			require.NotNil(t, created.ExpiresAt)
+			require.WithinDuration(t, time.Now().AddDate(0, 0, 3), *created.ExpiresAt, time.Minute)
+		})
+	}
+}
+
+func Test_CreateInvite_ByEmail(t *testing.T) {
+	existingId := uuid.NewString()
+	callerId := uuid.NewString()
+	tests := []struct {
+		name     string
+		email    string
+		domains  []string
+		status   int
+		toUserId string
+		mailedTo string
+	}{
+		{"should invite people without account by email", " New.Hire@Email.com ", nil, http.StatusCreated, "", "new.hire@email.com"},
+		{"should invite the account of a known address", "Known@email.com", nil, http.StatusCreated, existingId, ""},
+		{"should invite the account of a known address outside the allowed domains", "known@email.com", []string{"company.com"}, http.StatusCreated, existingId, ""},
+		{"should fail if the address can't register", "new.hire@email.com", []string{"company.com"}, http.StatusForbidden, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusCreated {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			var created *types.Invite
+			teamStore := &mockTeam{}
+			administratorOf(teamStore, callerId)
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id, Name: "Support"}, nil }
+			teamStore.GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) { return types.DefaultTeamSettings(), nil }
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id, Name: "Chris"}, nil }
+			userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+				if email == "known@email.com" {
+					return &types.User{Id: existingId, Email: email}, nil
+				}
+				return nil, fmt.Errorf("user not found")
+			}
+			inviteStore := &mockInvite{}
+			inviteStore.ExpireOverdueInvitesMock = func(execable interface{}, invitee, teamId string) error { return nil }
+			inviteStore.HasOpenInviteMock = func(execable interface{}, invitee, teamId string) (bool, error) { return false, nil }
+			inviteStore.CreateInviteMock = func(execable interface{}, inv types.Invite) error {
+				created = &inv
+				return nil
+			}
+			mailer := &mockMailer{}
+			handler := NewHandler(db, inviteStore, userStore, teamStore, openRegistration(tt.domains...), mailer)
+
+			testHttp := postInvite(t, handler, callerId, types.CreateInvitePayload{Email: tt.email, TeamId: uuid.NewString()})
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status != http.StatusCreated {
+				require.Nil(t, created)
+				require.Empty(t, mailer.sentTo)
+				return
+			}
+
+			require.Equal(t, tt.toUserId, created.ToUserId)
+			if tt.mailedTo != "" {
+				require.Equal(t, tt.mailedTo, *created.Email)
+				require.Equal(t, []string{tt.mailedTo}, mailer.sentTo)
+			} else {
+				require.Nil(t, created.Email)
+				require.Empty(t, mailer.sentTo)
+			}
+		})
+	}
+}
+
+func Test_CreateInvite_Should_NotMail_IfTheTransactionFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(fmt.Errorf("commit failed"))
+
+	callerId := uuid.NewString()
+	teamStore := &mockTeam{}
+	administratorOf(teamStore, callerId)
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+	teamStore.GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) { return types.DefaultTeamSettings(), nil }
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	inviteStore := &mockInvite{}
+	inviteStore.ExpireOverdueInvitesMock = func(execable interface{}, invitee, teamId string) error { return nil }
+	inviteStore.HasOpenInviteMock = func(execable interface{}, invitee, teamId string) (bool, error) { return false, nil }
+	inviteStore.CreateInviteMock = func(execable interface{}, inv types.Invite) error { return nil }
+	mailer := &mockMailer{}
+	handler := NewHandler(db, inviteStore, userStore, teamStore, openRegistration(), mailer)
+
+	postInvite(t, handler, callerId, types.CreateInvitePayload{Email: "new.hire@email.com", TeamId: uuid.NewString()})
+
+	require.Empty(t, mailer.sentTo)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_RegisterWithInvite(t *testing.T) {
+	inviteId := uuid.NewString()
+	teamId := uuid.NewString()
+	email := "new.hire@email.com"
+	expiresAt := time.Now().Add(time.Hour)
+	validToken, err := auth.CreateInviteToken([]byte(config.Envs.JWTSecret), inviteId, email, expiresAt)
+	require.NoError(t, err)
+	otherToken, err := auth.CreateInviteToken([]byte(config.Envs.JWTSecret), inviteId, "someone@email.com", expiresAt)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		accepted bool
+		existing bool
+		status   int
+	}{
+		{"should create the account and join the team", validToken, true, false, http.StatusCreated},
+		{"should fail for a token of another address", otherToken, true, false, http.StatusBadRequest},
+		{"should fail if the address has an account", validToken, true, true, http.StatusConflict},
+		{"should fail if the invite was answered meanwhile", validToken, false, false, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if !tt.existing && tt.token == validToken {
+				mock.ExpectBegin()
+				if tt.status == http.StatusCreated {
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectRollback()
+				}
+			}
 
-	require.Equal(t, http.StatusCreated, testHttp.Code)
+			var created *types.User
+			var joined string
+			teamStore := &mockTeam{}
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.AddUserToTeamMock = func(execable interface{}, userId, team string, role types.UserRole) error {
+				joined = team
+				return nil
+			}
+			userStore := &mockUser{}
+			userStore.GetUserByEmailMock = func(address string) (*types.User, error) {
+				if tt.existing {
+					return &types.User{Id: uuid.NewString(), Email: address}, nil
+				}
+				return nil, fmt.Errorf("user not found")
+			}
+			userStore.CreateUserMock = func(execable interface{}, u types.User) error {
+				created = &u
+				return nil
+			}
+			inviteStore := &mockInvite{}
+			inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
+				return &types.Invite{Id: id, Email: &email, TeamId: teamId, Status: types.INVITE_OPEN, ExpiresAt: &expiresAt}, nil
+			}
+			inviteStore.AcceptInviteMock = func(execable interface{}, id, userId string) (bool, error) { return tt.accepted, nil }
+			handler := NewHandler(db, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
+
+			payload := types.RegisterWithInvitePayload{Token: tt.token, Name: "Sam", Password: "plum-harbor-lantern-42"}
+			marshalled, _ := json.Marshal(payload)
+			req, err := http.NewRequest(http.MethodPost, "/invites/register", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/invites/register", handler.RegisterWithInvite).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status != http.StatusCreated {
+				require.Empty(t, joined)
+				return
+			}
+
+			require.Equal(t, email, created.Email)
+			require.NotNil(t, created.EmailVerifiedAt)
+			require.Equal(t, teamId, joined)
+		})
+	}
 }
 
 func Test_GetInvites_Should_Pass_ForFrom(t *testing.T) {
@@ -89,7 +350,7 @@ func Test_GetInvites_Should_Pass_ForFrom(t *testing.T) {
 	userStore := &mockUser{}
 	inviteStore := &mockInvite{}
 	inviteStore.GetInviteInfosFromMock = func(from string) ([]types.InviteInfo, error) { return make([]types.InviteInfo, 0), nil }
-	handler := NewHandler(db, inviteStore, userStore, teamStore)
+	handler := NewHandler(db, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
 
 	testGuid := uuid.NewString()
 	req, err := http.NewRequest(http.MethodGet, "/invites/from/"+testGuid, bytes.NewBuffer(nil))
@@ -113,7 +374,7 @@ func Test_GetInvites_Should_Pass_ForTo(t *testing.T) {
 	userStore := &mockUser{}
 	inviteStore := &mockInvite{}
 	inviteStore.GetInviteInfosToMock = func(to string) ([]types.InviteInfo, error) { return make([]types.InviteInfo, 0), nil }
-	handler := NewHandler(db, inviteStore, userStore, teamStore)
+	handler := NewHandler(db, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
 
 	testGuid := uuid.NewString()
 	req, err := http.NewRequest(http.MethodGet, "/invites/to/"+testGuid, nil)
@@ -147,7 +408,7 @@ func Test_ApproveInvite_Should_Fail_IfUserIsAlreadyInTeam(t *testing.T) {
 	inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
 		return &types.Invite{ToUserId: testGuid}, nil
 	}
-	handler := NewHandler(db, inviteStore, userStore, teamStore)
+	handler := NewHandler(db, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
 
 	req, err := http.NewRequest(http.MethodPost, "/invites/"+testGuid+"/approve", nil)
 	if err != nil {
@@ -182,9 +443,9 @@ func Test_ApproveInvite_Should_Pass_IfUserIsNotInTeam(t *testing.T) {
 	inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
 		return &types.Invite{ToUserId: uuid.NewString()}, nil
 	}
-	inviteStore.UpdateInviteStatusMock = func(execable interface{}, id string, status types.InviteStatus) error { return nil }
+	inviteStore.CloseInviteMock = func(execable interface{}, id string, status types.InviteStatus) (bool, error) { return true, nil }
 
-	handler := NewHandler(db, inviteStore, userStore, teamStore)
+	handler := NewHandler(db, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
 
 	req, err := http.NewRequest(http.MethodPost, "/invites/"+testGuid+"/approve", nil)
 	if err != nil {
@@ -200,13 +461,242 @@ func Test_ApproveInvite_Should_Pass_IfUserIsNotInTeam(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func Test_ApproveInvite_Should_Fail_IfInviteIsCancelled(t *testing.T) {
+	teamStore := &mockTeam{}
+	userStore := &mockUser{}
+	inviteStore := &mockInvite{}
+	inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
+		return &types.Invite{Id: id, ToUserId: uuid.NewString(), Status: types.INVITE_CANCELLED}, nil
+	}
+	handler := NewHandler(nil, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/invites/"+uuid.NewString()+"/approve", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/invites/{id}/approve", handler.ApproveInvite).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+}
+
+func Test_ApproveInvite_Should_Fail_IfInviteHasExpired(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Hour)
+	inviteStore := &mockInvite{}
+	inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
+		return &types.Invite{Id: id, ToUserId: uuid.NewString(), Status: types.INVITE_OPEN, ExpiresAt: &expiredAt}, nil
+	}
+	handler := NewHandler(nil, inviteStore, &mockUser{}, &mockTeam{}, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/invites/"+uuid.NewString()+"/approve", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/invites/{id}/approve", handler.ApproveInvite).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+}
+
+func Test_AnswerInvite_Should_Fail_IfInviteWasAnsweredMeanwhile(t *testing.T) {
+	for _, action := range []string{"approve", "decline"} {
+		t.Run(action, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectRollback()
+
+			teamStore := &mockTeam{}
+			teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error {
+				t.Fatal("the user must not join the team")
+				return nil
+			}
+			userStore := &mockUser{}
+			userStore.GetUsersFromTeamMock = func(id string) ([]types.TeamUser, error) { return []types.TeamUser{}, nil }
+			inviteStore := &mockInvite{}
+			inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
+				return &types.Invite{Id: id, ToUserId: uuid.NewString(), Status: types.INVITE_OPEN}, nil
+			}
+			inviteStore.CloseInviteMock = func(execable interface{}, id string, status types.InviteStatus) (bool, error) { return false, nil }
+			handler := NewHandler(db, inviteStore, userStore, teamStore, &mockSettings{}, &mockMailer{})
+
+			req, err := http.NewRequest(http.MethodPost, "/invites/"+uuid.NewString()+"/"+action, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/invites/{id}/approve", handler.ApproveInvite).Methods(http.MethodPost)
+			router.HandleFunc("/invites/{id}/decline", handler.DeclineInvite).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, http.StatusConflict, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_RevokeInvite(t *testing.T) {
+	senderId := uuid.NewString()
+	tests := []struct {
+		name     string
+		callerId string
+		status   types.InviteStatus
+		code     int
+	}{
+		{"should revoke the open invite", senderId, types.INVITE_OPEN, http.StatusOK},
+		{"should fail if the caller didn't send the invite", uuid.NewString(), types.INVITE_OPEN, http.StatusForbidden},
+		{"should fail if the invite was answered", senderId, types.INVITE_DECLINED, http.StatusConflict},
+		{"should fail if the invite was answered meanwhile", senderId, types.INVITE_OPEN, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var revoked bool
+			inviteStore := &mockInvite{}
+			inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
+				return &types.Invite{Id: id, FromUserId: senderId, Status: tt.status}, nil
+			}
+			inviteStore.CloseInviteMock = func(execable interface{}, id string, status types.InviteStatus) (bool, error) {
+				// the invite of the last case was declined after it was loaded
+				revoked = status == types.INVITE_REVOKED && tt.code == http.StatusOK
+				return revoked, nil
+			}
+			handler := NewHandler(nil, inviteStore, &mockUser{}, &mockTeam{}, &mockSettings{}, &mockMailer{})
+
+			req, err := http.NewRequest(http.MethodPost, "/invites/"+uuid.NewString()+"/revoke", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, tt.callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/invites/{id}/revoke", handler.RevokeInvite).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.code, testHttp.Code, testHttp.Body.String())
+			require.Equal(t, tt.code == http.StatusOK, revoked)
+		})
+	}
+}
+
+func Test_ResendInvite(t *testing.T) {
+	senderId := uuid.NewString()
+	tests := []struct {
+		name   string
+		status types.InviteStatus
+		open   bool
+		code   int
+	}{
+		{"should open an expired invite again", types.INVITE_EXPIRED, false, http.StatusOK},
+		{"should fail if another invite was sent meanwhile", types.INVITE_EXPIRED, true, http.StatusConflict},
+		{"should fail if the invite was revoked", types.INVITE_REVOKED, false, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == types.INVITE_EXPIRED {
+				mock.ExpectBegin()
+				if tt.code == http.StatusOK {
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectRollback()
+				}
+			}
+
+			var renewedUntil *time.Time
+			teamStore := &mockTeam{}
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) { return types.DefaultTeamSettings(), nil }
+			inviteStore := &mockInvite{}
+			inviteStore.GetInviteMock = func(id string) (*types.Invite, error) {
+				return &types.Invite{Id: id, FromUserId: senderId, TeamId: uuid.NewString(), Status: tt.status}, nil
+			}
+			inviteStore.ExpireOverdueInvitesMock = func(execable interface{}, toUserId, teamId string) error { return nil }
+			inviteStore.HasOpenInviteMock = func(execable interface{}, toUserId, teamId string) (bool, error) { return tt.open, nil }
+			inviteStore.RenewInviteMock = func(execable interface{}, id string, expiresAt time.Time) error {
+				renewedUntil = &expiresAt
+				return nil
+			}
+			handler := NewHandler(db, inviteStore, &mockUser{}, teamStore, &mockSettings{}, &mockMailer{})
+
+			req, err := http.NewRequest(http.MethodPost, "/invites/"+uuid.NewString()+"/resend", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, senderId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/invites/{id}/resend", handler.ResendInvite).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.code, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.code != http.StatusOK {
+				require.Nil(t, renewedUntil)
+				return
+			}
+
+			require.WithinDuration(t, time.Now().AddDate(0, 0, types.DefaultInviteExpiryDays), *renewedUntil, time.Minute)
+		})
+	}
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	invites := []types.InviteInfo{{Id: uuid.NewString(), FromUserName: "Chris", ToUserName: "Alex", TeamName: "Team A"}}
+	inviteStore := &mockInvite{}
+	inviteStore.GetInviteInfosFromMock = func(from string) ([]types.InviteInfo, error) { return invites, nil }
+	inviteStore.GetInviteInfosToMock = func(to string) ([]types.InviteInfo, error) { return invites, nil }
+	inviteStore.GetInviteMock = func(id string) (*types.Invite, error) { return nil, fmt.Errorf("invite not found") }
+	callerId := uuid.NewString()
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Name: "Chris", Email: "chris@email.com", Password: "$argon2id$secret-hash", SystemRole: types.SystemRoleUser}, nil
+	}
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: callerId}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	handler := NewHandler(nil, inviteStore, userStore, &mockTeam{}, &mockSettings{}, &mockMailer{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), callerId, uuid.NewString())
+	require.NoError(t, err)
+
+	routecheck.CheckResponses(t, router, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+type mockMailer struct {
+	sentTo []string
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.sentTo = append(m.sentTo, to)
+	return nil
+}
+
 type mockInvite struct {
-	CreateInviteMock       func(types.Invite) error
-	GetInviteInfosFromMock func(from string) ([]types.InviteInfo, error)
-	GetInviteInfosToMock   func(to string) ([]types.InviteInfo, error)
-	GetInviteMock          func(id string) (*types.Invite, error)
-	DeleteInviteMock       func(execable interface{}, id string) error
-	UpdateInviteStatusMock func(execable interface{}, id string, status types.InviteStatus) error
+	CreateInviteMock         func(execable interface{}, inv types.Invite) error
+	GetInviteInfosFromMock   func(from string) ([]types.InviteInfo, error)
+	GetInviteInfosToMock     func(to string) ([]types.InviteInfo, error)
+	GetInviteMock            func(id string) (*types.Invite, error)
+	DeleteInviteMock         func(execable interface{}, id string) error
+	CloseInviteMock          func(execable interface{}, id string, status types.InviteStatus) (bool, error)
+	HasOpenInviteMock        func(execable interface{}, toUserId, teamId string) (bool, error)
+	ExpireOverdueInvitesMock func(execable interface{}, toUserId, teamId string) error
+	RenewInviteMock          func(execable interface{}, id string, expiresAt time.Time) error
+	AcceptInviteMock         func(execable interface{}, id, userId string) (bool, error)
+	DeleteInvitesToEmailMock func(execable interface{}, email string) error
 }
 
 func (m *mockInvite) DeleteInvite(execable interface{}, id string) error {
@@ -216,12 +706,12 @@ func (m *mockInvite) DeleteInvite(execable interface{}, id string) error {
 func (m *mockInvite) GetInvite(id string) (*types.Invite, error) {
 	return m.GetInviteMock(id)
 }
-func (m *mockInvite) UpdateInviteStatus(execable interface{}, id string, status types.InviteStatus) error {
-	return m.UpdateInviteStatusMock(execable, id, status)
+func (m *mockInvite) CloseInvite(execable interface{}, id string, status types.InviteStatus) (bool, error) {
+	return m.CloseInviteMock(execable, id, status)
 }
 
-func (m *mockInvite) CreateInvite(inv types.Invite) error {
-	return m.CreateInviteMock(inv)
+func (m *mockInvite) CreateInvite(execable interface{}, inv types.Invite) error {
+	return m.CreateInviteMock(execable, inv)
 }
 
 func (m *mockInvite) GetInviteInfosFrom(from string) ([]types.InviteInfo, error) {
@@ -232,11 +722,65 @@ func (m *mockInvite) GetInviteInfosTo(to string) ([]types.InviteInfo, error) {
 	return m.GetInviteInfosToMock(to)
 }
 
+func (m *mockInvite) HasOpenInvite(execable interface{}, toUserId, teamId string) (bool, error) {
+	return m.HasOpenInviteMock(execable, toUserId, teamId)
+}
+
+func (m *mockInvite) ExpireOverdueInvites(execable interface{}, toUserId, teamId string) error {
+	return m.ExpireOverdueInvitesMock(execable, toUserId, teamId)
+}
+
+func (m *mockInvite) RenewInvite(execable interface{}, id string, expiresAt time.Time) error {
+	return m.RenewInviteMock(execable, id, expiresAt)
+}
+
+func (m *mockInvite) AcceptInvite(execable interface{}, id, userId string) (bool, error) {
+	return m.AcceptInviteMock(execable, id, userId)
+}
+
+func (m *mockInvite) DeleteInvitesToEmail(execable interface{}, email string) error {
+	return m.DeleteInvitesToEmailMock(execable, email)
+}
+
 type mockUser struct {
-	GetUserByEmailMock   func(email string) (*types.User, error)
-	GetUserByIdMock      func(id string) (*types.User, error)
-	CreateUserMock       func(types.User) error
-	GetUsersFromTeamMock func(teamId string) ([]types.TeamUser, error)
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
 }
 
 func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
@@ -245,34 +789,190 @@ func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
 func (m *mockUser) GetUserById(id string) (*types.User, error) {
 	return m.GetUserByIdMock(id)
 }
-func (m *mockUser) CreateUser(u types.User) error {
-	return m.CreateUserMock(u)
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
 }
 
 func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
 	return m.GetUsersFromTeamMock(teamId)
 }
 
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
+}
+
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
+}
+
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
+}
+
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
+}
+
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
+}
+
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
+}
+
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
+}
+
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
+
 type mockTeam struct {
-	GetAllTeamsMock        func() ([]types.Team, error)
-	CreateTeamMock         func(types.Team) error
-	RenameTeamMock         func(name, teamId string) error
-	GetTeamByIdMock        func(id string) (*types.Team, error)
-	GetTeamByNameMock      func(name string) (*types.Team, error)
-	AddUserToTeamMock      func(execable interface{}, userId, teamId string, role types.UserRole) error
-	RemoveUserFromTeamMock func(userId, teamId string) error
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	RenameTeamMock              func(name, teamId string) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
 }
 
-func (m *mockTeam) GetAllTeams() ([]types.Team, error) {
-	return m.GetAllTeamsMock()
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
 }
 
 func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
 	return m.GetTeamByIdMock(id)
 }
 
-func (m *mockTeam) CreateTeam(t types.Team) error {
-	return m.CreateTeamMock(t)
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
 }
 
 func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
@@ -283,10 +983,103 @@ func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, ro
 	return m.AddUserToTeamMock(execable, userId, teamId, role)
 }
 
-func (m *mockTeam) RemoveUserFromTeam(userId, teamId string) error {
-	return m.RemoveUserFromTeamMock(userId, teamId)
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
 }
 
 func (m *mockTeam) RenameTeam(name, teamId string) error {
 	return nil
 }
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
+type mockSettings struct {
+	GetInstanceSettingsMock  func() (*types.InstanceSettings, error)
+	SaveInstanceSettingsMock func(execable interface{}, settings types.InstanceSettings) error
+}
+
+func (m *mockSettings) GetInstanceSettings() (*types.InstanceSettings, error) {
+	return m.GetInstanceSettingsMock()
+}
+
+func (m *mockSettings) SaveInstanceSettings(execable interface{}, settings types.InstanceSettings) error {
+	return m.SaveInstanceSettingsMock(execable, settings)
+}
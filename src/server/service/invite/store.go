@@ -3,6 +3,7 @@ package invite
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
@@ -16,9 +17,14 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) CreateInvite(i types.Invite) error {
-	_, err := s.db.Exec("INSERT INTO invites (id, fromUserId, toUserId, teamId, InviteType, status) VALUES (?, ?, ?, ?, ?, ?)",
-		i.Id, i.FromUserId, i.ToUserId, i.TeamId, i.InviteType, i.Status)
+func (s *Store) CreateInvite(execable interface{}, i types.Invite) error {
+	var toUserId *string
+	if i.ToUserId != "" {
+		toUserId = &i.ToUserId
+	}
+
+	_, err := utils.Exec(execable, "INSERT INTO invites (id, fromUserId, toUserId, email, teamId, InviteType, status, expiresAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		i.Id, i.FromUserId, toUserId, i.Email, i.TeamId, i.InviteType, i.Status, i.ExpiresAt)
 
 	if err != nil {
 		return err
@@ -27,6 +33,59 @@ func (s *Store) CreateInvite(i types.Invite) error {
 	return nil
 }
 
+// HasOpenInvite reports whether the invitee already has an invite to the team, which
+// can be answered. The unique open key of the table rejects a second one anyway.
+func (s *Store) HasOpenInvite(execable interface{}, invitee, teamId string) (bool, error) {
+	rows, err := utils.Query(execable, "SELECT COUNT(*) FROM invites WHERE COALESCE(toUserId, email) = ? AND teamId = ? AND status = ?",
+		invitee, teamId, types.INVITE_OPEN)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return false, err
+		}
+	}
+
+	return count > 0, nil
+}
+
+// ExpireOverdueInvites marks the open invites of the invitee to the team expired, once
+// their expiry has passed. This frees the open key for a new invite.
+func (s *Store) ExpireOverdueInvites(execable interface{}, invitee, teamId string) error {
+	_, err := utils.Exec(execable, `UPDATE invites SET status = ?, changedAt = UTC_TIMESTAMP
+		WHERE COALESCE(toUserId, email) = ? AND teamId = ? AND status = ? AND expiresAt <= UTC_TIMESTAMP`,
+		types.INVITE_EXPIRED, invitee, teamId, types.INVITE_OPEN)
+	return err
+}
+
+// AcceptInvite hands an invite by email over to the account which was registered
+// for it. It reports false if the invite was answered meanwhile.
+func (s *Store) AcceptInvite(execable interface{}, id, userId string) (bool, error) {
+	result, err := utils.Exec(execable, `UPDATE invites SET toUserId = ?, status = ?, changedAt = UTC_TIMESTAMP
+		WHERE id = ? AND status = ?`, userId, types.INVITE_ACCEPTED, id, types.INVITE_OPEN)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// RenewInvite opens the invite again until the new expiry
+func (s *Store) RenewInvite(execable interface{}, id string, expiresAt time.Time) error {
+	_, err := utils.Exec(execable, "UPDATE invites SET status = ?, expiresAt = ?, changedAt = UTC_TIMESTAMP WHERE id = ?",
+		types.INVITE_OPEN, expiresAt, id)
+	return err
+}
+
 func (s *Store) DeleteInvite(execable interface{}, id string) error {
 	_, err := utils.Exec(execable, "DELETE FROM invites where Id = ?",
 		id)
@@ -39,7 +98,7 @@ func (s *Store) DeleteInvite(execable interface{}, id string) error {
 }
 
 func (s *Store) GetInvite(id string) (*types.Invite, error) {
-	rows, err := s.db.Query(`SELECT * from invites i where i.Id = ?`, id)
+	rows, err := s.db.Query(`SELECT id, fromUserId, toUserId, email, teamId, status, inviteType, createdAt, changedAt, expiresAt from invites i where i.Id = ?`, id)
 
 	if err != nil {
 		return nil, err
@@ -60,10 +119,14 @@ func (s *Store) GetInvite(id string) (*types.Invite, error) {
 	return invite, nil
 }
 
+// inviteStatusColumn reports open invites past their expiry as expired, before they are marked
+var inviteStatusColumn = fmt.Sprintf("CASE WHEN i.status = %d AND i.expiresAt <= UTC_TIMESTAMP THEN %d ELSE i.status END",
+	types.INVITE_OPEN, types.INVITE_EXPIRED)
+
 func (s *Store) GetInviteInfosFrom(fromUserId string) ([]types.InviteInfo, error) {
-	rows, err := s.db.Query(`SELECT i.Id, ufrom.name as 'FromUserName', uto.name as 'ToUserName', t.name as 'TeamName', i.status, i.createdAt From invites i  
+	rows, err := s.db.Query(`SELECT i.Id, ufrom.name as 'FromUserName', ufrom.deactivatedAt, COALESCE(uto.name, i.email) as 'ToUserName', uto.deactivatedAt, t.name as 'TeamName', `+inviteStatusColumn+`, i.createdAt, i.expiresAt From invites i  
 							inner join users ufrom  on ufrom.id = i.fromUserId 
-							inner join users uto  on uto.id = i.toUserId  
+							left join users uto  on uto.id = i.toUserId  
 							inner join teams t  on t.Id = i.teamId 
 							where i.fromUserId = ?`, fromUserId)
 
@@ -83,20 +146,33 @@ func (s *Store) GetInviteInfosFrom(fromUserId string) ([]types.InviteInfo, error
 	return inviteInfos, nil
 }
 
-func (s *Store) UpdateInviteStatus(execable interface{}, id string, status types.InviteStatus) error {
+// CloseInvite sets the status of the invite as long as it is still open. It reports
+// false if the invite was answered, revoked or cancelled meanwhile.
+func (s *Store) CloseInvite(execable interface{}, id string, status types.InviteStatus) (bool, error) {
+	result, err := utils.Exec(execable, `UPDATE invites set status = ?, changedAt = UTC_TIMESTAMP where id = ? AND status = ?`,
+		status, id, types.INVITE_OPEN)
+	if err != nil {
+		return false, err
+	}
 
-	_, err := utils.Exec(execable, `UPDATE invites set status = ?, changedAt = UTC_TIMESTAMP where id = ?`, status, id)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return affected == 1, nil
+}
+
+// DeleteInvitesToEmail removes the invites sent to the address, whatever their status is
+func (s *Store) DeleteInvitesToEmail(execable interface{}, email string) error {
+	_, err := utils.Exec(execable, "DELETE FROM invites WHERE email = ?", email)
+	return err
 }
 
 func (s *Store) GetInviteInfosTo(toUserId string) ([]types.InviteInfo, error) {
-	rows, err := s.db.Query(`SELECT i.Id, ufrom.name as 'FromUserName', uto.name as 'ToUserName', t.name as 'TeamName', i.status, i.createdAt From invites i
+	rows, err := s.db.Query(`SELECT i.Id, ufrom.name as 'FromUserName', ufrom.deactivatedAt, COALESCE(uto.name, i.email) as 'ToUserName', uto.deactivatedAt, t.name as 'TeamName', `+inviteStatusColumn+`, i.createdAt, i.expiresAt From invites i
 	inner join users ufrom  on ufrom.id = i.fromUserId 
-	inner join users uto  on uto.id = i.toUserId  
+	left join users uto  on uto.id = i.toUserId  
 	inner join teams t  on t.Id = i.teamId 
 	where i.toUserId = ?`, toUserId)
 
@@ -118,34 +194,46 @@ func (s *Store) GetInviteInfosTo(toUserId string) ([]types.InviteInfo, error) {
 
 func readInviteInfoData(rows *sql.Rows) (*types.InviteInfo, error) {
 	inv := new(types.InviteInfo)
+	var fromDeactivatedAt, toDeactivatedAt *time.Time
 	err := rows.Scan(
 		&inv.Id,
 		&inv.FromUserName,
+		&fromDeactivatedAt,
 		&inv.ToUserName,
+		&toDeactivatedAt,
 		&inv.TeamName,
 		&inv.Status,
 		&inv.CreatedAt,
+		&inv.ExpiresAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+
+	inv.FromUserName = types.DisplayName(inv.FromUserName, fromDeactivatedAt)
+	inv.ToUserName = types.DisplayName(inv.ToUserName, toDeactivatedAt)
 	return inv, nil
 }
 
 func readInviteData(rows *sql.Rows) (*types.Invite, error) {
 	inv := new(types.Invite)
+	var toUserId sql.NullString
 	err := rows.Scan(
 		&inv.Id,
 		&inv.FromUserId,
-		&inv.ToUserId,
+		&toUserId,
+		&inv.Email,
 		&inv.TeamId,
 		&inv.Status,
 		&inv.InviteType,
 		&inv.CreatedAt,
 		&inv.ChangedAt,
+		&inv.ExpiresAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+
+	inv.ToUserId = toUserId.String
 	return inv, nil
 }
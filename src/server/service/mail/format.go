@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// date layouts per locale, a locale like de-AT falls back to its language
+var dateLayouts = map[string]string{
+	"de":    "02.01.2006",
+	"fr":    "02/01/2006",
+	"en-GB": "02/01/2006",
+	"en-US": "01/02/2006",
+}
+
+const defaultDateLayout = "2006-01-02"
+
+func dateLayout(locale string) string {
+	if layout, ok := dateLayouts[locale]; ok {
+		return layout
+	}
+
+	language, _, _ := strings.Cut(locale, "-")
+	if layout, ok := dateLayouts[language]; ok {
+		return layout
+	}
+
+	return defaultDateLayout
+}
+
+// FormatDate formats a calendar date like the day of a vacation request. Calendar
+// dates are stored as midnight UTC, so they aren't moved into the timezone.
+func FormatDate(date time.Time, preferences *types.UserPreferences) string {
+	return date.UTC().Format(dateLayout(preferences.Locale))
+}
+
+// FormatTime formats a point in time in the timezone of the user
+func FormatTime(t time.Time, preferences *types.UserPreferences) string {
+	return t.In(preferences.Location()).Format(dateLayout(preferences.Locale) + " 15:04 MST")
+}
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		locale   string
+		timezone string
+		expected string
+	}{
+		{"de", "Europe/Berlin", "04.03.2026"},
+		{"de-AT", "Europe/Vienna", "04.03.2026"},
+		{"en-US", "America/Los_Angeles", "03/04/2026"},
+		{"nl", "UTC", "2026-03-04"},
+	}
+
+	for _, tt := range tests {
+		preferences := &types.UserPreferences{Locale: tt.locale, Timezone: tt.timezone}
+		require.Equal(t, tt.expected, FormatDate(date, preferences), tt.locale)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	preferences := &types.UserPreferences{Locale: "de", Timezone: "Europe/Berlin"}
+
+	formatted := FormatTime(time.Date(2026, time.March, 4, 23, 30, 0, 0, time.UTC), preferences)
+
+	require.Equal(t, "05.03.2026 00:30 CET", formatted)
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// NewMailer returns a smtp mailer if a smtp host is configured, otherwise
+// the mails are only written to the log which is handy for local development.
+func NewMailer() types.Mailer {
+	if config.Envs.SMTPHost == "" {
+		return &LogMailer{}
+	}
+
+	return &SmtpMailer{
+		host:     config.Envs.SMTPHost,
+		port:     config.Envs.SMTPPort,
+		user:     config.Envs.SMTPUser,
+		password: config.Envs.SMTPPassword,
+		from:     config.Envs.MailFrom,
+	}
+}
+
+type SmtpMailer struct {
+	host     string
+	port     int64
+	user     string
+	password string
+	from     string
+}
+
+func (m *SmtpMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.user != "" {
+		auth = smtp.PlainAuth("", m.user, m.password, m.host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+		"",
+		body,
+	}, "\r\n")
+
+	address := fmt.Sprintf("%s:%d", m.host, m.port)
+	return smtp.SendMail(address, auth, m.from, []string{to}, []byte(msg))
+}
+
+type LogMailer struct{}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
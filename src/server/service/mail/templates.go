@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+func AccountCreatedMail(name, temporaryPassword string) (string, string) {
+	subject := "Your simpleHolidayPlaner account"
+	body := fmt.Sprintf(`Hello %s,
+
+an account for simpleHolidayPlaner was created for you.
+
+Your temporary password is: %s
+
+You have to change this password when you log in for the first time.`, name, temporaryPassword)
+
+	return subject, body
+}
+
+func VerificationMail(name, link string) (string, string) {
+	subject := "Please verify your email address"
+	body := fmt.Sprintf(`Hello %s,
+
+please confirm your email address for simpleHolidayPlaner by opening the following link:
+
+%s
+
+If you did not register, you can ignore this mail.`, name, link)
+
+	return subject, body
+}
+
+// SendVerificationMail sends a link with which the user confirms the current email address
+func SendVerificationMail(mailer types.Mailer, u *types.User) error {
+	token, err := auth.CreateEmailVerificationToken([]byte(config.Envs.JWTSecret), u.Id, u.Email)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/verify?token=%s", config.Envs.AppBaseUrl, url.QueryEscape(token))
+	subject, body := VerificationMail(u.Name, link)
+	return mailer.Send(u.Email, subject, body)
+}
+
+func InviteMail(inviterName, teamName, link string) (string, string) {
+	subject := fmt.Sprintf("%s invited you to %s", inviterName, teamName)
+	body := fmt.Sprintf(`Hello,
+
+%s invited you to join the team %s in simpleHolidayPlaner.
+
+Create your account with the following link to join the team:
+
+%s
+
+If you don't know the team, you can ignore this mail.`, inviterName, teamName, link)
+
+	return subject, body
+}
+
+// SendInviteMail sends the link with which somebody without account registers
+// and joins the team of the invite
+func SendInviteMail(mailer types.Mailer, inv *types.Invite, inviterName, teamName string) error {
+	token, err := auth.CreateInviteToken([]byte(config.Envs.JWTSecret), inv.Id, *inv.Email, *inv.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/register?invite=%s", config.Envs.AppBaseUrl, url.QueryEscape(token))
+	subject, body := InviteMail(inviterName, teamName, link)
+	return mailer.Send(*inv.Email, subject, body)
+}
+
+func VacationRequestCreatedMail(approverName, requesterName, from, to, info string) (string, string) {
+	subject := fmt.Sprintf("New vacation request from %s", requesterName)
+	body := fmt.Sprintf(`Hello %s,
+
+%s requested vacation from %s to %s:
+
+%s
+
+Please approve or decline the request in simpleHolidayPlaner.`, approverName, requesterName, from, to, info)
+
+	return subject, body
+}
+
+func VacationRequestDecidedMail(requesterName, from, to string, approved bool, reason string) (string, string) {
+	decision := "declined"
+	if approved {
+		decision = "approved"
+	}
+
+	subject := fmt.Sprintf("Your vacation request was %s", decision)
+	body := fmt.Sprintf(`Hello %s,
+
+your vacation request from %s to %s was %s.`, requesterName, from, to, decision)
+
+	if reason != "" {
+		body += fmt.Sprintf("\n\nReason: %s", reason)
+	}
+
+	return subject, body
+}
@@ -0,0 +1,53 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// GroupMapping is the team membership a group of the identity provider grants
+type GroupMapping struct {
+	TeamName string
+	Role     types.UserRole
+}
+
+// ParseGroupMappings parses mappings in the form
+// "/developers=Team Titan:Member;/developers/leads=Team Titan:Administrator".
+// The role is optional and defaults to Member.
+func ParseGroupMappings(value string) (map[string]GroupMapping, error) {
+	mappings := make(map[string]GroupMapping)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, target, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(group) == "" {
+			return nil, fmt.Errorf("invalid group mapping %q", entry)
+		}
+
+		teamName, roleName, hasRole := strings.Cut(target, ":")
+		mapping := GroupMapping{TeamName: strings.TrimSpace(teamName), Role: types.Member}
+		if mapping.TeamName == "" {
+			return nil, fmt.Errorf("invalid group mapping %q", entry)
+		}
+
+		if hasRole {
+			switch strings.TrimSpace(roleName) {
+			case "Administrator":
+				mapping.Role = types.Administrator
+			case "Member":
+				mapping.Role = types.Member
+			default:
+				return nil, fmt.Errorf("unknown role %q in group mapping %q", roleName, entry)
+			}
+		}
+
+		mappings[strings.TrimSpace(group)] = mapping
+	}
+
+	return mappings, nil
+}
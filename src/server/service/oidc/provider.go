@@ -0,0 +1,294 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// the keys are fetched again if a token was signed with an unknown key, but
+// not more often than this to not hammer the provider with invalid tokens
+const jwksRefreshInterval = time.Minute
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Claims are the claims of an id token which are used to provision users
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// Provider talks to an OpenID Connect provider (e.g. Keycloak) using the
+// authorization code flow. The discovery document and the signing keys are
+// loaded on first use and cached.
+type Provider struct {
+	issuer       string
+	clientId     string
+	clientSecret string
+	redirectUrl  string
+	groupsClaim  string
+	client       *http.Client
+
+	mu            sync.Mutex
+	discovery     *discoveryDocument
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewProvider(issuer, clientId, clientSecret, redirectUrl, groupsClaim string) *Provider {
+	return &Provider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		redirectUrl:  redirectUrl,
+		groupsClaim:  groupsClaim,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		keys:         make(map[string]*rsa.PublicKey),
+	}
+}
+
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.clientId)
+	values.Set("redirect_uri", p.redirectUrl)
+	values.Set("scope", "openid email profile")
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return doc.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the raw id token
+func (p *Provider) Exchange(code, codeVerifier string) (string, error) {
+	doc, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", p.redirectUrl)
+	values.Set("client_id", p.clientId)
+	values.Set("code_verifier", codeVerifier)
+	if p.clientSecret != "" {
+		values.Set("client_secret", p.clientSecret)
+	}
+
+	resp, err := p.client.PostForm(doc.TokenEndpoint, values)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint answered with status %d", resp.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+
+	if tokenResponse.IdToken == "" {
+		return "", fmt.Errorf("token response contains no id token")
+	}
+
+	return tokenResponse.IdToken, nil
+}
+
+// VerifyIDToken checks the signature against the keys of the provider as well
+// as issuer, audience, expiry and nonce.
+func (p *Provider) VerifyIDToken(rawToken, nonce string) (*Claims, error) {
+	token, err := jwt.Parse(rawToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid id token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if !claims.VerifyIssuer(p.issuer, true) {
+		return nil, fmt.Errorf("id token has an unexpected issuer")
+	}
+
+	if !claims.VerifyAudience(p.clientId, true) {
+		return nil, fmt.Errorf("id token was not issued for this client")
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("id token is expired")
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("id token has an unexpected nonce")
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.Name, _ = claims["name"].(string)
+	if groups, ok := claims[p.groupsClaim].([]interface{}); ok {
+		for _, g := range groups {
+			if group, ok := g.(string); ok {
+				result.Groups = append(result.Groups, group)
+			}
+		}
+	}
+
+	if result.Subject == "" {
+		return nil, fmt.Errorf("id token has no subject")
+	}
+
+	return result, nil
+}
+
+func (p *Provider) discover() (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	doc := new(discoveryDocument)
+	if err := p.getJson(p.issuer+"/.well-known/openid-configuration", doc); err != nil {
+		return nil, fmt.Errorf("error while loading the discovery document: %v", err)
+	}
+
+	if strings.TrimSuffix(doc.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("discovery document belongs to issuer %s", doc.Issuer)
+	}
+
+	p.discovery = doc
+	return doc, nil
+}
+
+func (p *Provider) key(kid string) (*rsa.PublicKey, error) {
+	doc, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJson(doc.JwksUri, &jwks); err != nil {
+		return nil, fmt.Errorf("error while loading the signing keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		key, err := parseRSAKey(k)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = key
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+
+	return key, nil
+}
+
+func (p *Provider) getJson(url string, v any) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered with status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func parseRSAKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus of key %s", k.Kid)
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent of key %s", k.Kid)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,173 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+const testClientId = "simple-holiday-planer"
+
+// mockProvider is a minimal OpenID Connect provider. The authorization endpoint
+// is not served, tests read state, nonce and challenge from the redirect url.
+type mockProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discoveryDocument{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JwksUri:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {{
+			Kid: "test-key",
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "valid-code" || codeChallenge(r.Form.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken(t, m.nonce)})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	m.claims = jwt.MapClaims{
+		"sub":            "subject-1",
+		"email":          "sso@email.com",
+		"email_verified": true,
+		"name":           "Single Sign On",
+		"groups":         []string{"/developers"},
+	}
+
+	return m
+}
+
+func (m *mockProvider) idToken(t *testing.T, nonce string) string {
+	claims := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   testClientId,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": nonce,
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(m.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(m.server.URL, testClientId, "", "http://localhost/callback", "groups")
+}
+
+// authorize reads the parameters of the authorization request like the real provider would
+func (m *mockProvider) authorize(t *testing.T, authUrl string) url.Values {
+	parsed, err := url.Parse(authUrl)
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	m.challenge = query.Get("code_challenge")
+	m.nonce = query.Get("nonce")
+	return query
+}
+
+func TestProvider_ExchangeAndVerify(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	verifier, err := randomString()
+	require.NoError(t, err)
+	authUrl, err := p.AuthCodeURL("state", "nonce", codeChallenge(verifier))
+	require.NoError(t, err)
+	m.authorize(t, authUrl)
+
+	idToken, err := p.Exchange("valid-code", verifier)
+	require.NoError(t, err)
+
+	claims, err := p.VerifyIDToken(idToken, "nonce")
+	require.NoError(t, err)
+	require.Equal(t, "subject-1", claims.Subject)
+	require.Equal(t, "sso@email.com", claims.Email)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, []string{"/developers"}, claims.Groups)
+}
+
+func TestProvider_Exchange_Should_Fail_WithWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	authUrl, err := p.AuthCodeURL("state", "nonce", codeChallenge("verifier"))
+	require.NoError(t, err)
+	m.authorize(t, authUrl)
+
+	_, err = p.Exchange("valid-code", "another verifier")
+	require.Error(t, err)
+}
+
+func TestProvider_VerifyIDToken_Should_Fail(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	_, err := p.VerifyIDToken(m.idToken(t, "nonce"), "another nonce")
+	require.Error(t, err, "nonce must match")
+
+	m.claims["aud"] = "another-client"
+	_, err = p.VerifyIDToken(m.idToken(t, "nonce"), "nonce")
+	require.Error(t, err, "audience must match")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	delete(m.claims, "aud")
+	m.key = otherKey
+	_, err = p.VerifyIDToken(m.idToken(t, "nonce"), "nonce")
+	require.Error(t, err, "signature must be valid")
+}
+
+func TestParseGroupMappings(t *testing.T) {
+	mappings, err := ParseGroupMappings("/developers=Team Titan; /leads=Team Titan:Administrator")
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+	require.Equal(t, "Team Titan", mappings["/developers"].TeamName)
+	require.Equal(t, types.Member, mappings["/developers"].Role)
+	require.Equal(t, types.Administrator, mappings["/leads"].Role)
+
+	_, err = ParseGroupMappings("/developers=Team Titan:Owner")
+	require.Error(t, err)
+}
@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// a login has to be completed within this time
+const pendingLoginTTL = 10 * time.Minute
+
+type pendingLogin struct {
+	nonce        string
+	codeVerifier string
+	createdAt    time.Time
+}
+
+type Handler struct {
+	db            *sql.DB
+	provider      *Provider
+	userStore     types.UserStore
+	teamStore     types.TeamStore
+	groupMappings map[string]GroupMapping
+
+	// pending logins are kept in memory, so the callback has to reach the same instance
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+func NewHandler(db *sql.DB, provider *Provider, userStore types.UserStore, teamStore types.TeamStore, groupMappings map[string]GroupMapping) *Handler {
+	return &Handler{
+		db:            db,
+		provider:      provider,
+		userStore:     userStore,
+		teamStore:     teamStore,
+		groupMappings: groupMappings,
+		pending:       make(map[string]pendingLogin),
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/oidc/login", h.handleLogin).Methods(http.MethodGet)
+	router.HandleFunc("/oidc/callback", h.handleCallback).Methods(http.MethodGet)
+}
+
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	state, err := randomString()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	codeVerifier, err := randomString()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	authUrl, err := h.provider.AuthCodeURL(state, nonce, codeChallenge(codeVerifier))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	h.mu.Lock()
+	for key, p := range h.pending {
+		if time.Since(p.createdAt) > pendingLoginTTL {
+			delete(h.pending, key)
+		}
+	}
+	h.pending[state] = pendingLogin{nonce: nonce, codeVerifier: codeVerifier, createdAt: time.Now()}
+	h.mu.Unlock()
+
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("login was rejected by the identity provider: %s", providerError))
+		return
+	}
+
+	// every state can only be used once
+	h.mu.Lock()
+	login, ok := h.pending[query.Get("state")]
+	delete(h.pending, query.Get("state"))
+	h.mu.Unlock()
+
+	if !ok || time.Since(login.createdAt) > pendingLoginTTL {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired login"))
+		return
+	}
+
+	idToken, err := h.provider.Exchange(query.Get("code"), login.codeVerifier)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	claims, err := h.provider.VerifyIDToken(idToken, login.nonce)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		u, err := h.provisionUser(tx, claims)
+		if err != nil {
+			return err
+		}
+
+		if u.DeactivatedAt != nil {
+			return fmt.Errorf("account is deactivated")
+		}
+
+		if err := h.syncGroups(tx, u.Id, claims.Groups); err != nil {
+			return err
+		}
+
+		// the identity provider replaces the password, not the second factor
+		response, err := auth.LoginResponse(h.userStore, tx, r, u)
+		if err != nil {
+			return fmt.Errorf("error while creating token")
+		}
+
+		utils.WriteJson(w, http.StatusOK, response)
+		return nil
+	})
+}
+
+// provisionUser returns the user linked to the subject. Unknown subjects are linked
+// to an existing account with the same verified email address or get a new account.
+func (h *Handler) provisionUser(tx *sql.Tx, claims *Claims) (*types.User, error) {
+	if u, err := h.userStore.GetUserByOidcSubject(claims.Subject); err == nil {
+		return u, nil
+	}
+
+	if claims.Email == "" {
+		return nil, fmt.Errorf("id token contains no email address")
+	}
+
+	if u, err := h.userStore.GetUserByEmail(claims.Email); err == nil {
+		// otherwise anybody who can choose the address at the provider could take over the account
+		if !claims.EmailVerified {
+			return nil, fmt.Errorf("email address is not verified by the identity provider")
+		}
+
+		if err := h.userStore.SetOidcSubject(tx, u.Id, claims.Subject); err != nil {
+			return nil, err
+		}
+
+		return u, nil
+	}
+
+	// the account can only be used with single sign-on, nobody knows the password
+	password, err := auth.GeneratePassword(32)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+
+	subject := claims.Subject
+	u := types.User{
+		Id:          uuid.NewString(),
+		Name:        name,
+		Email:       claims.Email,
+		Password:    hashedPassword,
+		OidcSubject: &subject,
+	}
+
+	if claims.EmailVerified {
+		verifiedAt := time.Now().UTC()
+		u.EmailVerifiedAt = &verifiedAt
+	}
+
+	if err := h.userStore.CreateUser(tx, u); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// syncGroups adds the user to the teams of the mapped groups. Memberships are
+// never removed here, teams can still be managed in the application.
+func (h *Handler) syncGroups(tx *sql.Tx, userId string, groups []string) error {
+	if len(h.groupMappings) == 0 || len(groups) == 0 {
+		return nil
+	}
+
+	memberships, err := h.teamStore.GetTeamsOfUser(userId)
+	if err != nil {
+		return err
+	}
+
+	isMember := make(map[string]bool)
+	for _, m := range memberships {
+		isMember[m.TeamId] = true
+	}
+
+	for _, group := range groups {
+		mapping, ok := h.groupMappings[group]
+		if !ok {
+			continue
+		}
+
+		team, err := h.teamStore.GetTeamByName(mapping.TeamName)
+		if err != nil {
+			log.Printf("team %s of group mapping %s does not exist", mapping.TeamName, group)
+			continue
+		}
+
+		if isMember[team.Id] {
+			continue
+		}
+
+		if err := h.teamStore.AddUserToTeam(tx, userId, team.Id, mapping.Role); err != nil {
+			return err
+		}
+		isMember[team.Id] = true
+	}
+
+	return nil
+}
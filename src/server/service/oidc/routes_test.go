@@ -0,0 +1,513 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Callback_Should_ProvisionUser_AndMapGroups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	m := newMockProvider(t)
+	var createdUser types.User
+	userStore := &mockUser{}
+	userStore.CreateSessionMock = func(execable interface{}, session types.Session) error { return nil }
+	userStore.GetUserByOidcSubjectMock = func(subject string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	userStore.CreateUserMock = func(execable interface{}, u types.User) error {
+		createdUser = u
+		return nil
+	}
+
+	teamId := uuid.NewString()
+	var addedRole *types.UserRole
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) { return []types.UserTeam{}, nil }
+	teamStore.GetTeamByNameMock = func(name string) (*types.Team, error) { return &types.Team{Id: teamId, Name: name}, nil }
+	teamStore.AddUserToTeamMock = func(execable interface{}, userId, id string, role types.UserRole) error {
+		require.Equal(t, teamId, id)
+		addedRole = &role
+		return nil
+	}
+
+	mappings, err := ParseGroupMappings("/developers=Team Titan:Administrator")
+	require.NoError(t, err)
+	handler := NewHandler(db, m.provider(), userStore, teamStore, mappings)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req, err := http.NewRequest(http.MethodGet, "/oidc/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+	require.Equal(t, http.StatusFound, testHttp.Code)
+	query := m.authorize(t, testHttp.Header().Get("Location"))
+
+	req, err = http.NewRequest(http.MethodGet, "/oidc/callback?code=valid-code&state="+query.Get("state"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp = httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.Equal(t, "sso@email.com", createdUser.Email)
+	require.Equal(t, "subject-1", *createdUser.OidcSubject)
+	require.NotNil(t, createdUser.EmailVerifiedAt)
+	require.NotNil(t, addedRole)
+	require.Equal(t, types.Administrator, *addedRole)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// the state can not be used twice
+	testHttp = httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+}
+
+func Test_Callback_Should_NotLinkAccount_IfEmailIsNotVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	m := newMockProvider(t)
+	m.claims["email_verified"] = false
+	userStore := &mockUser{}
+	userStore.CreateSessionMock = func(execable interface{}, session types.Session) error { return nil }
+	userStore.GetUserByOidcSubjectMock = func(subject string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email}, nil
+	}
+
+	handler := NewHandler(db, m.provider(), userStore, &mockTeam{}, nil)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req, err := http.NewRequest(http.MethodGet, "/oidc/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+	query := m.authorize(t, testHttp.Header().Get("Location"))
+
+	req, err = http.NewRequest(http.MethodGet, "/oidc/callback?code=valid-code&state="+query.Get("state"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp = httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusInternalServerError, testHttp.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Callback_Should_AskForSecondFactor_IfTotpIsEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	m := newMockProvider(t)
+	userStore := &mockUser{}
+	userStore.CreateSessionMock = func(execable interface{}, session types.Session) error {
+		t.Fatal("no session may be started before the second factor")
+		return nil
+	}
+	userStore.GetUserByOidcSubjectMock = func(subject string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: "sso@email.com", TotpEnabled: true}, nil
+	}
+
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) { return []types.UserTeam{}, nil }
+
+	handler := NewHandler(db, m.provider(), userStore, teamStore, nil)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req, err := http.NewRequest(http.MethodGet, "/oidc/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+	query := m.authorize(t, testHttp.Header().Get("Location"))
+
+	req, err = http.NewRequest(http.MethodGet, "/oidc/callback?code=valid-code&state="+query.Get("state"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp = httptest.NewRecorder()
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &response))
+	require.Equal(t, true, response["twoFactorRequired"])
+	require.NotEmpty(t, response["challengeToken"])
+	require.Nil(t, response["token"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	m := newMockProvider(t)
+	handler := NewHandler(nil, m.provider(), &mockUser{}, &mockTeam{}, map[string]GroupMapping{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	// the routes of the login are public
+	routecheck.CheckResponses(t, router, nil)
+}
+
+type mockTeam struct {
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
+}
+
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
+}
+
+func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
+	return m.GetTeamByIdMock(id)
+}
+
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
+}
+
+func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
+	return m.GetTeamByNameMock(name)
+}
+
+func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.AddUserToTeamMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
+}
+
+func (m *mockTeam) RenameTeam(name, teamId string) error {
+	return nil
+}
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
+type mockUser struct {
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
+}
+
+func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
+	return m.GetUserByEmailMock(email)
+}
+func (m *mockUser) GetUserById(id string) (*types.User, error) {
+	return m.GetUserByIdMock(id)
+}
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
+}
+
+func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
+	return m.GetUsersFromTeamMock(teamId)
+}
+
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
+}
+
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
+}
+
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
+}
+
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
+}
+
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
+}
+
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
+}
+
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
+}
+
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
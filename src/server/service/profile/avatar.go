@@ -0,0 +1,143 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const maxAvatarSize = 2 << 20
+
+// the type is detected from the content, the file name and the sent content type can't be trusted
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+func avatarUrl(userId string) string {
+	return fmt.Sprintf("/api/v1/users/%s/avatar", userId)
+}
+
+func (h *Handler) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	// leave some room for the multipart headers
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize+64<<10)
+	if err := r.ParseMultipartForm(maxAvatarSize); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("avatar must be a multipart upload of at most %d bytes", maxAvatarSize))
+		return
+	}
+
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing avatar file"))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAvatarSize {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("avatar must not be larger than %d bytes", maxAvatarSize))
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	extension, ok := avatarTypes[http.DetectContentType(content)]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("avatar must be a png, jpeg, gif or webp image"))
+		return
+	}
+
+	u, err := h.userStore.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// every upload gets a new key, so cached old avatars aren't shown for the new one
+	key := path.Join("avatars", u.Id, uuid.NewString()+extension)
+	if err := h.avatars.Save(key, bytes.NewReader(content)); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.userStore.SetAvatarKey(u.Id, &key); err != nil {
+		h.deleteAvatarFile(key)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u.AvatarKey != nil {
+		h.deleteAvatarFile(*u.AvatarKey)
+	}
+
+	utils.WriteJson(w, http.StatusOK, map[string]string{"avatarUrl": avatarUrl(u.Id)})
+}
+
+func (h *Handler) handleDeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userStore.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if u.AvatarKey == nil {
+		utils.WriteJson(w, http.StatusOK, nil)
+		return
+	}
+
+	if err := h.userStore.SetAvatarKey(u.Id, nil); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.deleteAvatarFile(*u.AvatarKey)
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleGetAvatar(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, ok := vars["userId"]
+	if !ok || !utils.IsValidUUID(userId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return
+	}
+
+	u, err := h.userStore.GetUserById(userId)
+	if err != nil || u.AvatarKey == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("avatar not found"))
+		return
+	}
+
+	file, err := h.avatars.Open(*u.AvatarKey)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("avatar not found"))
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(*u.AvatarKey)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// a file which couldn't be deleted only wastes space, so the request doesn't fail
+func (h *Handler) deleteAvatarFile(key string) {
+	if err := h.avatars.Delete(key); err != nil {
+		log.Printf("error while deleting avatar %s: %v", key, err)
+	}
+}
@@ -0,0 +1,291 @@
+package profile
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/gorilla/mux"
+)
+
+// exportedUser contains all stored data of the user except the credentials
+type exportedUser struct {
+	Id                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Email              string     `json:"email"`
+	EmailVerifiedAt    *time.Time `json:"emailVerifiedAt"`
+	MustChangePassword bool       `json:"mustChangePassword"`
+	TotpEnabled        bool       `json:"totpEnabled"`
+	SingleSignOn       bool       `json:"singleSignOn"`
+	DeactivatedAt      *time.Time `json:"deactivatedAt"`
+	CreatedAt          time.Time  `json:"createdAt"`
+}
+
+type exportFile struct {
+	name    string
+	content any
+}
+
+// handleExport returns a zip archive with everything which is stored about the user
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userStore.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	files, err := h.collectExport(u)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the archive is built in memory first, so errors can still be answered as json
+	archive := &bytes.Buffer{}
+	writer := zip.NewWriter(archive)
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := writeZipFile(writer, file.name, bytes.NewReader(content)); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if u.AvatarKey != nil {
+		if err := h.exportAvatar(writer, *u.AvatarKey); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, u.Id))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive.Bytes())
+}
+
+func (h *Handler) collectExport(u *types.User) ([]exportFile, error) {
+	teams, err := h.teamStore.GetTeamsOfUser(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	sentInvites, err := h.inviteStore.GetInviteInfosFrom(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	receivedInvites, err := h.inviteStore.GetInviteInfosTo(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the info of a request is the comment of the user
+	requests, err := h.vacationStore.GetVacationRequestsFromUserId(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals, err := h.vacationStore.GetApprovalsOfApprover(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := h.userStore.GetAccessTokensOfUser(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sessions record the addresses and devices of every login
+	sessions, err := h.userStore.GetSessionsOfUser(u.Id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := h.store.GetPreferences(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	user := exportedUser{
+		Id:                 u.Id,
+		Name:               u.Name,
+		Email:              u.Email,
+		EmailVerifiedAt:    u.EmailVerifiedAt,
+		MustChangePassword: u.MustChangePassword,
+		TotpEnabled:        u.TotpEnabled,
+		SingleSignOn:       u.OidcSubject != nil,
+		DeactivatedAt:      u.DeactivatedAt,
+		CreatedAt:          u.CreatedAt,
+	}
+
+	return []exportFile{
+		{"profile.json", user},
+		{"memberships.json", teams},
+		{"invites.json", map[string]any{"sent": sentInvites, "received": receivedInvites}},
+		{"vacation_requests.json", requests},
+		{"approvals.json", approvals},
+		{"access_tokens.json", tokens},
+		{"sessions.json", sessions},
+		{"preferences.json", preferences},
+	}, nil
+}
+
+func (h *Handler) exportAvatar(writer *zip.Writer, key string) error {
+	file, err := h.avatars.Open(key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeZipFile(writer, "avatar"+path.Ext(key), file)
+}
+
+func writeZipFile(writer *zip.Writer, name string, content io.Reader) error {
+	file, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, content)
+	return err
+}
+
+// handleEraseUser pseudonymizes a deactivated user. The requests keep their dates
+// and states, so the taken vacation days can still be accounted.
+func (h *Handler) handleEraseUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, ok := vars["userId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing user id"))
+		return
+	}
+
+	if !utils.IsValidUUID(userId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return
+	}
+
+	adminId := auth.GetUserIdFromContext(r.Context())
+	if adminId == userId {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you can't erase your own account"))
+		return
+	}
+
+	u, err := h.userStore.GetUserById(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if u.DeactivatedAt == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user has to be deactivated before the personal data can be erased"))
+		return
+	}
+
+	isAdmin, err := h.isAdministratorOfFormerTeam(adminId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !isAdmin {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of a former team of the user can erase the personal data"))
+		return
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		name := fmt.Sprintf("User %s", u.Id[:8])
+		email := fmt.Sprintf("erased-%s@invalid", u.Id)
+		if err := h.userStore.PseudonymizeUser(tx, u.Id, name, email); err != nil {
+			return err
+		}
+
+		if err := h.userStore.ReplaceRecoveryCodes(tx, u.Id, nil); err != nil {
+			return err
+		}
+
+		if err := h.userStore.DeleteAccessTokensOfUser(tx, u.Id); err != nil {
+			return err
+		}
+
+		if err := h.userStore.DeleteSessionsOfUser(tx, u.Id); err != nil {
+			return err
+		}
+
+		if err := h.vacationStore.ClearRequestInfosOfUser(tx, u.Id); err != nil {
+			return err
+		}
+
+		if err := h.vacationStore.ClearApprovalReasonsOfUser(tx, u.Id); err != nil {
+			return err
+		}
+
+		if err := h.store.DeletePreferences(tx, u.Id); err != nil {
+			return err
+		}
+
+		// invites by email are stored with the normalised address
+		if err := h.inviteStore.DeleteInvitesToEmail(tx, strings.ToLower(u.Email)); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+
+	if !committed {
+		return
+	}
+
+	auth.ForgetSessionsOfUser(u.Id)
+	// the picture is personal data as well
+	if u.AvatarKey != nil {
+		h.deleteAvatarFile(*u.AvatarKey)
+	}
+}
+
+// isAdministratorOfFormerTeam reports whether the admin is an administrator of a team
+// the user left or was removed from, deactivated users aren't members of any team.
+// Superadmins may erase all users.
+func (h *Handler) isAdministratorOfFormerTeam(adminId, userId string) (bool, error) {
+	formerTeamIds, err := h.teamStore.GetFormerTeamIdsOfUser(userId)
+	if err != nil {
+		return false, err
+	}
+
+	adminTeams, err := h.teamStore.GetTeamsOfUser(adminId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, adminTeam := range adminTeams {
+		if adminTeam.RoleType == types.Administrator && slices.Contains(formerTeamIds, adminTeam.TeamId) {
+			return true, nil
+		}
+	}
+
+	return auth.IsSuperadmin(h.userStore, adminId), nil
+}
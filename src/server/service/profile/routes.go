@@ -0,0 +1,181 @@
+package profile
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/mail"
+	"github.com/cebuh/simpleHolidayPlaner/service/vacation"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	db            *sql.DB
+	userStore     types.UserStore
+	teamStore     types.TeamStore
+	vacationStore types.VacationStore
+	inviteStore   types.InviteStore
+	store         types.PreferenceStore
+	mailer        types.Mailer
+	avatars       types.FileStorage
+}
+
+func NewHandler(db *sql.DB, userStore types.UserStore, teamStore types.TeamStore, vacationStore types.VacationStore,
+	inviteStore types.InviteStore, store types.PreferenceStore, mailer types.Mailer, avatars types.FileStorage) *Handler {
+	return &Handler{db: db, userStore: userStore, teamStore: teamStore, vacationStore: vacationStore, inviteStore: inviteStore,
+		store: store, mailer: mailer, avatars: avatars}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/me", auth.Require(h.handleGetProfile, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/me", auth.Require(h.handleUpdateProfile, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/me/avatar", auth.Require(h.handleUploadAvatar, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/me/avatar", auth.Require(h.handleDeleteAvatar, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{userId}/avatar", auth.Require(h.handleGetAvatar, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/me/preferences", auth.Require(h.handleGetPreferences, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/me/preferences", auth.Require(h.handleSavePreferences, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/me/export", auth.Require(h.handleExport, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{userId}/erase", auth.Require(h.handleEraseUser, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPost)
+}
+
+func (h *Handler) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userStore.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	profile, err := h.buildProfile(u)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, profile)
+}
+
+func (h *Handler) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var payload types.UpdateProfilePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	u, err := h.userStore.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	emailChanged := payload.Email != nil && !strings.EqualFold(*payload.Email, u.Email)
+	if emailChanged {
+		if _, err := h.userStore.GetUserByEmail(*payload.Email); err == nil {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", *payload.Email))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if payload.Name != nil {
+			if err := h.userStore.UpdateName(tx, u.Id, *payload.Name); err != nil {
+				return err
+			}
+			u.Name = *payload.Name
+		}
+
+		if emailChanged {
+			if err := h.userStore.UpdateEmail(tx, u.Id, *payload.Email); err != nil {
+				return err
+			}
+			u.Email = *payload.Email
+			u.EmailVerifiedAt = nil
+		}
+
+		profile, err := h.buildProfile(u)
+		if err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, profile)
+		return nil
+	})
+
+	// the user can request another mail, if this one doesn't arrive
+	if committed && emailChanged {
+		if err := mail.SendVerificationMail(h.mailer, u); err != nil {
+			log.Printf("error while sending verification mail: %v", err)
+		}
+	}
+}
+
+func (h *Handler) buildProfile(u *types.User) (*types.Profile, error) {
+	teams, err := h.teamStore.GetTeamsOfUser(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := h.vacationStore.GetVacationRequestsFromUserId(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &types.Profile{
+		Id:              u.Id,
+		Name:            u.Name,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		TotpEnabled:     u.TotpEnabled,
+		CreatedAt:       u.CreatedAt,
+		Teams:           teams,
+		Balance:         vacation.SummarizeBalance(requests, time.Now()),
+	}
+
+	if u.AvatarKey != nil {
+		avatarUrl := avatarUrl(u.Id)
+		profile.AvatarUrl = &avatarUrl
+	}
+
+	return profile, nil
+}
+
+func (h *Handler) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	preferences, err := h.store.GetPreferences(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, preferences)
+}
+
+func (h *Handler) handleSavePreferences(w http.ResponseWriter, r *http.Request) {
+	var preferences types.UserPreferences
+	if err := utils.ParseJson(r, &preferences); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, preferences) {
+		return
+	}
+
+	preferences.UserId = auth.GetUserIdFromContext(r.Context())
+	if err := h.store.SavePreferences(preferences); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, preferences)
+}
@@ -0,0 +1,962 @@
+package profile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetProfile_Should_ReturnTeamsAndBalance_WithoutPassword(t *testing.T) {
+	userId := uuid.NewString()
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: userId, Name: "Chris", Email: "chris@email.com", Password: "$argon2id$secret-hash"}, nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: uuid.NewString(), TeamName: "Support", RoleType: types.Administrator}}, nil
+	}
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestsFromUserIdMock = func(requestedFromId string) ([]types.VacationRequest, error) {
+		return []types.VacationRequest{{Status: types.REQUEST_OPEN, FromDate: nextMonday(), ToDate: nextMonday().AddDate(0, 0, 1)}}, nil
+	}
+	handler := NewHandler(nil, userStore, teamStore, vacationStore, &mockInvite{}, &mockPreferences{}, &mockMailer{}, newMemoryStorage())
+
+	req, err := http.NewRequest(http.MethodGet, "/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/me", handler.handleGetProfile).Methods(http.MethodGet)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.NotContains(t, testHttp.Body.String(), "secret-hash")
+	var profile types.Profile
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &profile))
+	require.Equal(t, userId, profile.Id)
+	require.Len(t, profile.Teams, 1)
+	require.Nil(t, profile.AvatarUrl)
+}
+
+func Test_UpdateProfile_Should_RequireVerification_IfEmailChanged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	verifiedAt := time.Now()
+	changedEmail := ""
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Name: "Chris", Email: "old@email.com", EmailVerifiedAt: &verifiedAt}, nil
+	}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	userStore.UpdateEmailMock = func(execable interface{}, userId, email string) error {
+		changedEmail = email
+		return nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) { return nil, nil }
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestsFromUserIdMock = func(requestedFromId string) ([]types.VacationRequest, error) { return nil, nil }
+	mailer := &mockMailer{}
+	handler := NewHandler(db, userStore, teamStore, vacationStore, &mockInvite{}, &mockPreferences{}, mailer, newMemoryStorage())
+
+	marshalled, _ := json.Marshal(map[string]string{"email": "new@email.com"})
+	req, err := http.NewRequest(http.MethodPatch, "/me", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/me", handler.handleUpdateProfile).Methods(http.MethodPatch)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.Equal(t, "new@email.com", changedEmail)
+	require.Equal(t, []string{"new@email.com"}, mailer.sentTo)
+	var profile types.Profile
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &profile))
+	require.Nil(t, profile.EmailVerifiedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_UploadAvatar(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+	tests := []struct {
+		name    string
+		content []byte
+		status  int
+	}{
+		{"should store png image", png, http.StatusOK},
+		{"should reject other file types", []byte("<html><script>alert(1)</script></html>"), http.StatusBadRequest},
+		{"should reject too large images", append(png, make([]byte, maxAvatarSize)...), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var avatarKey *string
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: uuid.NewString()}, nil }
+			userStore.SetAvatarKeyMock = func(userId string, key *string) error {
+				avatarKey = key
+				return nil
+			}
+			avatars := newMemoryStorage()
+			handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockInvite{}, &mockPreferences{}, &mockMailer{}, avatars)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("avatar", "avatar.png")
+			require.NoError(t, err)
+			part.Write(tt.content)
+			writer.Close()
+
+			req, err := http.NewRequest(http.MethodPut, "/me/avatar", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/me/avatar", handler.handleUploadAvatar).Methods(http.MethodPut)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code)
+			if tt.status == http.StatusOK {
+				require.NotNil(t, avatarKey)
+				require.True(t, strings.HasSuffix(*avatarKey, ".png"))
+				require.Contains(t, avatars.files, *avatarKey)
+			} else {
+				require.Nil(t, avatarKey)
+				require.Empty(t, avatars.files)
+			}
+		})
+	}
+}
+
+func Test_Export_Should_ReturnZipWithAllData(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	avatarKey := "avatars/user/avatar.png"
+	u := &types.User{Id: uuid.NewString(), Name: "Chris", Email: "chris@email.com", Password: hashedPassword, AvatarKey: &avatarKey}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return u, nil }
+	userStore.GetAccessTokensOfUserMock = func(userId string) ([]types.PersonalAccessToken, error) {
+		return []types.PersonalAccessToken{{Id: uuid.NewString(), Name: "script", TokenHash: "token-hash"}}, nil
+	}
+	userStore.GetSessionsOfUserMock = func(userId string, includeRevoked bool) ([]types.Session, error) {
+		require.True(t, includeRevoked)
+		return []types.Session{{Id: uuid.NewString(), UserId: userId, IP: "203.0.113.7"}}, nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: uuid.NewString(), TeamName: "Support"}}, nil
+	}
+	inviteStore := &mockInvite{}
+	inviteStore.GetInviteInfosFromMock = func(from string) ([]types.InviteInfo, error) { return nil, nil }
+	inviteStore.GetInviteInfosToMock = func(to string) ([]types.InviteInfo, error) { return nil, nil }
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestsFromUserIdMock = func(requestedFromId string) ([]types.VacationRequest, error) {
+		return []types.VacationRequest{{Id: uuid.NewString(), Info: "family trip"}}, nil
+	}
+	vacationStore.GetApprovalsOfApproverMock = func(approverId string) ([]types.VacationApproval, error) { return nil, nil }
+	preferenceStore := &mockPreferences{}
+	preferenceStore.GetPreferencesMock = func(userId string) (*types.UserPreferences, error) {
+		preferences := types.DefaultPreferences(userId)
+		preferences.Timezone = "Europe/Berlin"
+		return preferences, nil
+	}
+	avatars := newMemoryStorage()
+	avatars.files[avatarKey] = []byte("\x89PNG\r\n\x1a\n")
+	handler := NewHandler(nil, userStore, teamStore, vacationStore, inviteStore, preferenceStore, &mockMailer{}, avatars)
+
+	req, err := http.NewRequest(http.MethodGet, "/me/export", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/me/export", handler.handleExport).Methods(http.MethodGet)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.Equal(t, "application/zip", testHttp.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(testHttp.Body.Bytes()), int64(testHttp.Body.Len()))
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		require.NoError(t, err)
+		contents[file.Name] = string(content)
+	}
+
+	require.Contains(t, contents, "avatar.png")
+	require.Contains(t, contents["profile.json"], "chris@email.com")
+	require.Contains(t, contents["memberships.json"], "Support")
+	require.Contains(t, contents["vacation_requests.json"], "family trip")
+	require.Contains(t, contents["preferences.json"], "Europe/Berlin")
+	require.Contains(t, contents["sessions.json"], "203.0.113.7")
+	for name, content := range contents {
+		require.NotContains(t, content, hashedPassword, name)
+		require.NotContains(t, content, "token-hash", name)
+	}
+}
+
+func Test_SavePreferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		status  int
+	}{
+		{"should save valid preferences", `{"timezone":"Europe/Berlin","locale":"de-DE","firstDayOfWeek":1,"notifications":{"request_created":["email"]}}`, http.StatusOK},
+		{"should reject unknown timezones", `{"timezone":"Mars/Olympus","locale":"de-DE","notifications":{}}`, http.StatusBadRequest},
+		{"should reject invalid locales", `{"timezone":"UTC","locale":"not a locale","notifications":{}}`, http.StatusBadRequest},
+		{"should reject invalid days of week", `{"timezone":"UTC","locale":"en","firstDayOfWeek":7,"notifications":{}}`, http.StatusBadRequest},
+		{"should reject unknown notification events", `{"timezone":"UTC","locale":"en","notifications":{"birthday":["email"]}}`, http.StatusBadRequest},
+		{"should reject unknown notification channels", `{"timezone":"UTC","locale":"en","notifications":{"request_created":["pigeon"]}}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *types.UserPreferences
+			preferenceStore := &mockPreferences{}
+			preferenceStore.SavePreferencesMock = func(preferences types.UserPreferences) error {
+				saved = &preferences
+				return nil
+			}
+			handler := NewHandler(nil, &mockUser{}, &mockTeam{}, &mockVacation{}, &mockInvite{}, preferenceStore, &mockMailer{}, newMemoryStorage())
+
+			req, err := http.NewRequest(http.MethodPut, "/me/preferences", strings.NewReader(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/me/preferences", handler.handleSavePreferences).Methods(http.MethodPut)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			if tt.status == http.StatusOK {
+				require.NotNil(t, saved)
+				require.Equal(t, time.Monday, saved.FirstDayOfWeek)
+				require.True(t, saved.Wants(types.NotificationRequestCreated, types.ChannelEmail))
+				require.False(t, saved.Wants(types.NotificationRequestDecided, types.ChannelEmail))
+			} else {
+				require.Nil(t, saved)
+			}
+		})
+	}
+}
+
+func Test_EraseUser(t *testing.T) {
+	teamId := uuid.NewString()
+	deactivatedAt := time.Now()
+	avatarKey := "avatars/user/avatar.png"
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: teamId, RoleType: types.Administrator}}, nil
+	}
+	teamStore.GetFormerTeamIdsOfUserMock = func(userId string) ([]string, error) { return []string{teamId}, nil }
+	vacationStore := &mockVacation{}
+	vacationStore.ClearRequestInfosOfUserMock = func(execable interface{}, userId string) error { return nil }
+	var reasonsCleared, preferencesDeleted, invitesDeleted string
+	vacationStore.ClearApprovalReasonsOfUserMock = func(execable interface{}, userId string) error {
+		reasonsCleared = userId
+		return nil
+	}
+	preferenceStore := &mockPreferences{}
+	preferenceStore.DeletePreferencesMock = func(execable interface{}, userId string) error {
+		preferencesDeleted = userId
+		return nil
+	}
+	inviteStore := &mockInvite{}
+	inviteStore.DeleteInvitesToEmailMock = func(execable interface{}, email string) error {
+		invitesDeleted = email
+		return nil
+	}
+
+	erase := func(t *testing.T, u *types.User, userStore *mockUser, avatars *memoryStorage) *httptest.ResponseRecorder {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		userStore.GetUserByIdMock = func(id string) (*types.User, error) { return u, nil }
+		handler := NewHandler(db, userStore, teamStore, vacationStore, inviteStore, preferenceStore, &mockMailer{}, avatars)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/"+u.Id+"/erase", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testHttp := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{userId}/erase", handler.handleEraseUser).Methods(http.MethodPost)
+		router.ServeHTTP(testHttp, req)
+		return testHttp
+	}
+
+	t.Run("should fail if user is still active", func(t *testing.T) {
+		testHttp := erase(t, &types.User{Id: uuid.NewString()}, &mockUser{}, newMemoryStorage())
+
+		require.Equal(t, http.StatusConflict, testHttp.Code)
+	})
+
+	t.Run("should fail if user was never a member of a team of the admin", func(t *testing.T) {
+		u := &types.User{Id: uuid.NewString(), DeactivatedAt: &deactivatedAt}
+		userStore := &mockUser{}
+		userStore.GetUserByIdMock = func(id string) (*types.User, error) { return u, nil }
+		otherTeams := &mockTeam{}
+		otherTeams.GetTeamsOfUserMock = teamStore.GetTeamsOfUserMock
+		otherTeams.GetFormerTeamIdsOfUserMock = func(userId string) ([]string, error) { return []string{uuid.NewString()}, nil }
+		handler := NewHandler(nil, userStore, otherTeams, vacationStore, &mockInvite{}, &mockPreferences{}, &mockMailer{}, newMemoryStorage())
+
+		req, err := http.NewRequest(http.MethodPost, "/users/"+u.Id+"/erase", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testHttp := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{userId}/erase", handler.handleEraseUser).Methods(http.MethodPost)
+		router.ServeHTTP(testHttp, req)
+
+		require.Equal(t, http.StatusForbidden, testHttp.Code)
+	})
+
+	t.Run("should pseudonymize deactivated user", func(t *testing.T) {
+		u := &types.User{Id: uuid.NewString(), Name: "Chris", Email: "Chris@email.com", DeactivatedAt: &deactivatedAt, AvatarKey: &avatarKey}
+		var name, email string
+		userStore := &mockUser{}
+		userStore.PseudonymizeUserMock = func(execable interface{}, userId, newName, newEmail string) error {
+			name, email = newName, newEmail
+			return nil
+		}
+		userStore.ReplaceRecoveryCodesMock = func(execable interface{}, userId string, codeHashes []string) error { return nil }
+		userStore.DeleteAccessTokensOfUserMock = func(execable interface{}, userId string) error { return nil }
+		sessionsDeleted := false
+		userStore.DeleteSessionsOfUserMock = func(execable interface{}, userId string) error {
+			sessionsDeleted = true
+			return nil
+		}
+		avatars := newMemoryStorage()
+		avatars.files[avatarKey] = []byte("\x89PNG\r\n\x1a\n")
+
+		testHttp := erase(t, u, userStore, avatars)
+
+		require.Equal(t, http.StatusOK, testHttp.Code)
+		require.NotContains(t, name, "Chris")
+		require.NotContains(t, email, "chris")
+		require.True(t, sessionsDeleted)
+		require.Empty(t, avatars.files)
+		require.Equal(t, u.Id, reasonsCleared)
+		require.Equal(t, u.Id, preferencesDeleted)
+		require.Equal(t, "chris@email.com", invitesDeleted)
+	})
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	avatarKey := "avatars/user/avatar.png"
+	u := &types.User{Id: uuid.NewString(), Name: "Chris", Email: "chris@email.com", Password: hashedPassword, AvatarKey: &avatarKey}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return u, nil }
+	userStore.SetAvatarKeyMock = func(userId string, key *string) error { return nil }
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) { return nil, nil }
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestsFromUserIdMock = func(requestedFromId string) ([]types.VacationRequest, error) { return nil, nil }
+	vacationStore.GetApprovalsOfApproverMock = func(approverId string) ([]types.VacationApproval, error) { return nil, nil }
+	userStore.GetAccessTokensOfUserMock = func(userId string) ([]types.PersonalAccessToken, error) {
+		return []types.PersonalAccessToken{{Id: uuid.NewString(), UserId: userId, Name: "script", TokenHash: auth.HashAccessToken("shp_token")}}, nil
+	}
+	userStore.GetSessionsOfUserMock = func(userId string, includeRevoked bool) ([]types.Session, error) { return nil, nil }
+	inviteStore := &mockInvite{}
+	inviteStore.GetInviteInfosFromMock = func(from string) ([]types.InviteInfo, error) { return nil, nil }
+	inviteStore.GetInviteInfosToMock = func(to string) ([]types.InviteInfo, error) { return nil, nil }
+	preferenceStore := &mockPreferences{}
+	preferenceStore.GetPreferencesMock = func(userId string) (*types.UserPreferences, error) { return &types.UserPreferences{}, nil }
+	avatars := newMemoryStorage()
+	avatars.files[avatarKey] = []byte("\x89PNG\r\n\x1a\n")
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	handler := NewHandler(db, userStore, teamStore, vacationStore, inviteStore, preferenceStore, &mockMailer{}, avatars)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: u.Id}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), u.Id, uuid.NewString())
+	require.NoError(t, err)
+
+	routecheck.CheckResponses(t, router, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+func nextMonday() time.Time {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	for day.Weekday() != time.Monday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+type mockPreferences struct {
+	GetPreferencesMock    func(userId string) (*types.UserPreferences, error)
+	SavePreferencesMock   func(preferences types.UserPreferences) error
+	DeletePreferencesMock func(execable interface{}, userId string) error
+}
+
+func (m *mockPreferences) GetPreferences(userId string) (*types.UserPreferences, error) {
+	return m.GetPreferencesMock(userId)
+}
+
+func (m *mockPreferences) SavePreferences(preferences types.UserPreferences) error {
+	return m.SavePreferencesMock(preferences)
+}
+
+func (m *mockPreferences) DeletePreferences(execable interface{}, userId string) error {
+	return m.DeletePreferencesMock(execable, userId)
+}
+
+type memoryStorage struct {
+	files map[string][]byte
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{files: map[string][]byte{}}
+}
+
+func (s *memoryStorage) Save(key string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	s.files[key] = data
+	return nil
+}
+
+func (s *memoryStorage) Open(key string) (io.ReadCloser, error) {
+	data, ok := s.files[key]
+	if !ok {
+		return nil, fmt.Errorf("file not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) Delete(key string) error {
+	delete(s.files, key)
+	return nil
+}
+
+type mockVacation struct {
+	GetVacationRequestsFromUserIdMock func(requestedFromId string) ([]types.VacationRequest, error)
+	GetTeamsWithOpenApprovalsMock     func(approverId string) ([]string, error)
+	ReassignOpenApprovalsMock         func(execable interface{}, teamId, fromApproverId, toApproverId string) error
+	CancelFutureRequestsOfUserMock    func(execable interface{}, userId string, from time.Time) error
+	GetApprovalsOfApproverMock        func(approverId string) ([]types.VacationApproval, error)
+	ClearRequestInfosOfUserMock       func(execable interface{}, userId string) error
+	GetVacationRequestByIdMock        func(id string) (*types.VacationRequest, error)
+	CancelUndecidedRequestsInTeamMock func(execable interface{}, userId, teamId string) error
+	GetAbsencesOfTeamsMock            func(teamIds []string, from, to time.Time) ([]types.Absence, error)
+	LockApprovalsForRequestMock       func(execable interface{}, requestId string) ([]types.VacationApproval, error)
+	DecideRequestMock                 func(execable interface{}, requestId string, status types.RequestStatus) (bool, error)
+	ClearApprovalReasonsOfUserMock    func(execable interface{}, userId string) error
+}
+
+func (m *mockVacation) CreateVacationRequest(execable interface{}, request types.VacationRequest) error {
+	return nil
+}
+
+func (m *mockVacation) GetVacationRequestsForUser(toUserId string) ([]types.VacationRequest, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) GetVacationRequestsFromUserId(requestedFromId string) ([]types.VacationRequest, error) {
+	return m.GetVacationRequestsFromUserIdMock(requestedFromId)
+}
+
+func (m *mockVacation) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error {
+	return nil
+}
+
+func (m *mockVacation) GetApprovalsForRequest(requestId string) ([]types.VacationApproval, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) CreateApprovalEntry(execable interface{}, requestId string, approverId string) error {
+	return nil
+}
+
+func (m *mockVacation) GetTeamsWithOpenApprovals(approverId string) ([]string, error) {
+	return m.GetTeamsWithOpenApprovalsMock(approverId)
+}
+
+func (m *mockVacation) ReassignOpenApprovals(execable interface{}, teamId, fromApproverId, toApproverId string) error {
+	return m.ReassignOpenApprovalsMock(execable, teamId, fromApproverId, toApproverId)
+}
+
+func (m *mockVacation) CancelFutureRequestsOfUser(execable interface{}, userId string, from time.Time) error {
+	return m.CancelFutureRequestsOfUserMock(execable, userId, from)
+}
+
+func (m *mockVacation) GetApprovalsOfApprover(approverId string) ([]types.VacationApproval, error) {
+	return m.GetApprovalsOfApproverMock(approverId)
+}
+
+func (m *mockVacation) ClearRequestInfosOfUser(execable interface{}, userId string) error {
+	return m.ClearRequestInfosOfUserMock(execable, userId)
+}
+
+func (m *mockVacation) GetVacationRequestById(id string) (*types.VacationRequest, error) {
+	return m.GetVacationRequestByIdMock(id)
+}
+
+func (m *mockVacation) CancelUndecidedRequestsInTeam(execable interface{}, userId, teamId string) error {
+	return m.CancelUndecidedRequestsInTeamMock(execable, userId, teamId)
+}
+
+func (m *mockVacation) GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]types.Absence, error) {
+	return m.GetAbsencesOfTeamsMock(teamIds, from, to)
+}
+
+func (m *mockVacation) LockApprovalsForRequest(execable interface{}, requestId string) ([]types.VacationApproval, error) {
+	return m.LockApprovalsForRequestMock(execable, requestId)
+}
+
+func (m *mockVacation) DecideRequest(execable interface{}, requestId string, status types.RequestStatus) (bool, error) {
+	return m.DecideRequestMock(execable, requestId, status)
+}
+
+func (m *mockVacation) ClearApprovalReasonsOfUser(execable interface{}, userId string) error {
+	return m.ClearApprovalReasonsOfUserMock(execable, userId)
+}
+
+type mockInvite struct {
+	CreateInviteMock         func(execable interface{}, inv types.Invite) error
+	GetInviteInfosFromMock   func(from string) ([]types.InviteInfo, error)
+	GetInviteInfosToMock     func(to string) ([]types.InviteInfo, error)
+	GetInviteMock            func(id string) (*types.Invite, error)
+	DeleteInviteMock         func(execable interface{}, id string) error
+	CloseInviteMock          func(execable interface{}, id string, status types.InviteStatus) (bool, error)
+	HasOpenInviteMock        func(execable interface{}, toUserId, teamId string) (bool, error)
+	ExpireOverdueInvitesMock func(execable interface{}, toUserId, teamId string) error
+	RenewInviteMock          func(execable interface{}, id string, expiresAt time.Time) error
+	AcceptInviteMock         func(execable interface{}, id, userId string) (bool, error)
+	DeleteInvitesToEmailMock func(execable interface{}, email string) error
+}
+
+func (m *mockInvite) DeleteInvite(execable interface{}, id string) error {
+	return m.DeleteInviteMock(execable, id)
+}
+
+func (m *mockInvite) GetInvite(id string) (*types.Invite, error) {
+	return m.GetInviteMock(id)
+}
+func (m *mockInvite) CloseInvite(execable interface{}, id string, status types.InviteStatus) (bool, error) {
+	return m.CloseInviteMock(execable, id, status)
+}
+
+func (m *mockInvite) CreateInvite(execable interface{}, inv types.Invite) error {
+	return m.CreateInviteMock(execable, inv)
+}
+
+func (m *mockInvite) GetInviteInfosFrom(from string) ([]types.InviteInfo, error) {
+	return m.GetInviteInfosFromMock(from)
+}
+
+func (m *mockInvite) GetInviteInfosTo(to string) ([]types.InviteInfo, error) {
+	return m.GetInviteInfosToMock(to)
+}
+
+func (m *mockInvite) HasOpenInvite(execable interface{}, toUserId, teamId string) (bool, error) {
+	return m.HasOpenInviteMock(execable, toUserId, teamId)
+}
+
+func (m *mockInvite) ExpireOverdueInvites(execable interface{}, toUserId, teamId string) error {
+	return m.ExpireOverdueInvitesMock(execable, toUserId, teamId)
+}
+
+func (m *mockInvite) RenewInvite(execable interface{}, id string, expiresAt time.Time) error {
+	return m.RenewInviteMock(execable, id, expiresAt)
+}
+
+func (m *mockInvite) AcceptInvite(execable interface{}, id, userId string) (bool, error) {
+	return m.AcceptInviteMock(execable, id, userId)
+}
+
+func (m *mockInvite) DeleteInvitesToEmail(execable interface{}, email string) error {
+	return m.DeleteInvitesToEmailMock(execable, email)
+}
+
+type mockMailer struct {
+	sentTo []string
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.sentTo = append(m.sentTo, to)
+	return nil
+}
+
+type mockTeam struct {
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
+}
+
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
+}
+
+func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
+	return m.GetTeamByIdMock(id)
+}
+
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
+}
+
+func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
+	return m.GetTeamByNameMock(name)
+}
+
+func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.AddUserToTeamMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
+}
+
+func (m *mockTeam) RenameTeam(name, teamId string) error {
+	return nil
+}
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
+type mockUser struct {
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
+}
+
+func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
+	return m.GetUserByEmailMock(email)
+}
+func (m *mockUser) GetUserById(id string) (*types.User, error) {
+	return m.GetUserByIdMock(id)
+}
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
+}
+
+func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
+	return m.GetUsersFromTeamMock(teamId)
+}
+
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
+}
+
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
+}
+
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
+}
+
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
+}
+
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
+}
+
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
+}
+
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
+}
+
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
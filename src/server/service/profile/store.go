@@ -0,0 +1,68 @@
+package profile
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetPreferences returns the default preferences if the user didn't save any yet
+func (s *Store) GetPreferences(userId string) (*types.UserPreferences, error) {
+	rows, err := s.db.Query("SELECT timezone, locale, firstDayOfWeek, holidayRegion, notifications FROM user_preferences WHERE user_id = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return types.DefaultPreferences(userId), nil
+	}
+
+	preferences := &types.UserPreferences{UserId: userId}
+	var holidayRegion sql.NullString
+	var notifications string
+	if err := rows.Scan(&preferences.Timezone, &preferences.Locale, &preferences.FirstDayOfWeek, &holidayRegion, &notifications); err != nil {
+		return nil, err
+	}
+
+	preferences.HolidayRegion = holidayRegion.String
+	if err := json.Unmarshal([]byte(notifications), &preferences.Notifications); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (s *Store) SavePreferences(preferences types.UserPreferences) error {
+	notifications, err := json.Marshal(preferences.Notifications)
+	if err != nil {
+		return err
+	}
+
+	var holidayRegion *string
+	if preferences.HolidayRegion != "" {
+		holidayRegion = &preferences.HolidayRegion
+	}
+
+	_, err = s.db.Exec(`INSERT INTO user_preferences (user_id, timezone, locale, firstDayOfWeek, holidayRegion, notifications)
+						VALUES (?, ?, ?, ?, ?, ?)
+						ON DUPLICATE KEY UPDATE timezone = VALUES(timezone), locale = VALUES(locale), firstDayOfWeek = VALUES(firstDayOfWeek),
+						holidayRegion = VALUES(holidayRegion), notifications = VALUES(notifications), changedAt = UTC_TIMESTAMP`,
+		preferences.UserId, preferences.Timezone, preferences.Locale, preferences.FirstDayOfWeek, holidayRegion, string(notifications))
+	return err
+}
+
+func (s *Store) DeletePreferences(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "DELETE FROM user_preferences WHERE user_id = ?", userId)
+	return err
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+
+	require.NoError(t, s.Save("avatars/user/avatar.png", strings.NewReader("image")))
+
+	file, err := s.Open("avatars/user/avatar.png")
+	require.NoError(t, err)
+	content, err := io.ReadAll(file)
+	file.Close()
+	require.NoError(t, err)
+	require.Equal(t, "image", string(content))
+
+	require.NoError(t, s.Delete("avatars/user/avatar.png"))
+	_, err = s.Open("avatars/user/avatar.png")
+	require.Error(t, err)
+
+	require.Error(t, s.Save("../outside.png", strings.NewReader("image")))
+	require.Error(t, s.Save("avatars/../../outside.png", strings.NewReader("image")))
+}
@@ -0,0 +1,180 @@
+package team
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+var errTeamArchived = fmt.Errorf("the team is archived and can't be changed")
+
+// handleArchiveTeam makes the team read-only. Open invites are cancelled, because
+// nobody can join an archived team anymore.
+func (h *Handler) handleArchiveTeam(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+func (h *Handler) handleUnarchiveTeam(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *Handler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can archive it"))
+		return
+	}
+
+	team, err := h.store.GetTeamById(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if team.IsArchived() == archived {
+		utils.WriteJson(w, http.StatusOK, nil)
+		return
+	}
+
+	action := types.TeamHistoryUnarchived
+	if archived {
+		action = types.TeamHistoryArchived
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.SetTeamArchived(tx, teamId, archived); err != nil {
+			return err
+		}
+
+		if archived {
+			if err := h.store.CancelOpenInvitesOfTeam(tx, teamId); err != nil {
+				return err
+			}
+		}
+
+		if err := h.recordHistory(tx, teamId, callerId, action, nil, nil); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+func (h *Handler) handleGetDeletionPreview(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can delete it"))
+		return
+	}
+
+	preview, err := h.deletionPreview(callerId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, preview)
+}
+
+// handleDeleteTeam removes an empty team for good. Teams with history worth keeping
+// have to be archived instead.
+func (h *Handler) handleDeleteTeam(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can delete it"))
+		return
+	}
+
+	preview, err := h.deletionPreview(callerId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if !preview.Deletable {
+		utils.WriteJson(w, http.StatusConflict, preview)
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.DeleteTeam(tx, teamId); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// deletionPreview decides whether the team is empty. The membership of the caller
+// doesn't count, an administrator can delete a team of which they are the only member.
+func (h *Handler) deletionPreview(callerId, teamId string) (*types.TeamDeletionPreview, error) {
+	if _, err := h.store.GetTeamById(teamId); err != nil {
+		return nil, err
+	}
+
+	preview, err := h.store.GetTeamDeletionPreview(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	otherMembers := preview.Members
+	if _, err := h.store.GetUserRoleInTeam(callerId, teamId); err == nil {
+		otherMembers--
+	}
+
+	preview.Blockers = []string{}
+	if otherMembers > 0 {
+		preview.Blockers = append(preview.Blockers, "the team has other members")
+	}
+
+	if preview.Units > 0 {
+		preview.Blockers = append(preview.Blockers, "the team contains other units")
+	}
+
+	if preview.VacationRequests > 0 {
+		preview.Blockers = append(preview.Blockers, "the team has vacation requests, archive it instead")
+	}
+
+	preview.Deletable = len(preview.Blockers) == 0
+	return preview, nil
+}
+
+// WritableTeam loads the team and answers with a conflict if it is archived, the
+// team has to be restored before anybody can change it
+func WritableTeam(store types.TeamStore, w http.ResponseWriter, teamId string) (*types.Team, bool) {
+	team, err := store.GetTeamById(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	if team.IsArchived() {
+		utils.WriteError(w, http.StatusConflict, errTeamArchived)
+		return nil, false
+	}
+
+	return team, true
+}
@@ -0,0 +1,253 @@
+package team
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+const (
+	calendarDateLayout  = "2006-01-02"
+	defaultCalendarDays = 31
+	maxCalendarDays     = 366
+)
+
+// handleMoveTeam places the team with all units below it under another parent
+func (h *Handler) handleMoveTeam(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.MoveTeamPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can move it"))
+		return
+	}
+
+	team, ok := WritableTeam(h.store, w, teamId)
+	if !ok {
+		return
+	}
+
+	if payload.ParentId == nil {
+		if team.ParentId == nil {
+			utils.WriteJson(w, http.StatusOK, nil)
+			return
+		}
+
+		// the administrators of the former parent would lose their rights
+		if !auth.IsSuperadmin(h.userStore, callerId) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only superadmins can move a unit to the top"))
+			return
+		}
+	} else {
+		if *payload.ParentId == teamId {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("a unit can't be moved below itself"))
+			return
+		}
+
+		descendantIds, err := h.store.GetDescendantIds(teamId)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if slices.Contains(descendantIds, *payload.ParentId) {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("a unit can't be moved below itself"))
+			return
+		}
+
+		if status, err := h.checkParent(callerId, team, *payload.ParentId); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.MoveTeam(tx, teamId, payload.ParentId); err != nil {
+			return err
+		}
+
+		details := map[string]*string{"from": team.ParentId, "to": payload.ParentId}
+		if err := h.recordHistory(tx, teamId, callerId, types.TeamHistoryMoved, nil, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// checkParent reports why the team can't be placed below the parent, together with the
+// status to answer
+func (h *Handler) checkParent(callerId string, team *types.Team, parentId string) (int, error) {
+	parent, err := h.store.GetTeamById(parentId)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("parent unit does not exist")
+	}
+
+	if parent.IsArchived() {
+		return http.StatusConflict, errTeamArchived
+	}
+
+	if !parent.UnitType.CanContain(team.UnitType) {
+		return http.StatusBadRequest, fmt.Errorf("a %s can't be placed below a %s", team.UnitType, parent.UnitType)
+	}
+
+	if !h.isAdministrator(callerId, parent.Id) {
+		return http.StatusForbidden, fmt.Errorf("only administrators of the parent unit can add units to it")
+	}
+
+	return http.StatusOK, nil
+}
+
+// handleGetCalendar returns the absences in the unit and all units below it. Members
+// of a unit can see the calendars of the units below it.
+func (h *Handler) handleGetCalendar(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := calendarPeriod(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	isMember, err := h.isMemberOfUnitOrAbove(callerId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !isMember && !auth.IsSuperadmin(h.userStore, callerId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only members of the unit can see its calendar"))
+		return
+	}
+
+	descendantIds, err := h.store.GetDescendantIds(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	absences, err := h.vacationStore.GetAbsencesOfTeams(append([]string{teamId}, descendantIds...), from, to)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// administrators decide about the requests, so they always see them
+	if !h.isAdministrator(callerId, teamId) {
+		absences, err = h.applyLeaveVisibility(callerId, absences)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	utils.WriteJson(w, http.StatusOK, absences)
+}
+
+// applyLeaveVisibility hides the leave of others in teams which only show that somebody
+// is absent. Requests which aren't approved yet aren't an absence there.
+func (h *Handler) applyLeaveVisibility(callerId string, absences []types.Absence) ([]types.Absence, error) {
+	settings := make(map[string]*types.TeamSettings)
+	visible := make([]types.Absence, 0, len(absences))
+	for _, absence := range absences {
+		teamSettings, ok := settings[absence.TeamId]
+		if !ok {
+			var err error
+			teamSettings, err = h.store.GetTeamSettings(absence.TeamId)
+			if err != nil {
+				return nil, err
+			}
+			settings[absence.TeamId] = teamSettings
+		}
+
+		if absence.UserId != callerId && teamSettings.LeaveVisibility == types.LeaveVisibilityAbsent {
+			if absence.Status == nil || *absence.Status != types.REQUEST_APPROVED {
+				continue
+			}
+
+			absence.RequestId = ""
+			absence.Type = ""
+			absence.Status = nil
+		}
+
+		visible = append(visible, absence)
+	}
+
+	return visible, nil
+}
+
+func (h *Handler) isMemberOfUnitOrAbove(userId, teamId string) (bool, error) {
+	if _, err := h.store.GetUserRoleInTeam(userId, teamId); err == nil {
+		return true, nil
+	}
+
+	ancestorIds, err := h.store.GetAncestorIds(teamId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, ancestorId := range ancestorIds {
+		if _, err := h.store.GetUserRoleInTeam(userId, ancestorId); err == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// calendarPeriod reads the days from the query, without them the next month is returned
+func calendarPeriod(r *http.Request) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(calendarDateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from is not a date like %s", calendarDateLayout)
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, defaultCalendarDays)
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(calendarDateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to is not a date like %s", calendarDateLayout)
+		}
+		to = parsed
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the period must not end before it starts")
+	}
+
+	if to.Sub(from) > maxCalendarDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("the period must not be longer than %d days", maxCalendarDays)
+	}
+
+	return from, to, nil
+}
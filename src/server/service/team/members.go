@@ -0,0 +1,442 @@
+package team
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var errLastAdministrator = fmt.Errorf("the team must keep at least one administrator")
+
+func (h *Handler) handleUpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	teamId, userId, ok := memberFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.UpdateMemberRolePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can change roles"))
+		return
+	}
+
+	if _, ok := WritableTeam(h.store, w, teamId); !ok {
+		return
+	}
+
+	current, err := h.store.GetUserRoleInTeam(userId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if current == *payload.RoleType {
+		utils.WriteJson(w, http.StatusOK, nil)
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if current == types.Administrator {
+			if err := KeepAnAdministrator(h.store, tx, teamId, userId); err != nil {
+				return err
+			}
+		}
+
+		if err := h.store.SetMemberRole(tx, userId, teamId, *payload.RoleType); err != nil {
+			return err
+		}
+
+		details := map[string]types.UserRole{"from": current, "to": *payload.RoleType}
+		if err := h.recordHistory(tx, teamId, callerId, types.TeamHistoryRoleChanged, &userId, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// handleTransferOwnership hands the administration over to another member, the
+// previous administrator stays in the team as member
+func (h *Handler) handleTransferOwnership(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.TransferOwnershipPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if role, err := h.store.GetUserRoleInTeam(callerId, teamId); err != nil || role != types.Administrator {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can transfer the ownership"))
+		return
+	}
+
+	if _, ok := WritableTeam(h.store, w, teamId); !ok {
+		return
+	}
+
+	if payload.UserId == callerId {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the ownership can't be transferred to yourself"))
+		return
+	}
+
+	if _, err := h.store.GetUserRoleInTeam(payload.UserId, teamId); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the new owner must be a member of the team"))
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		// the new owner is promoted first, so the team has an administrator at any time
+		if err := h.store.SetMemberRole(tx, payload.UserId, teamId, types.Administrator); err != nil {
+			return err
+		}
+
+		if err := h.store.SetMemberRole(tx, callerId, teamId, types.Member); err != nil {
+			return err
+		}
+
+		details := map[string]string{"from": callerId}
+		if err := h.recordHistory(tx, teamId, callerId, types.TeamHistoryOwnershipTransferred, &payload.UserId, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// handleLeaveTeam removes the caller from the team. The undecided requests of the caller
+// in the team are cancelled and the open approvals are handed over to another administrator.
+func (h *Handler) handleLeaveTeam(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	userId := auth.GetUserIdFromContext(r.Context())
+	role, err := h.store.GetUserRoleInTeam(userId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("you are not a member of the team"))
+		return
+	}
+
+	if _, ok := WritableTeam(h.store, w, teamId); !ok {
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.removeMember(tx, teamId, userId, role, userId, types.TeamHistoryMemberLeft); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// removeMember takes the user out of the team. The team keeps an administrator, the
+// undecided requests of the user in the team are cancelled and the open approvals
+// are handed over to another administrator.
+func (h *Handler) removeMember(tx *sql.Tx, teamId, userId string, role types.UserRole, actorId, action string) error {
+	if role == types.Administrator {
+		if err := KeepAnAdministrator(h.store, tx, teamId, userId); err != nil {
+			return err
+		}
+	}
+
+	successorId, err := h.findApprovalSuccessor(userId, teamId)
+	if err != nil {
+		return err
+	}
+
+	if successorId != "" {
+		if err := h.vacationStore.ReassignOpenApprovals(tx, teamId, userId, successorId); err != nil {
+			return err
+		}
+	}
+
+	if err := h.vacationStore.CancelUndecidedRequestsInTeam(tx, userId, teamId); err != nil {
+		return err
+	}
+
+	if err := h.store.RemoveUserFromTeam(tx, userId, teamId); err != nil {
+		return err
+	}
+
+	return h.recordHistory(tx, teamId, actorId, action, &userId, nil)
+}
+
+// findApprovalSuccessor returns the administrator who takes over the open approvals
+// of the user in the team, or an empty id if the user has none
+func (h *Handler) findApprovalSuccessor(userId, teamId string) (string, error) {
+	teamIds, err := h.vacationStore.GetTeamsWithOpenApprovals(userId)
+	if err != nil {
+		return "", err
+	}
+
+	if !slices.Contains(teamIds, teamId) {
+		return "", nil
+	}
+
+	admins, err := h.store.GetTeamAdministrators(teamId)
+	if err != nil {
+		return "", err
+	}
+
+	for _, admin := range admins {
+		if admin.Id != userId {
+			return admin.Id, nil
+		}
+	}
+
+	return "", utils.NewStatusError(http.StatusConflict, fmt.Errorf("the team has no other administrator who can take over your open approvals"))
+}
+
+func (h *Handler) handleGetTeamHistory(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if _, err := h.store.GetUserRoleInTeam(callerId, teamId); err != nil && !auth.IsSuperadmin(h.userStore, callerId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only members of the team can see its history"))
+		return
+	}
+
+	entries, err := h.store.GetTeamHistory(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, entries)
+}
+
+func (h *Handler) isAdministrator(userId, teamId string) bool {
+	return IsAdministrator(h.store, h.userStore, userId, teamId)
+}
+
+// IsAdministrator reports whether the user may manage the team. The administrators
+// of a unit manage all units below it as well.
+func IsAdministrator(store types.TeamStore, userStore types.UserStore, userId, teamId string) bool {
+	if role, err := store.GetUserRoleInTeam(userId, teamId); err == nil && role == types.Administrator {
+		return true
+	}
+
+	ancestorIds, err := store.GetAncestorIds(teamId)
+	if err == nil {
+		for _, ancestorId := range ancestorIds {
+			if role, err := store.GetUserRoleInTeam(userId, ancestorId); err == nil && role == types.Administrator {
+				return true
+			}
+		}
+	}
+
+	return auth.IsSuperadmin(userStore, userId)
+}
+
+// KeepAnAdministrator fails if the user is the last administrator of the team. Every
+// change which takes the administrator role away from a user has to check it.
+func KeepAnAdministrator(store types.TeamStore, tx *sql.Tx, teamId, userId string) error {
+	admins, err := store.LockTeamAdministrators(tx, teamId)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range admins {
+		if id != userId {
+			return nil
+		}
+	}
+
+	return utils.NewStatusError(http.StatusConflict, errLastAdministrator)
+}
+
+func (h *Handler) recordHistory(execable interface{}, teamId, actorId, action string, userId *string, details any) error {
+	marshalled, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return h.store.RecordTeamHistory(execable, types.TeamHistoryEntry{
+		Id:      uuid.NewString(),
+		TeamId:  teamId,
+		ActorId: actorId,
+		Action:  action,
+		UserId:  userId,
+		Details: string(marshalled),
+	})
+}
+
+// memberRoles maps the role filter of the member listing to the roles
+var memberRoles = map[string]types.UserRole{
+	"administrator": types.Administrator,
+	"member":        types.Member,
+}
+
+// handleGetMembers lists the members with their role, whether they are present today
+// and their next absence within a year. ?role=administrator|member filters by role.
+func (h *Handler) handleGetMembers(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var role *types.UserRole
+	if value := r.URL.Query().Get("role"); value != "" {
+		filter, ok := memberRoles[value]
+		if !ok {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("role has to be administrator or member"))
+			return
+		}
+		role = &filter
+	}
+
+	if _, err := h.store.GetTeamById(teamId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	isMember, err := h.isMemberOfUnitOrAbove(callerId, teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !isMember && !auth.IsSuperadmin(h.userStore, callerId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only members of the unit can see its members"))
+		return
+	}
+
+	users, err := h.userStore.GetUsersFromTeam(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	absences, err := h.vacationStore.GetAbsencesOfTeams([]string{teamId}, today, today.AddDate(1, 0, 0))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !h.isAdministrator(callerId, teamId) {
+		absences, err = h.applyLeaveVisibility(callerId, absences)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	members := make([]types.TeamMember, 0, len(users))
+	for _, u := range users {
+		if role != nil && u.RoleType != *role {
+			continue
+		}
+
+		members = append(members, newTeamMember(u, absences, today))
+	}
+
+	utils.WriteJson(w, http.StatusOK, members)
+}
+
+// newTeamMember derives the presence from the approved absences covering today. The
+// absences are ordered by their start, so the first one after today is the next.
+func newTeamMember(u types.TeamUser, absences []types.Absence, today time.Time) types.TeamMember {
+	member := types.TeamMember{TeamUser: u, Presence: types.PresencePresent}
+	for _, absence := range absences {
+		if absence.UserId != u.Id {
+			continue
+		}
+
+		if absence.FromDate.After(today) {
+			if member.NextAbsence == nil {
+				next := absence
+				member.NextAbsence = &next
+			}
+			continue
+		}
+
+		// hidden absences don't carry a status, only approved ones are left of them
+		approved := absence.Status == nil || *absence.Status == types.REQUEST_APPROVED
+		if !approved || absence.ToDate.Before(today) {
+			continue
+		}
+
+		switch absence.Type {
+		case types.AbsenceSick:
+			member.Presence = types.PresenceSick
+		case types.AbsenceVacation:
+			member.Presence = types.PresenceVacation
+		default:
+			member.Presence = types.PresenceAbsent
+		}
+	}
+
+	return member
+}
+
+func teamIdFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	teamId, ok := mux.Vars(r)["teamId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing team id"))
+		return "", false
+	}
+
+	if !utils.IsValidUUID(teamId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return "", false
+	}
+
+	return teamId, true
+}
+
+func memberFromPath(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return "", "", false
+	}
+
+	userId, ok := mux.Vars(r)["userId"]
+	if !ok || !utils.IsValidUUID(userId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user id is not valid"))
+		return "", "", false
+	}
+
+	return teamId, userId, true
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
 	"github.com/go-playground/validator/v10"
@@ -13,26 +15,41 @@ import (
 )
 
 type Handler struct {
-	db        *sql.DB
-	store     types.TeamStore
-	userStore types.UserStore
+	db            *sql.DB
+	store         types.TeamStore
+	userStore     types.UserStore
+	vacationStore types.VacationStore
+	inviteStore   types.InviteStore
 }
 
-func NewHandler(db *sql.DB, store types.TeamStore, userStore types.UserStore) *Handler {
-	return &Handler{db: db, store: store, userStore: userStore}
+func NewHandler(db *sql.DB, store types.TeamStore, userStore types.UserStore, vacationStore types.VacationStore, inviteStore types.InviteStore) *Handler {
+	return &Handler{db: db, store: store, userStore: userStore, vacationStore: vacationStore, inviteStore: inviteStore}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/teams", h.handleGetAllTeams).Methods(http.MethodGet)
+	router.HandleFunc("/teams", auth.Require(h.handleGetAllTeams, h.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/teams/{teamId}", h.handleGetTeam).Methods(http.MethodGet)
-	router.HandleFunc("/teams", h.handleAddTeam).Methods(http.MethodPost)
+	router.HandleFunc("/teams", auth.Require(h.handleAddTeam, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPost)
 	// accept an invite will add the user
 	// router.HandleFunc("/teams/addUser", h.handleAddUserToTeam).Methods(http.MethodPost)
-	router.HandleFunc("/teams/removeUser", h.handleRemoveUserFromTeam).Methods(http.MethodPost)
-	router.HandleFunc("/teams/{teamId}/getUsers", h.handleGetUsersFromTeam).Methods(http.MethodGet)
+	router.HandleFunc("/teams/removeUser", auth.Require(h.handleRemoveUserFromTeam, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/members", auth.Require(h.handleGetMembers, h.userStore)).Methods(http.MethodGet)
+	// the former name of the member listing
+	router.HandleFunc("/teams/{teamId}/getUsers", auth.Require(h.handleGetMembers, h.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/teams/{teamId}", h.handleRenameTeam).Methods(http.MethodPatch)
-
-	// router.HandleFunc("/teams", auth.Require(h.handleAddTeam, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/members/{userId}", auth.Require(h.handleUpdateMemberRole, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPatch)
+	router.HandleFunc("/teams/{teamId}/move", auth.Require(h.handleMoveTeam, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/calendar", auth.Require(h.handleGetCalendar, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/teams/{teamId}/leave", auth.Require(h.handleLeaveTeam, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/transfer-ownership", auth.Require(h.handleTransferOwnership, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/history", auth.Require(h.handleGetTeamHistory, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/teams/{teamId}/settings", auth.Require(h.handleGetTeamSettings, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/teams/{teamId}/settings", auth.Require(h.handleUpdateTeamSettings, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPut)
+	router.HandleFunc("/teams/{teamId}/settings/versions", auth.Require(h.handleGetTeamSettingsVersions, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodGet)
+	router.HandleFunc("/teams/{teamId}/archive", auth.Require(h.handleArchiveTeam, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/archive", auth.Require(h.handleUnarchiveTeam, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodDelete)
+	router.HandleFunc("/teams/{teamId}/deletion-preview", auth.Require(h.handleGetDeletionPreview, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodGet)
+	router.HandleFunc("/teams/{teamId}", auth.Require(h.handleDeleteTeam, h.userStore, types.ScopeAdminTeams)).Methods(http.MethodDelete)
 }
 
 func (h *Handler) handleRemoveUserFromTeam(w http.ResponseWriter, r *http.Request) {
@@ -48,12 +65,32 @@ func (h *Handler) handleRemoveUserFromTeam(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.store.RemoveUserFromTeam(userTeamPayload.UserId, userTeamPayload.TeamId); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error while remove user from team. error: %e", err))
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, userTeamPayload.TeamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can remove members"))
 		return
 	}
 
-	utils.WriteJson(w, http.StatusOK, nil)
+	if _, ok := WritableTeam(h.store, w, userTeamPayload.TeamId); !ok {
+		return
+	}
+
+	role, err := h.store.GetUserRoleInTeam(userTeamPayload.UserId, userTeamPayload.TeamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		err := h.removeMember(tx, userTeamPayload.TeamId, userTeamPayload.UserId, role, callerId, types.TeamHistoryMemberRemoved)
+		if err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
 }
 
 func (h *Handler) handleRenameTeam(w http.ResponseWriter, r *http.Request) {
@@ -81,6 +118,10 @@ func (h *Handler) handleRenameTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := WritableTeam(h.store, w, id); !ok {
+		return
+	}
+
 	if err := h.store.RenameTeam(renamePayload.Name, id); err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -102,11 +143,17 @@ func (h *Handler) handleAddUserToTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := h.store.GetTeamById(payload.TeamId); err != nil {
+	team, err := h.store.GetTeamById(payload.TeamId)
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("team with id %s does not exists", payload.TeamId))
 		return
 	}
 
+	if team.IsArchived() {
+		utils.WriteError(w, http.StatusConflict, errTeamArchived)
+		return
+	}
+
 	if _, err := h.userStore.GetUserById(payload.UserId); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user with id %s does not exists", payload.UserId))
 		return
@@ -169,30 +216,31 @@ func (h *Handler) handleGetTeam(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJson(w, http.StatusOK, team)
 }
 
-func (h *Handler) handleGetUsersFromTeam(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, ok := vars["teamId"]
-	if !ok {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing team id"))
+// handleGetAllTeams lists the teams page by page. Archived teams are hidden unless
+// ?archived=true is given, ?name=, ?mine=true and ?administered=true narrow the list.
+func (h *Handler) handleGetAllTeams(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.ParsePageRequest(r, teamSortColumns, "name")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if !utils.IsValidUUID(id) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
-		return
+	query := r.URL.Query()
+	userId := auth.GetUserIdFromContext(r.Context())
+	filter := types.TeamFilter{
+		Name:            query.Get("name"),
+		IncludeArchived: query.Get("archived") == "true",
 	}
 
-	users, err := h.userStore.GetUsersFromTeam(id)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
+	if query.Get("mine") == "true" {
+		filter.MemberId = userId
 	}
 
-	utils.WriteJson(w, http.StatusOK, users)
-}
+	if query.Get("administered") == "true" {
+		filter.AdministratorId = userId
+	}
 
-func (h *Handler) handleGetAllTeams(w http.ResponseWriter, r *http.Request) {
-	teams, err := h.store.GetAllTeams()
+	teams, err := h.store.ListTeams(filter, page)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -201,6 +249,8 @@ func (h *Handler) handleGetAllTeams(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJson(w, http.StatusOK, teams)
 }
 
+// handleAddTeam creates the team with the caller as administrator, so the team
+// always has someone who can invite others
 func (h *Handler) handleAddTeam(w http.ResponseWriter, r *http.Request) {
 	var payload types.AddTeamPayload
 	if err := utils.ParseJson(r, &payload); err != nil {
@@ -208,9 +258,11 @@ func (h *Handler) handleAddTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := utils.Validate.Struct(payload); err != nil {
-		errors := err.(validator.ValidationErrors)
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	if payload.Settings != nil && !utils.ValidatePayload(w, *payload.Settings) {
 		return
 	}
 
@@ -219,16 +271,132 @@ func (h *Handler) handleAddTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	creatorId := auth.GetUserIdFromContext(r.Context())
+	members, invitees, err := h.splitInitialMembers(creatorId, payload.Members)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	team := types.Team{
-		Id:   uuid.NewString(),
-		Name: payload.Name,
+		Id:       uuid.NewString(),
+		Name:     payload.Name,
+		ParentId: payload.ParentId,
+		UnitType: payload.UnitType,
+	}
+
+	if team.UnitType == "" {
+		team.UnitType = types.UnitTeam
+	}
+
+	if team.ParentId != nil {
+		status, err := h.checkParent(creatorId, &team, *team.ParentId)
+		if err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
 	}
 
-	err := h.store.CreateTeam(team)
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.CreateTeam(tx, team); err != nil {
+			return err
+		}
+
+		if err := h.store.AddUserToTeam(tx, creatorId, team.Id, types.Administrator); err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			if err := h.store.AddUserToTeam(tx, member.UserId, team.Id, *member.RoleType); err != nil {
+				return err
+			}
+		}
+
+		settings := types.DefaultTeamSettings()
+		if payload.Settings != nil {
+			settings = payload.Settings
+			if err := h.store.SaveTeamSettings(tx, team.Id, *settings, creatorId); err != nil {
+				return err
+			}
+			settings.Normalize()
+		}
+
+		expiresAt := time.Now().UTC().AddDate(0, 0, settings.InviteExpiryDays)
+		for _, invitee := range invitees {
+			err := h.inviteStore.CreateInvite(tx, types.Invite{
+				Id:         uuid.NewString(),
+				InviteType: types.Group_Invite,
+				FromUserId: creatorId,
+				ToUserId:   invitee.UserId,
+				TeamId:     team.Id,
+				Status:     types.INVITE_OPEN,
+				ExpiresAt:  &expiresAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		details := map[string]int{"members": len(members) + 1, "invites": len(invitees)}
+		if err := h.recordHistory(tx, team.Id, creatorId, types.TeamHistoryCreated, nil, details); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusCreated, team)
+		return nil
+	})
+}
+
+// splitInitialMembers makes sure every initial member is an active user and listed once.
+// Superadmins add all of them directly, other creators only the users they already
+// administer in another team. Everybody else gets an invite and joins as member.
+func (h *Handler) splitInitialMembers(creatorId string, initial []types.InitialMember) ([]types.InitialMember, []types.InitialMember, error) {
+	isSuperadmin := auth.IsSuperadmin(h.userStore, creatorId)
+	members := make([]types.InitialMember, 0)
+	invitees := make([]types.InitialMember, 0)
+	seen := map[string]bool{creatorId: true}
+	for _, member := range initial {
+		if seen[member.UserId] {
+			return nil, nil, fmt.Errorf("user %s is listed more than once", member.UserId)
+		}
+		seen[member.UserId] = true
+
+		u, err := h.userStore.GetUserById(member.UserId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("user %s not found", member.UserId)
+		}
+
+		if u.DeactivatedAt != nil {
+			return nil, nil, fmt.Errorf("user %s is deactivated", member.UserId)
+		}
+
+		if isSuperadmin || h.administersUser(creatorId, member.UserId) {
+			members = append(members, member)
+			continue
+		}
+
+		if *member.RoleType == types.Administrator {
+			return nil, nil, fmt.Errorf("user %s can only be invited as member", member.UserId)
+		}
+		invitees = append(invitees, member)
+	}
+
+	return members, invitees, nil
+}
+
+// administersUser reports whether the user is a member of a team the administrator manages
+func (h *Handler) administersUser(administratorId, userId string) bool {
+	teams, err := h.store.GetTeamsOfUser(userId)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
+		return false
+	}
+
+	for _, membership := range teams {
+		if h.isAdministrator(administratorId, membership.TeamId) {
+			return true
+		}
 	}
 
-	utils.WriteJson(w, http.StatusCreated, team)
+	return false
 }
@@ -2,34 +2,83 @@ package team
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/vacation"
 	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTeamServiceHandlers(t *testing.T) {
-	db, _, err := sqlmock.New()
+	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
+	creatorId := uuid.NewString()
+	memberId := uuid.NewString()
+	strangerId := uuid.NewString()
+	administeredTeamId := uuid.NewString()
+	members := map[string]types.UserRole{}
+	invited := []string{}
+	var settings *types.TeamSettings
 	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		if userId == memberId {
+			return []types.UserTeam{{TeamId: administeredTeamId, RoleType: types.Member}}, nil
+		}
+		return []types.UserTeam{}, nil
+	}
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) {
+		if userId == creatorId && teamId == administeredTeamId {
+			return types.Administrator, nil
+		}
+		return types.Member, fmt.Errorf("user is not a member of the team")
+	}
+	teamStore.GetAncestorIdsMock = func(teamId string) ([]string, error) { return []string{}, nil }
 	teamStore.GetTeamByNameMock = func(name string) (*types.Team, error) { return nil, fmt.Errorf("Not found") }
-	teamStore.CreateTeamMock = func(t types.Team) error { return nil }
+	teamStore.CreateTeamMock = func(execable interface{}, t types.Team) error { return nil }
+	teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error {
+		members[userId] = role
+		return nil
+	}
+	teamStore.SaveTeamSettingsMock = func(execable interface{}, teamId string, s types.TeamSettings, changedBy string) error {
+		settings = &s
+		return nil
+	}
+	teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error { return nil }
 
 	userStore := &mockUser{}
-	handler := NewHandler(db, teamStore, userStore)
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+	inviteStore := &mockInvite{}
+	inviteStore.CreateInviteMock = func(execable interface{}, inv types.Invite) error {
+		require.Equal(t, creatorId, inv.FromUserId)
+		require.NotNil(t, inv.ExpiresAt)
+		invited = append(invited, inv.ToUserId)
+		return nil
+	}
+	handler := NewHandler(db, teamStore, userStore, &mockVacation{}, inviteStore)
 
 	t.Run("should run if team is created",
 		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
 			payload := types.AddTeamPayload{
-				Name: "Team A",
+				Name:     "Team A",
+				Members:  []types.InitialMember{{UserId: memberId, RoleType: userRole(types.Member)}, {UserId: strangerId, RoleType: userRole(types.Member)}},
+				Settings: &types.TeamSettings{HolidayRegion: "DE-BY"},
 			}
 
 			marshalled, _ := json.Marshal(payload)
@@ -37,6 +86,7 @@ func TestTeamServiceHandlers(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, creatorId))
 
 			testHttp := httptest.NewRecorder()
 			router := mux.NewRouter()
@@ -44,9 +94,117 @@ func TestTeamServiceHandlers(t *testing.T) {
 			router.ServeHTTP(testHttp, req)
 
 			require.Equal(t, http.StatusCreated, testHttp.Code)
+			require.Equal(t, types.Administrator, members[creatorId])
+			require.Equal(t, types.Member, members[memberId])
+			require.NotContains(t, members, strangerId)
+			require.Equal(t, []string{strangerId}, invited)
+			require.NotNil(t, settings)
+			require.Equal(t, "DE-BY", settings.HolidayRegion)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+	t.Run("should fail if the creator is listed as initial member",
+		func(t *testing.T) {
+			payload := types.AddTeamPayload{
+				Name:    "Team B",
+				Members: []types.InitialMember{{UserId: creatorId, RoleType: userRole(types.Member)}},
+			}
+
+			marshalled, _ := json.Marshal(payload)
+			req, err := http.NewRequest(http.MethodPost, "/teams", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, creatorId))
+
+			testHttp := httptest.NewRecorder()
+			handler.handleAddTeam(testHttp, req)
+
+			require.Equal(t, http.StatusBadRequest, testHttp.Code)
+		})
+
+	t.Run("should fail if a user who must be invited is listed as administrator",
+		func(t *testing.T) {
+			payload := types.AddTeamPayload{
+				Name:    "Team C",
+				Members: []types.InitialMember{{UserId: strangerId, RoleType: userRole(types.Administrator)}},
+			}
+
+			marshalled, _ := json.Marshal(payload)
+			req, err := http.NewRequest(http.MethodPost, "/teams", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, creatorId))
+
+			testHttp := httptest.NewRecorder()
+			handler.handleAddTeam(testHttp, req)
+
+			require.Equal(t, http.StatusBadRequest, testHttp.Code)
+			require.NotContains(t, members, strangerId)
+		})
+	t.Run("should fail if the role of an initial member is missing",
+		func(t *testing.T) {
+			payload := `{"name":"Team D","members":[{"userId":"` + memberId + `"}]}`
+			req, err := http.NewRequest(http.MethodPost, "/teams", strings.NewReader(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, creatorId))
+
+			testHttp := httptest.NewRecorder()
+			handler.handleAddTeam(testHttp, req)
+
+			require.Equal(t, http.StatusBadRequest, testHttp.Code)
 		})
 }
 
+func Test_CreateTeam_Should_AddInitialMembersDirectly_IfCreatorIsSuperadmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	superadminId := uuid.NewString()
+	strangerId := uuid.NewString()
+	members := map[string]types.UserRole{}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamByNameMock = func(name string) (*types.Team, error) { return nil, fmt.Errorf("Not found") }
+	teamStore.CreateTeamMock = func(execable interface{}, t types.Team) error { return nil }
+	teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error {
+		members[userId] = role
+		return nil
+	}
+	teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error { return nil }
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		if id == superadminId {
+			return &types.User{Id: id, SystemRole: types.SystemRoleSuperadmin}, nil
+		}
+		return &types.User{Id: id}, nil
+	}
+	handler := NewHandler(db, teamStore, userStore, &mockVacation{}, &mockInvite{})
+
+	payload := types.AddTeamPayload{
+		Name:    "Team A",
+		Members: []types.InitialMember{{UserId: strangerId, RoleType: userRole(types.Administrator)}},
+	}
+	marshalled, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, "/teams", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, superadminId))
+
+	testHttp := httptest.NewRecorder()
+	handler.handleAddTeam(testHttp, req)
+
+	require.Equal(t, http.StatusCreated, testHttp.Code, testHttp.Body.String())
+	require.Equal(t, types.Administrator, members[strangerId])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func Test_CreateTeam_Should_Fail_IfTeamAlreadyExists(t *testing.T) {
 	db, _, err := sqlmock.New()
 	require.NoError(t, err)
@@ -55,7 +213,7 @@ func Test_CreateTeam_Should_Fail_IfTeamAlreadyExists(t *testing.T) {
 	teamStore.GetTeamByNameMock = func(name string) (*types.Team, error) { return &types.Team{}, nil }
 	userStore := &mockUser{}
 	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return nil, fmt.Errorf("user does not exists") }
-	handler := NewHandler(db, teamStore, userStore)
+	handler := NewHandler(db, teamStore, userStore, &mockVacation{}, &mockInvite{})
 	payload := types.AddTeamPayload{
 		Name: "Team A",
 	}
@@ -83,7 +241,7 @@ func Test_AddUserToTeam_Should_Fail_IfUserDontExists(t *testing.T) {
 	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{}, nil }
 	userStore := &mockUser{}
 	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return nil, fmt.Errorf("user does not exists") }
-	handler := NewHandler(db, teamStore, userStore)
+	handler := NewHandler(db, teamStore, userStore, &mockVacation{}, &mockInvite{})
 	payload := types.UserToTeamPayload{
 		UserId:   uuid.NewString(),
 		TeamId:   uuid.NewString(),
@@ -112,7 +270,7 @@ func Test_AddUserToTeam_Should_Fail_IfTeamDontExists(t *testing.T) {
 	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return nil, fmt.Errorf("team does not exists") }
 	userStore := &mockUser{}
 	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{}, nil }
-	handler := NewHandler(db, teamStore, userStore)
+	handler := NewHandler(db, teamStore, userStore, &mockVacation{}, &mockInvite{})
 	payload := types.UserToTeamPayload{
 		UserId:   uuid.NewString(),
 		TeamId:   uuid.NewString(),
@@ -134,10 +292,44 @@ func Test_AddUserToTeam_Should_Fail_IfTeamDontExists(t *testing.T) {
 }
 
 type mockUser struct {
-	GetUserByEmailMock   func(email string) (*types.User, error)
-	GetUserByIdMock      func(id string) (*types.User, error)
-	CreateUserMock       func(types.User) error
-	GetUsersFromTeamMock func(teamId string) ([]types.TeamUser, error)
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
 }
 
 func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
@@ -146,48 +338,1401 @@ func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
 func (m *mockUser) GetUserById(id string) (*types.User, error) {
 	return m.GetUserByIdMock(id)
 }
-func (m *mockUser) CreateUser(u types.User) error {
-	return m.CreateUserMock(u)
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
 }
 
 func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
 	return m.GetUsersFromTeamMock(teamId)
 }
 
-type mockTeam struct {
-	GetAllTeamsMock        func() ([]types.Team, error)
-	CreateTeamMock         func(types.Team) error
-	RenameTeamMock         func(name, teamId string) error
-	GetTeamByIdMock        func(id string) (*types.Team, error)
-	GetTeamByNameMock      func(name string) (*types.Team, error)
-	AddUserToTeamMock      func(execable interface{}, userId, teamId string, role types.UserRole) error
-	RemoveUserFromTeamMock func(userId, teamId string) error
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
 }
 
-func (m *mockTeam) GetAllTeams() ([]types.Team, error) {
-	return m.GetAllTeamsMock()
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
 }
 
-func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
-	return m.GetTeamByIdMock(id)
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
 }
 
-func (m *mockTeam) CreateTeam(t types.Team) error {
-	return m.CreateTeamMock(t)
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
 }
 
-func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
-	return m.GetTeamByNameMock(name)
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
 }
 
-func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
-	return m.AddUserToTeamMock(execable, userId, teamId, role)
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
 }
 
-func (m *mockTeam) RemoveUserFromTeam(userId, teamId string) error {
-	return m.RemoveUserFromTeamMock(userId, teamId)
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
 }
 
-func (m *mockTeam) RenameTeam(name, teamId string) error {
-	return nil
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
+
+func Test_UpdateMemberRole(t *testing.T) {
+	teamId := uuid.NewString()
+	adminId := uuid.NewString()
+	otherAdminId := uuid.NewString()
+	memberId := uuid.NewString()
+
+	tests := []struct {
+		name     string
+		callerId string
+		userId   string
+		role     *types.UserRole
+		admins   []string
+		status   int
+	}{
+		{"should promote members", adminId, memberId, userRole(types.Administrator), []string{adminId}, http.StatusOK},
+		{"should demote administrators if another one is left", adminId, otherAdminId, userRole(types.Member), []string{adminId, otherAdminId}, http.StatusOK},
+		{"should fail to demote the last administrator", adminId, adminId, userRole(types.Member), []string{adminId}, http.StatusConflict},
+		{"should fail if the caller is no administrator", memberId, memberId, userRole(types.Administrator), []string{adminId}, http.StatusForbidden},
+		{"should fail without a role instead of promoting", adminId, memberId, nil, []string{adminId}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			switch tt.status {
+			case http.StatusOK:
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			case http.StatusConflict:
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			}
+
+			roles := map[string]types.UserRole{memberId: types.Member}
+			for _, id := range tt.admins {
+				roles[id] = types.Administrator
+			}
+			var history []types.TeamHistoryEntry
+			teamStore := &mockTeam{}
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) {
+				role, ok := roles[userId]
+				if !ok {
+					return 0, fmt.Errorf("user is not a member of the team")
+				}
+				return role, nil
+			}
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.GetAncestorIdsMock = func(team string) ([]string, error) { return []string{}, nil }
+			teamStore.LockTeamAdministratorsMock = func(execable interface{}, team string) ([]string, error) { return tt.admins, nil }
+			teamStore.SetMemberRoleMock = func(execable interface{}, userId, team string, role types.UserRole) error {
+				roles[userId] = role
+				return nil
+			}
+			teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error {
+				history = append(history, entry)
+				return nil
+			}
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+				return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+			}
+			handler := NewHandler(db, teamStore, userStore, &mockVacation{}, &mockInvite{})
+
+			marshalled, _ := json.Marshal(types.UpdateMemberRolePayload{RoleType: tt.role})
+			req, err := http.NewRequest(http.MethodPatch, "/teams/"+teamId+"/members/"+tt.userId, bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, tt.callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/{teamId}/members/{userId}", handler.handleUpdateMemberRole).Methods(http.MethodPatch)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status == http.StatusOK {
+				require.Equal(t, *tt.role, roles[tt.userId])
+				require.Len(t, history, 1)
+				require.Equal(t, types.TeamHistoryRoleChanged, history[0].Action)
+				require.Equal(t, tt.userId, *history[0].UserId)
+			} else {
+				require.Empty(t, history)
+			}
+		})
+	}
+}
+
+func Test_TransferOwnership_Should_SwapRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	teamId := uuid.NewString()
+	ownerId := uuid.NewString()
+	memberId := uuid.NewString()
+	roles := map[string]types.UserRole{ownerId: types.Administrator, memberId: types.Member}
+	var history []types.TeamHistoryEntry
+	teamStore := &mockTeam{}
+	teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return roles[userId], nil }
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+	teamStore.SetMemberRoleMock = func(execable interface{}, userId, team string, role types.UserRole) error {
+		roles[userId] = role
+		return nil
+	}
+	teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error {
+		history = append(history, entry)
+		return nil
+	}
+	handler := NewHandler(db, teamStore, &mockUser{}, &mockVacation{}, &mockInvite{})
+
+	marshalled, _ := json.Marshal(types.TransferOwnershipPayload{UserId: memberId})
+	req, err := http.NewRequest(http.MethodPost, "/teams/"+teamId+"/transfer-ownership", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, ownerId))
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/teams/{teamId}/transfer-ownership", handler.handleTransferOwnership).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	require.Equal(t, types.Member, roles[ownerId])
+	require.Equal(t, types.Administrator, roles[memberId])
+	require.Len(t, history, 1)
+	require.Equal(t, types.TeamHistoryOwnershipTransferred, history[0].Action)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_LeaveTeam(t *testing.T) {
+	teamId := uuid.NewString()
+	adminId := uuid.NewString()
+	memberId := uuid.NewString()
+
+	tests := []struct {
+		name     string
+		callerId string
+		admins   []string
+		status   int
+	}{
+		{"should hand open approvals over to another administrator", memberId, []string{adminId}, http.StatusOK},
+		{"should let an administrator leave if another one is left", adminId, []string{adminId, memberId}, http.StatusOK},
+		{"should fail for the last administrator", adminId, []string{adminId}, http.StatusConflict},
+		{"should fail if the caller is no member", uuid.NewString(), []string{adminId}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			switch tt.status {
+			case http.StatusOK:
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			case http.StatusConflict:
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			}
+
+			roles := map[string]types.UserRole{memberId: types.Member}
+			for _, id := range tt.admins {
+				roles[id] = types.Administrator
+			}
+			removed, cancelled, successor := "", "", ""
+			teamStore := &mockTeam{}
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) {
+				role, ok := roles[userId]
+				if !ok {
+					return 0, fmt.Errorf("user is not a member of the team")
+				}
+				return role, nil
+			}
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.GetTeamAdministratorsMock = func(team string) ([]types.TeamUser, error) {
+				admins := make([]types.TeamUser, 0)
+				for _, id := range tt.admins {
+					admins = append(admins, types.TeamUser{Id: id})
+				}
+				return admins, nil
+			}
+			teamStore.LockTeamAdministratorsMock = func(execable interface{}, team string) ([]string, error) { return tt.admins, nil }
+			teamStore.RemoveUserFromTeamMock = func(execable interface{}, userId, team string) error {
+				removed = userId
+				return nil
+			}
+			teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error { return nil }
+			vacationStore := &mockVacation{}
+			vacationStore.GetTeamsWithOpenApprovalsMock = func(approverId string) ([]string, error) { return []string{teamId}, nil }
+			vacationStore.ReassignOpenApprovalsMock = func(execable interface{}, team, fromApproverId, toApproverId string) error {
+				successor = toApproverId
+				return nil
+			}
+			vacationStore.CancelUndecidedRequestsInTeamMock = func(execable interface{}, userId, team string) error {
+				cancelled = userId
+				return nil
+			}
+			handler := NewHandler(db, teamStore, &mockUser{}, vacationStore, &mockInvite{})
+
+			req, err := http.NewRequest(http.MethodPost, "/teams/"+teamId+"/leave", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, tt.callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/{teamId}/leave", handler.handleLeaveTeam).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status == http.StatusOK {
+				require.Equal(t, tt.callerId, removed)
+				require.Equal(t, tt.callerId, cancelled)
+				require.NotEmpty(t, successor)
+				require.NotEqual(t, tt.callerId, successor)
+			} else {
+				require.Empty(t, removed)
+			}
+		})
+	}
+}
+
+func Test_RemoveUserFromTeam(t *testing.T) {
+	teamId := uuid.NewString()
+	adminId := uuid.NewString()
+	otherAdminId := uuid.NewString()
+	memberId := uuid.NewString()
+
+	tests := []struct {
+		name     string
+		callerId string
+		userId   string
+		admins   []string
+		status   int
+	}{
+		{"should remove a member", adminId, memberId, []string{adminId}, http.StatusOK},
+		{"should remove an administrator if another one is left", adminId, otherAdminId, []string{adminId, otherAdminId}, http.StatusOK},
+		{"should fail for the last administrator", adminId, adminId, []string{adminId}, http.StatusConflict},
+		{"should fail if the caller is no administrator", memberId, adminId, []string{adminId}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			switch tt.status {
+			case http.StatusOK:
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			case http.StatusConflict:
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			}
+
+			roles := map[string]types.UserRole{memberId: types.Member}
+			for _, id := range tt.admins {
+				roles[id] = types.Administrator
+			}
+			removed, action := "", ""
+			teamStore := &mockTeam{}
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) {
+				role, ok := roles[userId]
+				if !ok {
+					return 0, fmt.Errorf("user is not a member of the team")
+				}
+				return role, nil
+			}
+			teamStore.GetAncestorIdsMock = func(id string) ([]string, error) { return []string{}, nil }
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.LockTeamAdministratorsMock = func(execable interface{}, team string) ([]string, error) { return tt.admins, nil }
+			teamStore.RemoveUserFromTeamMock = func(execable interface{}, userId, team string) error {
+				removed = userId
+				return nil
+			}
+			teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error {
+				action = entry.Action
+				return nil
+			}
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+			vacationStore := &mockVacation{}
+			vacationStore.GetTeamsWithOpenApprovalsMock = func(approverId string) ([]string, error) { return []string{}, nil }
+			vacationStore.CancelUndecidedRequestsInTeamMock = func(execable interface{}, userId, team string) error { return nil }
+			handler := NewHandler(db, teamStore, userStore, vacationStore, &mockInvite{})
+
+			payload := types.UserToTeamPayload{UserId: tt.userId, TeamId: teamId, RoleType: types.Member}
+			marshalled, _ := json.Marshal(payload)
+			req, err := http.NewRequest(http.MethodPost, "/teams/removeUser", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, tt.callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/removeUser", handler.handleRemoveUserFromTeam).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status == http.StatusOK {
+				require.Equal(t, tt.userId, removed)
+				require.Equal(t, types.TeamHistoryMemberRemoved, action)
+			} else {
+				require.Empty(t, removed)
+			}
+		})
+	}
+}
+
+func Test_MoveTeam(t *testing.T) {
+	callerId := uuid.NewString()
+	teamId := uuid.NewString()
+	childId := uuid.NewString()
+	departmentId := uuid.NewString()
+	otherTeamId := uuid.NewString()
+	units := map[string]*types.Team{
+		teamId:       {Id: teamId, UnitType: types.UnitTeam, ParentId: &departmentId},
+		departmentId: {Id: departmentId, UnitType: types.UnitDepartment},
+		otherTeamId:  {Id: otherTeamId, UnitType: types.UnitTeam},
+		childId:      {Id: childId, UnitType: types.UnitDepartment},
+	}
+
+	tests := []struct {
+		name     string
+		unitId   string
+		parentId *string
+		status   int
+	}{
+		{"should move a team below a department", teamId, &departmentId, http.StatusOK},
+		{"should fail to move a unit below a unit of the same level", teamId, &otherTeamId, http.StatusBadRequest},
+		{"should fail to move a unit below itself", departmentId, &childId, http.StatusConflict},
+		{"should fail to move a unit to the top without being superadmin", teamId, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			var movedTo *string
+			teamStore := &mockTeam{}
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return types.Administrator, nil }
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return units[id], nil }
+			teamStore.GetDescendantIdsMock = func(team string) ([]string, error) {
+				if team == departmentId {
+					return []string{teamId, childId}, nil
+				}
+				return []string{}, nil
+			}
+			teamStore.MoveTeamMock = func(execable interface{}, team string, parentId *string) error {
+				movedTo = parentId
+				return nil
+			}
+			teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error { return nil }
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+				return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+			}
+			handler := NewHandler(db, teamStore, userStore, &mockVacation{}, &mockInvite{})
+
+			marshalled, _ := json.Marshal(types.MoveTeamPayload{ParentId: tt.parentId})
+			req, err := http.NewRequest(http.MethodPost, "/teams/"+tt.unitId+"/move", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/{teamId}/move", handler.handleMoveTeam).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			if tt.status == http.StatusOK {
+				require.Equal(t, tt.parentId, movedTo)
+			} else {
+				require.Nil(t, movedTo)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_GetCalendar_Should_Aggregate_UnitsBelow(t *testing.T) {
+	memberId := uuid.NewString()
+	colleagueId := uuid.NewString()
+	departmentId := uuid.NewString()
+	teamIds := []string{uuid.NewString(), uuid.NewString()}
+	approved, open := types.REQUEST_APPROVED, types.REQUEST_OPEN
+	var queried []string
+	teamStore := &mockTeam{}
+	teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) {
+		if team == departmentId {
+			return types.Member, nil
+		}
+		return 0, fmt.Errorf("user is not a member of the team")
+	}
+	teamStore.GetAncestorIdsMock = func(team string) ([]string, error) { return []string{}, nil }
+	teamStore.GetDescendantIdsMock = func(team string) ([]string, error) { return teamIds, nil }
+	teamStore.GetTeamSettingsMock = func(team string) (*types.TeamSettings, error) {
+		settings := types.DefaultTeamSettings()
+		if team == teamIds[0] {
+			settings.LeaveVisibility = types.LeaveVisibilityAbsent
+		}
+		return settings, nil
+	}
+	vacationStore := &mockVacation{}
+	vacationStore.GetAbsencesOfTeamsMock = func(ids []string, from, to time.Time) ([]types.Absence, error) {
+		queried = ids
+		return []types.Absence{
+			{RequestId: uuid.NewString(), UserId: colleagueId, TeamId: teamIds[0], Status: &approved},
+			{RequestId: uuid.NewString(), UserId: colleagueId, TeamId: teamIds[0], Status: &open},
+			{RequestId: uuid.NewString(), UserId: colleagueId, TeamId: teamIds[1], Status: &open},
+		}, nil
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+	}
+	handler := NewHandler(nil, teamStore, userStore, vacationStore, &mockInvite{})
+
+	req, err := http.NewRequest(http.MethodGet, "/teams/"+departmentId+"/calendar?from=2026-11-01&to=2026-11-30", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, memberId))
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/teams/{teamId}/calendar", handler.handleGetCalendar).Methods(http.MethodGet)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	require.Equal(t, append([]string{departmentId}, teamIds...), queried)
+
+	var absences []types.Absence
+	require.NoError(t, json.NewDecoder(testHttp.Body).Decode(&absences))
+	require.Len(t, absences, 2)
+	// the first team only shows that somebody is absent
+	require.Empty(t, absences[0].RequestId)
+	require.Nil(t, absences[0].Status)
+	require.Equal(t, teamIds[1], absences[1].TeamId)
+	require.NotNil(t, absences[1].Status)
+}
+
+func Test_GetMembers(t *testing.T) {
+	teamId := uuid.NewString()
+	callerId := uuid.NewString()
+	adminId := uuid.NewString()
+	colleagueId := uuid.NewString()
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	approved, open := types.REQUEST_APPROVED, types.REQUEST_OPEN
+
+	tests := []struct {
+		name       string
+		query      string
+		visibility string
+		presences  map[string]types.Presence
+	}{
+		{"should show why members are absent", "", types.LeaveVisibilityDetails,
+			map[string]types.Presence{callerId: types.PresencePresent, adminId: types.PresenceSick, colleagueId: types.PresenceVacation}},
+		{"should only show that members are absent", "", types.LeaveVisibilityAbsent,
+			map[string]types.Presence{callerId: types.PresencePresent, adminId: types.PresenceAbsent, colleagueId: types.PresenceAbsent}},
+		{"should filter by role", "?role=administrator", types.LeaveVisibilityDetails,
+			map[string]types.Presence{adminId: types.PresenceSick}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teamStore := &mockTeam{}
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return types.Member, nil }
+			teamStore.GetAncestorIdsMock = func(team string) ([]string, error) { return []string{}, nil }
+			teamStore.GetTeamSettingsMock = func(team string) (*types.TeamSettings, error) {
+				settings := types.DefaultTeamSettings()
+				settings.LeaveVisibility = tt.visibility
+				return settings, nil
+			}
+			userStore := &mockUser{}
+			userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+				return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+			}
+			userStore.GetUsersFromTeamMock = func(team string) ([]types.TeamUser, error) {
+				return []types.TeamUser{
+					{Id: callerId, RoleType: types.Member},
+					{Id: adminId, RoleType: types.Administrator},
+					{Id: colleagueId, RoleType: types.Member},
+				}, nil
+			}
+			vacationStore := &mockVacation{}
+			vacationStore.GetAbsencesOfTeamsMock = func(ids []string, from, to time.Time) ([]types.Absence, error) {
+				return []types.Absence{
+					{UserId: adminId, TeamId: teamId, Type: types.AbsenceSick, Status: &approved, FromDate: today.AddDate(0, 0, -1), ToDate: today},
+					{UserId: colleagueId, TeamId: teamId, Type: types.AbsenceVacation, Status: &approved, FromDate: today, ToDate: today.AddDate(0, 0, 2)},
+					{UserId: callerId, TeamId: teamId, Type: types.AbsenceVacation, Status: &open, FromDate: today.AddDate(0, 0, 5), ToDate: today.AddDate(0, 0, 6)},
+				}, nil
+			}
+			handler := NewHandler(nil, teamStore, userStore, vacationStore, &mockInvite{})
+
+			req, err := http.NewRequest(http.MethodGet, "/teams/"+teamId+"/members"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/{teamId}/members", handler.handleGetMembers).Methods(http.MethodGet)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+
+			var members []types.TeamMember
+			require.NoError(t, json.NewDecoder(testHttp.Body).Decode(&members))
+			presences := make(map[string]types.Presence)
+			for _, member := range members {
+				presences[member.Id] = member.Presence
+				if member.Id == callerId {
+					// the own requests stay visible before they are approved
+					require.NotNil(t, member.NextAbsence)
+				}
+			}
+			require.Equal(t, tt.presences, presences)
+		})
+	}
+}
+
+func Test_GetMembers_Should_Show_Vacation_OnceApprovedByAllApprovers(t *testing.T) {
+	teamId := uuid.NewString()
+	requesterId := uuid.NewString()
+	leadId := uuid.NewString()
+	substituteId := uuid.NewString()
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	vacationStore := &decidingVacation{
+		mockVacation: &mockVacation{},
+		request: types.VacationRequest{Id: uuid.NewString(), RequestedFrom: requesterId, TeamId: teamId, Type: types.AbsenceVacation,
+			Status: types.REQUEST_OPEN, FromDate: today, ToDate: today.AddDate(0, 0, 2)},
+	}
+	vacationStore.approvals = []types.VacationApproval{
+		{RequestId: vacationStore.request.Id, ApproverId: leadId, Status: types.APPROVAL_OPEN},
+		{RequestId: vacationStore.request.Id, ApproverId: substituteId, Status: types.APPROVAL_OPEN},
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+	teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return types.Member, nil }
+	teamStore.GetAncestorIdsMock = func(team string) ([]string, error) { return []string{}, nil }
+	teamStore.GetTeamSettingsMock = func(team string) (*types.TeamSettings, error) { return types.DefaultTeamSettings(), nil }
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Email: id + "@email.com", SystemRole: types.SystemRoleUser}, nil
+	}
+	userStore.GetUsersFromTeamMock = func(team string) ([]types.TeamUser, error) {
+		return []types.TeamUser{{Id: requesterId, RoleType: types.Member}}, nil
+	}
+	preferenceStore := &mockPreferences{}
+	preferenceStore.GetPreferencesMock = func(userId string) (*types.UserPreferences, error) { return types.DefaultPreferences(userId), nil }
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	approvals := vacation.NewHandler(db, userStore, teamStore, vacationStore, preferenceStore, &mockMailer{})
+	members := NewHandler(nil, teamStore, userStore, vacationStore, &mockInvite{})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/vacations/requests/updateApproval", approvals.UpdateRequestApproval).Methods(http.MethodPost)
+	router.HandleFunc("/teams/{teamId}/members", members.handleGetMembers).Methods(http.MethodGet)
+
+	presence := func() types.Presence {
+		req, err := http.NewRequest(http.MethodGet, "/teams/"+teamId+"/members", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, requesterId))
+
+		testHttp := httptest.NewRecorder()
+		router.ServeHTTP(testHttp, req)
+		require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+
+		var result []types.TeamMember
+		require.NoError(t, json.NewDecoder(testHttp.Body).Decode(&result))
+		require.Len(t, result, 1)
+		return result[0].Presence
+	}
+	approve := func(approverId string) {
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		payload := fmt.Sprintf(`{"requestId":%q,"status":%d}`, vacationStore.request.Id, types.APPROVAL_APPROVED)
+		req, err := http.NewRequest(http.MethodPost, "/vacations/requests/updateApproval", strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, approverId))
+
+		testHttp := httptest.NewRecorder()
+		router.ServeHTTP(testHttp, req)
+		require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	}
+
+	require.Equal(t, types.PresencePresent, presence())
+	approve(leadId)
+	require.Equal(t, types.PresencePresent, presence())
+	approve(substituteId)
+	require.Equal(t, types.PresenceVacation, presence())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_UpdateTeamSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		status  int
+	}{
+		{"should save valid settings", `{"approvalChain":["approver","parent_unit"],"minimumNoticeDays":7,"leaveVisibility":"absent"}`, http.StatusOK},
+		{"should reject unknown approval steps", `{"approvalChain":["everybody"]}`, http.StatusBadRequest},
+		{"should reject negative notice periods", `{"minimumNoticeDays":-1}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			adminId := uuid.NewString()
+			var saved *types.TeamSettings
+			teamStore := &mockTeam{}
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return types.Administrator, nil }
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.SaveTeamSettingsMock = func(execable interface{}, team string, settings types.TeamSettings, changedBy string) error {
+				require.Equal(t, adminId, changedBy)
+				saved = &settings
+				return nil
+			}
+			teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error { return nil }
+			handler := NewHandler(db, teamStore, &mockUser{}, &mockVacation{}, &mockInvite{})
+
+			req, err := http.NewRequest(http.MethodPut, "/teams/"+uuid.NewString()+"/settings", strings.NewReader(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, adminId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/{teamId}/settings", handler.handleUpdateTeamSettings).Methods(http.MethodPut)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status == http.StatusOK {
+				require.Equal(t, 7, saved.MinimumNoticeDays)
+				require.Equal(t, types.LeaveVisibilityAbsent, saved.LeaveVisibility)
+			} else {
+				require.Nil(t, saved)
+			}
+		})
+	}
+}
+
+func Test_ArchiveTeam_Should_CancelOpenInvites(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	teamId := uuid.NewString()
+	adminId := uuid.NewString()
+	archived, cancelled := false, false
+	var history []types.TeamHistoryEntry
+	teamStore := &mockTeam{}
+	teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return types.Administrator, nil }
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+	teamStore.SetTeamArchivedMock = func(execable interface{}, team string, a bool) error {
+		archived = a
+		return nil
+	}
+	teamStore.CancelOpenInvitesOfTeamMock = func(execable interface{}, team string) error {
+		cancelled = true
+		return nil
+	}
+	teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error {
+		history = append(history, entry)
+		return nil
+	}
+	handler := NewHandler(db, teamStore, &mockUser{}, &mockVacation{}, &mockInvite{})
+
+	req, err := http.NewRequest(http.MethodPost, "/teams/"+teamId+"/archive", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, adminId))
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/teams/{teamId}/archive", handler.handleArchiveTeam).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	require.True(t, archived)
+	require.True(t, cancelled)
+	require.Len(t, history, 1)
+	require.Equal(t, types.TeamHistoryArchived, history[0].Action)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_RenameTeam_Should_Fail_IfTeamIsArchived(t *testing.T) {
+	archivedAt := time.Now()
+	teamStore := &mockTeam{}
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id, ArchivedAt: &archivedAt}, nil }
+	teamStore.RenameTeamMock = func(name, teamId string) error {
+		t.Fatal("archived teams must not be renamed")
+		return nil
+	}
+	handler := NewHandler(nil, teamStore, &mockUser{}, &mockVacation{}, &mockInvite{})
+
+	marshalled, _ := json.Marshal(types.RenameTeamPayload{Name: "Team B"})
+	req, err := http.NewRequest(http.MethodPatch, "/teams/"+uuid.NewString(), bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/teams/{teamId}", handler.handleRenameTeam).Methods(http.MethodPatch)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+}
+
+func Test_DeleteTeam(t *testing.T) {
+	tests := []struct {
+		name     string
+		members  int
+		requests int
+		status   int
+	}{
+		{"should delete a team with the caller as only member", 1, 0, http.StatusOK},
+		{"should fail if other members are left", 2, 0, http.StatusConflict},
+		{"should fail if the team has vacation requests", 1, 3, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			teamId := uuid.NewString()
+			deleted := false
+			teamStore := &mockTeam{}
+			teamStore.GetUserRoleInTeamMock = func(userId, team string) (types.UserRole, error) { return types.Administrator, nil }
+			teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id}, nil }
+			teamStore.GetTeamDeletionPreviewMock = func(team string) (*types.TeamDeletionPreview, error) {
+				return &types.TeamDeletionPreview{Members: tt.members, VacationRequests: tt.requests}, nil
+			}
+			teamStore.DeleteTeamMock = func(execable interface{}, team string) error {
+				deleted = true
+				return nil
+			}
+			handler := NewHandler(db, teamStore, &mockUser{}, &mockVacation{}, &mockInvite{})
+
+			req, err := http.NewRequest(http.MethodDelete, "/teams/"+teamId, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, uuid.NewString()))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams/{teamId}", handler.handleDeleteTeam).Methods(http.MethodDelete)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.Equal(t, tt.status == http.StatusOK, deleted)
+			if tt.status == http.StatusConflict {
+				var preview types.TeamDeletionPreview
+				require.NoError(t, json.NewDecoder(testHttp.Body).Decode(&preview))
+				require.False(t, preview.Deletable)
+				require.NotEmpty(t, preview.Blockers)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_ListTeams(t *testing.T) {
+	callerId := uuid.NewString()
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		filter types.TeamFilter
+		sort   string
+	}{
+		{"should list the active teams by name", "", http.StatusOK, types.TeamFilter{}, "name"},
+		{"should filter by name and membership", "?name=dev&mine=true&archived=true", http.StatusOK, types.TeamFilter{Name: "dev", MemberId: callerId, IncludeArchived: true}, "name"},
+		{"should list the administered teams by member count", "?administered=true&sort=-members", http.StatusOK, types.TeamFilter{AdministratorId: callerId}, "-members"},
+		{"should fail for an unknown sort key", "?sort=secret", http.StatusBadRequest, types.TeamFilter{}, ""},
+		{"should fail for a limit above the maximum", "?limit=1000", http.StatusBadRequest, types.TeamFilter{}, ""},
+		{"should fail for an invalid cursor", "?cursor=abc", http.StatusBadRequest, types.TeamFilter{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listed *types.TeamFilter
+			var sort string
+			teamStore := &mockTeam{}
+			teamStore.ListTeamsMock = func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+				listed = &filter
+				sort = page.Sort
+				return &types.Page[types.TeamListEntry]{Items: []types.TeamListEntry{}}, nil
+			}
+			handler := NewHandler(nil, teamStore, &mockUser{}, &mockVacation{}, &mockInvite{})
+
+			req, err := http.NewRequest(http.MethodGet, "/teams"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/teams", handler.handleGetAllTeams).Methods(http.MethodGet)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			if tt.status == http.StatusOK {
+				require.Equal(t, tt.filter, *listed)
+				require.Equal(t, tt.sort, sort)
+			} else {
+				require.Nil(t, listed)
+			}
+		})
+	}
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	team := types.Team{Id: uuid.NewString(), Name: "Team A"}
+	teamStore := &mockTeam{}
+	teamStore.ListTeamsMock = func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+		return &types.Page[types.TeamListEntry]{Items: []types.TeamListEntry{{Team: team, MemberCount: 1}}}, nil
+	}
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &team, nil }
+	// the caller administers the team, so the routes answer with the team data
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) { return types.Administrator, nil }
+	teamStore.GetAncestorIdsMock = func(teamId string) ([]string, error) { return []string{}, nil }
+	teamStore.GetDescendantIdsMock = func(teamId string) ([]string, error) { return []string{}, nil }
+	teamStore.GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) { return types.DefaultTeamSettings(), nil }
+	teamStore.GetTeamSettingsVersionsMock = func(teamId string) ([]types.TeamSettingsVersion, error) { return []types.TeamSettingsVersion{}, nil }
+	teamStore.GetTeamHistoryMock = func(teamId string) ([]types.TeamHistoryEntry, error) { return []types.TeamHistoryEntry{}, nil }
+	teamStore.GetTeamDeletionPreviewMock = func(teamId string) (*types.TeamDeletionPreview, error) { return &types.TeamDeletionPreview{}, nil }
+	userStore := &mockUser{}
+	userStore.GetUsersFromTeamMock = func(teamId string) ([]types.TeamUser, error) {
+		return []types.TeamUser{{Id: uuid.NewString(), Name: "Chris", Email: "chris@email.com", RoleType: types.Member}}, nil
+	}
+	vacationStore := &mockVacation{}
+	vacationStore.GetAbsencesOfTeamsMock = func(teamIds []string, from, to time.Time) ([]types.Absence, error) { return []types.Absence{}, nil }
+	// transactions fail, the check is about what the routes answer
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	handler := NewHandler(db, teamStore, userStore, vacationStore, &mockInvite{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	callerId := uuid.NewString()
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Name: "Chris", Email: "chris@email.com", Password: "$argon2id$secret-hash", SystemRole: types.SystemRoleUser}, nil
+	}
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: callerId}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), callerId, uuid.NewString())
+	require.NoError(t, err)
+
+	routecheck.CheckResponses(t, router, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+type mockTeam struct {
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	RenameTeamMock              func(name, teamId string) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
+}
+
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
+}
+
+func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
+	return m.GetTeamByIdMock(id)
+}
+
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
+}
+
+func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
+	return m.GetTeamByNameMock(name)
+}
+
+func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.AddUserToTeamMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
+}
+
+func (m *mockTeam) RenameTeam(name, teamId string) error {
+	return nil
+}
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
+type mockVacation struct {
+	GetVacationRequestsFromUserIdMock func(requestedFromId string) ([]types.VacationRequest, error)
+	GetTeamsWithOpenApprovalsMock     func(approverId string) ([]string, error)
+	ReassignOpenApprovalsMock         func(execable interface{}, teamId, fromApproverId, toApproverId string) error
+	CancelFutureRequestsOfUserMock    func(execable interface{}, userId string, from time.Time) error
+	GetApprovalsOfApproverMock        func(approverId string) ([]types.VacationApproval, error)
+	ClearRequestInfosOfUserMock       func(execable interface{}, userId string) error
+	GetVacationRequestByIdMock        func(id string) (*types.VacationRequest, error)
+	CancelUndecidedRequestsInTeamMock func(execable interface{}, userId, teamId string) error
+	GetAbsencesOfTeamsMock            func(teamIds []string, from, to time.Time) ([]types.Absence, error)
+	LockApprovalsForRequestMock       func(execable interface{}, requestId string) ([]types.VacationApproval, error)
+	DecideRequestMock                 func(execable interface{}, requestId string, status types.RequestStatus) (bool, error)
+	ClearApprovalReasonsOfUserMock    func(execable interface{}, userId string) error
+}
+
+func (m *mockVacation) CreateVacationRequest(execable interface{}, request types.VacationRequest) error {
+	return nil
+}
+
+func (m *mockVacation) GetVacationRequestsForUser(toUserId string) ([]types.VacationRequest, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) GetVacationRequestsFromUserId(requestedFromId string) ([]types.VacationRequest, error) {
+	return m.GetVacationRequestsFromUserIdMock(requestedFromId)
+}
+
+func (m *mockVacation) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error {
+	return nil
+}
+
+func (m *mockVacation) GetApprovalsForRequest(requestId string) ([]types.VacationApproval, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) CreateApprovalEntry(execable interface{}, requestId string, approverId string) error {
+	return nil
+}
+
+func (m *mockVacation) GetTeamsWithOpenApprovals(approverId string) ([]string, error) {
+	return m.GetTeamsWithOpenApprovalsMock(approverId)
+}
+
+func (m *mockVacation) ReassignOpenApprovals(execable interface{}, teamId, fromApproverId, toApproverId string) error {
+	return m.ReassignOpenApprovalsMock(execable, teamId, fromApproverId, toApproverId)
+}
+
+func (m *mockVacation) CancelFutureRequestsOfUser(execable interface{}, userId string, from time.Time) error {
+	return m.CancelFutureRequestsOfUserMock(execable, userId, from)
+}
+
+func (m *mockVacation) GetApprovalsOfApprover(approverId string) ([]types.VacationApproval, error) {
+	return m.GetApprovalsOfApproverMock(approverId)
+}
+
+func (m *mockVacation) ClearRequestInfosOfUser(execable interface{}, userId string) error {
+	return m.ClearRequestInfosOfUserMock(execable, userId)
+}
+
+func (m *mockVacation) GetVacationRequestById(id string) (*types.VacationRequest, error) {
+	return m.GetVacationRequestByIdMock(id)
+}
+
+func (m *mockVacation) CancelUndecidedRequestsInTeam(execable interface{}, userId, teamId string) error {
+	return m.CancelUndecidedRequestsInTeamMock(execable, userId, teamId)
+}
+
+func (m *mockVacation) GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]types.Absence, error) {
+	return m.GetAbsencesOfTeamsMock(teamIds, from, to)
+}
+
+func (m *mockVacation) LockApprovalsForRequest(execable interface{}, requestId string) ([]types.VacationApproval, error) {
+	return m.LockApprovalsForRequestMock(execable, requestId)
+}
+
+func (m *mockVacation) DecideRequest(execable interface{}, requestId string, status types.RequestStatus) (bool, error) {
+	return m.DecideRequestMock(execable, requestId, status)
+}
+
+func (m *mockVacation) ClearApprovalReasonsOfUser(execable interface{}, userId string) error {
+	return m.ClearApprovalReasonsOfUserMock(execable, userId)
+}
+
+func userRole(role types.UserRole) *types.UserRole {
+	return &role
+}
+
+// decidingVacation keeps a single request with its approvals, so the decisions
+// of the vacation handler show up in the absences of the team
+type decidingVacation struct {
+	*mockVacation
+	request   types.VacationRequest
+	approvals []types.VacationApproval
+}
+
+func (m *decidingVacation) GetVacationRequestById(id string) (*types.VacationRequest, error) {
+	request := m.request
+	return &request, nil
+}
+
+func (m *decidingVacation) GetApprovalsForRequest(requestId string) ([]types.VacationApproval, error) {
+	return slices.Clone(m.approvals), nil
+}
+
+func (m *decidingVacation) LockApprovalsForRequest(execable interface{}, requestId string) ([]types.VacationApproval, error) {
+	return slices.Clone(m.approvals), nil
+}
+
+func (m *decidingVacation) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error {
+	for i := range m.approvals {
+		if m.approvals[i].ApproverId == approverId {
+			m.approvals[i].Status = status
+		}
+	}
+	return nil
+}
+
+func (m *decidingVacation) DecideRequest(execable interface{}, requestId string, status types.RequestStatus) (bool, error) {
+	m.request.Status = status
+	return true, nil
+}
+
+func (m *decidingVacation) GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]types.Absence, error) {
+	status := m.request.Status
+	return []types.Absence{{RequestId: m.request.Id, UserId: m.request.RequestedFrom, TeamId: m.request.TeamId,
+		Type: m.request.Type, Status: &status, FromDate: m.request.FromDate, ToDate: m.request.ToDate}}, nil
+}
+
+type mockPreferences struct {
+	GetPreferencesMock    func(userId string) (*types.UserPreferences, error)
+	SavePreferencesMock   func(preferences types.UserPreferences) error
+	DeletePreferencesMock func(execable interface{}, userId string) error
+}
+
+func (m *mockPreferences) GetPreferences(userId string) (*types.UserPreferences, error) {
+	return m.GetPreferencesMock(userId)
+}
+
+func (m *mockPreferences) SavePreferences(preferences types.UserPreferences) error {
+	return m.SavePreferencesMock(preferences)
+}
+
+func (m *mockPreferences) DeletePreferences(execable interface{}, userId string) error {
+	return m.DeletePreferencesMock(execable, userId)
+}
+
+type mockMailer struct {
+	sentTo []string
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.sentTo = append(m.sentTo, to)
+	return nil
+}
+
+type mockInvite struct {
+	CreateInviteMock         func(execable interface{}, inv types.Invite) error
+	GetInviteInfosFromMock   func(from string) ([]types.InviteInfo, error)
+	GetInviteInfosToMock     func(to string) ([]types.InviteInfo, error)
+	GetInviteMock            func(id string) (*types.Invite, error)
+	DeleteInviteMock         func(execable interface{}, id string) error
+	CloseInviteMock          func(execable interface{}, id string, status types.InviteStatus) (bool, error)
+	HasOpenInviteMock        func(execable interface{}, toUserId, teamId string) (bool, error)
+	ExpireOverdueInvitesMock func(execable interface{}, toUserId, teamId string) error
+	RenewInviteMock          func(execable interface{}, id string, expiresAt time.Time) error
+	AcceptInviteMock         func(execable interface{}, id, userId string) (bool, error)
+	DeleteInvitesToEmailMock func(execable interface{}, email string) error
+}
+
+func (m *mockInvite) DeleteInvite(execable interface{}, id string) error {
+	return m.DeleteInviteMock(execable, id)
+}
+
+func (m *mockInvite) GetInvite(id string) (*types.Invite, error) {
+	return m.GetInviteMock(id)
+}
+func (m *mockInvite) CloseInvite(execable interface{}, id string, status types.InviteStatus) (bool, error) {
+	return m.CloseInviteMock(execable, id, status)
+}
+
+func (m *mockInvite) CreateInvite(execable interface{}, inv types.Invite) error {
+	return m.CreateInviteMock(execable, inv)
+}
+
+func (m *mockInvite) GetInviteInfosFrom(from string) ([]types.InviteInfo, error) {
+	return m.GetInviteInfosFromMock(from)
+}
+
+func (m *mockInvite) GetInviteInfosTo(to string) ([]types.InviteInfo, error) {
+	return m.GetInviteInfosToMock(to)
+}
+
+func (m *mockInvite) HasOpenInvite(execable interface{}, toUserId, teamId string) (bool, error) {
+	return m.HasOpenInviteMock(execable, toUserId, teamId)
+}
+
+func (m *mockInvite) ExpireOverdueInvites(execable interface{}, toUserId, teamId string) error {
+	return m.ExpireOverdueInvitesMock(execable, toUserId, teamId)
+}
+
+func (m *mockInvite) RenewInvite(execable interface{}, id string, expiresAt time.Time) error {
+	return m.RenewInviteMock(execable, id, expiresAt)
+}
+
+func (m *mockInvite) AcceptInvite(execable interface{}, id, userId string) (bool, error) {
+	return m.AcceptInviteMock(execable, id, userId)
+}
+
+func (m *mockInvite) DeleteInvitesToEmail(execable interface{}, email string) error {
+	return m.DeleteInvitesToEmailMock(execable, email)
 }
@@ -0,0 +1,97 @@
+package team
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+// handleGetTeamSettings returns the settings to members of the team
+func (h *Handler) handleGetTeamSettings(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if _, err := h.store.GetUserRoleInTeam(callerId, teamId); err != nil && !auth.IsSuperadmin(h.userStore, callerId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only members of the team can see its settings"))
+		return
+	}
+
+	settings, err := h.store.GetTeamSettings(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, settings)
+}
+
+// handleUpdateTeamSettings replaces the settings, the former settings stay as version
+func (h *Handler) handleUpdateTeamSettings(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var settings types.TeamSettings
+	if err := utils.ParseJson(r, &settings); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, settings) {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can change its settings"))
+		return
+	}
+
+	if _, ok := WritableTeam(h.store, w, teamId); !ok {
+		return
+	}
+
+	settings.Normalize()
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.SaveTeamSettings(tx, teamId, settings, callerId); err != nil {
+			return err
+		}
+
+		if err := h.recordHistory(tx, teamId, callerId, types.TeamHistorySettingsChanged, nil, settings); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, settings)
+		return nil
+	})
+}
+
+func (h *Handler) handleGetTeamSettingsVersions(w http.ResponseWriter, r *http.Request) {
+	teamId, ok := teamIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	if !h.isAdministrator(callerId, teamId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can see former settings"))
+		return
+	}
+
+	versions, err := h.store.GetTeamSettingsVersions(teamId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, versions)
+}
@@ -2,7 +2,9 @@ package team
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
@@ -16,8 +18,13 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) GetAllTeams() ([]types.Team, error) {
-	rows, err := s.db.Query("SELECT * FROM teams")
+func (s *Store) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	query := "SELECT * FROM teams WHERE archivedAt IS NULL"
+	if includeArchived {
+		query = "SELECT * FROM teams"
+	}
+
+	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -34,9 +41,92 @@ func (s *Store) GetAllTeams() ([]types.Team, error) {
 	return teams, nil
 }
 
-func (s *Store) CreateTeam(team types.Team) error {
-	_, err := s.db.Exec("INSERT INTO teams (Id, name) VALUES (?, ?)",
-		team.Id, team.Name)
+// teamSortColumns maps the sort keys of the team listing to their columns
+var teamSortColumns = map[string]string{
+	"name":      "name",
+	"createdAt": "createdAt",
+	"members":   "memberCount",
+}
+
+// ListTeams returns a page of the teams matching the filter. The member count is
+// computed in a derived table, so the listing can be sorted and paginated by it.
+func (s *Store) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	conditions := utils.Conditions{}
+	if !filter.IncludeArchived {
+		conditions.Add("archivedAt IS NULL")
+	}
+
+	if filter.Name != "" {
+		conditions.Add("name LIKE ?", "%"+utils.EscapeLike(filter.Name)+"%")
+	}
+
+	if filter.MemberId != "" {
+		conditions.Add("id IN (SELECT team_id FROM users_teams WHERE user_id = ?)", filter.MemberId)
+	}
+
+	if filter.AdministratorId != "" {
+		conditions.Add("id IN (SELECT team_id FROM users_teams WHERE user_id = ? AND roletype = ?)", filter.AdministratorId, types.Administrator)
+	}
+
+	if page.After != nil && page.Column == "createdAt" {
+		// the cursor carries the time as json string, the driver needs a time to compare it
+		value, _ := page.After.Value.(string)
+		createdAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		after := *page.After
+		after.Value = createdAt
+		page.After = &after
+	}
+
+	if keyset, args := page.Keyset("id"); keyset != "" {
+		conditions.Add(keyset, args...)
+	}
+
+	query := `SELECT id, name, createdAt, archivedAt, parentId, unitType, memberCount FROM (
+		SELECT t.*, (SELECT COUNT(*) FROM users_teams ut WHERE ut.team_id = t.id) AS memberCount FROM teams t
+	) teams` + conditions.Where() + " ORDER BY " + page.OrderBy("id") + " LIMIT ?"
+
+	rows, err := s.db.Query(query, append(conditions.Args(), page.QueryLimit())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := make([]types.TeamListEntry, 0)
+	for rows.Next() {
+		var t types.TeamListEntry
+		if err := rows.Scan(&t.Id, &t.Name, &t.CreatedAt, &t.ArchivedAt, &t.ParentId, &t.UnitType, &t.MemberCount); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := utils.NewPage(teams, page, func(t types.TeamListEntry) (any, string) {
+		switch page.Column {
+		case "createdAt":
+			return t.CreatedAt, t.Id
+		case "memberCount":
+			return t.MemberCount, t.Id
+		default:
+			return t.Name, t.Id
+		}
+	})
+	return &result, nil
+}
+
+func (s *Store) CreateTeam(execable interface{}, team types.Team) error {
+	if team.UnitType == "" {
+		team.UnitType = types.UnitTeam
+	}
+
+	_, err := utils.Exec(execable, "INSERT INTO teams (Id, name, parentId, unitType) VALUES (?, ?, ?, ?)",
+		team.Id, team.Name, team.ParentId, team.UnitType)
 
 	if err != nil {
 		return err
@@ -99,8 +189,8 @@ func (s *Store) AddUserToTeam(execable interface{}, userId, teamId string, role
 	return nil
 }
 
-func (s *Store) RemoveUserFromTeam(userId, teamId string) error {
-	_, err := s.db.Exec("DELETE FROM users_teams WHERE user_id = ? AND team_id = ?",
+func (s *Store) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	_, err := utils.Exec(execable, "DELETE FROM users_teams WHERE user_id = ? AND team_id = ?",
 		userId, teamId)
 
 	if err != nil {
@@ -110,6 +200,71 @@ func (s *Store) RemoveUserFromTeam(userId, teamId string) error {
 	return nil
 }
 
+func (s *Store) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	rows, err := s.db.Query("SELECT roletype FROM users_teams WHERE user_id = ? AND team_id = ?", userId, teamId)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, fmt.Errorf("user is not a member of the team")
+	}
+
+	var role types.UserRole
+	if err := rows.Scan(&role); err != nil {
+		return 0, err
+	}
+
+	return role, nil
+}
+
+func (s *Store) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	rows, err := s.db.Query(`SELECT t.id, t.name, ut.roletype, ut.addedAt FROM users_teams ut
+							inner join teams t on t.id = ut.team_id
+							where ut.user_id = ?`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	teams := make([]types.UserTeam, 0)
+	for rows.Next() {
+		t := types.UserTeam{}
+		if err := rows.Scan(&t.TeamId, &t.TeamName, &t.RoleType, &t.AddedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+
+	return teams, nil
+}
+
+func (s *Store) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	rows, err := s.db.Query(`SELECT u.id, u.name, u.email, ut.addedAt, ut.roletype FROM users_teams ut
+							inner join users u on u.id = ut.user_id
+							where ut.team_id = ? and ut.roletype = ? and u.deactivatedAt IS NULL
+							order by ut.addedAt`, teamId, types.Administrator)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	admins := make([]types.TeamUser, 0)
+	for rows.Next() {
+		u := types.TeamUser{}
+		if err := rows.Scan(&u.Id, &u.Name, &u.Email, &u.AddedAt, &u.RoleType); err != nil {
+			return nil, err
+		}
+		admins = append(admins, u)
+	}
+
+	return admins, nil
+}
+
+func (s *Store) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "DELETE FROM users_teams WHERE user_id = ?", userId)
+	return err
+}
+
 func (s *Store) RenameTeam(name, teamId string) error {
 	_, err := s.db.Exec("UPDATE teams SET Name = ? WHERE id = ?",
 		name, teamId)
@@ -127,9 +282,269 @@ func readTeamData(rows *sql.Rows) (*types.Team, error) {
 		&team.Id,
 		&team.Name,
 		&team.CreatedAt,
+		&team.ArchivedAt,
+		&team.ParentId,
+		&team.UnitType,
 	)
 	if err != nil {
 		return nil, err
 	}
 	return team, nil
 }
+
+func (s *Store) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	_, err := utils.Exec(execable, "UPDATE users_teams SET roletype = ? WHERE user_id = ? AND team_id = ?", role, userId, teamId)
+	return err
+}
+
+// LockTeamAdministrators returns the ids of the administrators and locks their memberships
+// until the transaction ends, so concurrent changes can't remove the last administrator
+func (s *Store) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	rows, err := utils.Query(execable, "SELECT user_id FROM users_teams WHERE team_id = ? AND roletype = ? FOR UPDATE",
+		teamId, types.Administrator)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (s *Store) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	_, err := utils.Exec(execable, "INSERT INTO team_history (id, team_id, actor_id, action, user_id, details) VALUES (?, ?, ?, ?, ?, ?)",
+		entry.Id, entry.TeamId, entry.ActorId, entry.Action, entry.UserId, entry.Details)
+	return err
+}
+
+func (s *Store) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	rows, err := s.db.Query(`SELECT id, team_id, actor_id, action, user_id, details, createdAt, source FROM team_history
+							WHERE team_id = ? ORDER BY createdAt DESC, id`, teamId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := make([]types.TeamHistoryEntry, 0)
+	for rows.Next() {
+		entry := types.TeamHistoryEntry{}
+		if err := rows.Scan(&entry.Id, &entry.TeamId, &entry.ActorId, &entry.Action, &entry.UserId, &entry.Details, &entry.CreatedAt, &entry.Source); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetFormerTeamIdsOfUser returns the teams the user left or was removed from
+func (s *Store) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT team_id FROM team_history WHERE user_id = ? AND action IN (?, ?)`,
+		userId, types.TeamHistoryMemberLeft, types.TeamHistoryMemberRemoved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetTeamSettings returns the defaults for teams whose settings were never saved
+func (s *Store) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	rows, err := s.db.Query("SELECT document FROM team_settings WHERE team_id = ?", teamId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := types.DefaultTeamSettings()
+	if !rows.Next() {
+		return settings, nil
+	}
+
+	var document string
+	if err := rows.Scan(&document); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(document), settings); err != nil {
+		return nil, err
+	}
+
+	settings.Normalize()
+	return settings, nil
+}
+
+// SaveTeamSettings replaces the settings and keeps the former ones as versions
+func (s *Store) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	settings.Normalize()
+	document, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.Exec(execable, `INSERT INTO team_settings (team_id, document, changedBy) VALUES (?, ?, ?)
+						ON DUPLICATE KEY UPDATE document = VALUES(document), changedBy = VALUES(changedBy),
+						version = version + 1, changedAt = UTC_TIMESTAMP`, teamId, string(document), changedBy)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.Exec(execable, `INSERT INTO team_settings_versions (team_id, version, document, changedBy)
+						SELECT team_id, version, document, changedBy FROM team_settings WHERE team_id = ?`, teamId)
+	return err
+}
+
+func (s *Store) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	rows, err := s.db.Query(`SELECT version, document, changedBy, createdAt FROM team_settings_versions
+						WHERE team_id = ? ORDER BY version DESC`, teamId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]types.TeamSettingsVersion, 0)
+	for rows.Next() {
+		var version types.TeamSettingsVersion
+		var document string
+		if err := rows.Scan(&version.Version, &document, &version.ChangedBy, &version.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(document), &version.Settings); err != nil {
+			return nil, err
+		}
+
+		version.Settings.Normalize()
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+func (s *Store) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	query := "UPDATE teams SET archivedAt = NULL WHERE id = ?"
+	if archived {
+		query = "UPDATE teams SET archivedAt = UTC_TIMESTAMP WHERE id = ?"
+	}
+
+	_, err := utils.Exec(execable, query, teamId)
+	return err
+}
+
+func (s *Store) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	_, err := utils.Exec(execable, "UPDATE invites SET status = ?, changedAt = UTC_TIMESTAMP WHERE teamId = ? AND status = ?",
+		types.INVITE_CANCELLED, teamId, types.INVITE_OPEN)
+	return err
+}
+
+// GetTeamDeletionPreview counts the rows which reference the team
+func (s *Store) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	rows, err := s.db.Query(`SELECT
+						(SELECT COUNT(*) FROM users_teams WHERE team_id = ?),
+						(SELECT COUNT(*) FROM teams WHERE parentId = ?),
+						(SELECT COUNT(*) FROM invites WHERE teamId = ?),
+						(SELECT COUNT(*) FROM vacation_requests WHERE teamId = ?),
+						(SELECT COUNT(*) FROM team_history WHERE team_id = ?),
+						EXISTS(SELECT 1 FROM team_settings WHERE team_id = ?),
+						(SELECT COUNT(*) FROM team_settings_versions WHERE team_id = ?)`,
+		teamId, teamId, teamId, teamId, teamId, teamId, teamId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preview := new(types.TeamDeletionPreview)
+	if !rows.Next() {
+		return nil, fmt.Errorf("team not found")
+	}
+
+	err = rows.Scan(&preview.Members, &preview.Units, &preview.Invites, &preview.VacationRequests, &preview.HistoryEntries, &preview.HasSettings, &preview.SettingsVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// DeleteTeam removes the team with its memberships, invites, history, settings and their versions.
+// Vacation requests are kept for the accounting, so teams with requests can't be deleted.
+func (s *Store) DeleteTeam(execable interface{}, teamId string) error {
+	queries := []string{
+		"DELETE FROM users_teams WHERE team_id = ?",
+		"DELETE FROM invites WHERE teamId = ?",
+		"DELETE FROM team_history WHERE team_id = ?",
+		"DELETE FROM team_settings_versions WHERE team_id = ?",
+		"DELETE FROM team_settings WHERE team_id = ?",
+		"DELETE FROM teams WHERE id = ?",
+	}
+
+	for _, query := range queries {
+		if _, err := utils.Exec(execable, query, teamId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAncestorIds returns the units above the team, the parent comes first
+func (s *Store) GetAncestorIds(teamId string) ([]string, error) {
+	return s.queryIds(`WITH RECURSIVE ancestors (id, parentId, depth) AS (
+							SELECT id, parentId, 0 FROM teams WHERE id = ?
+							UNION ALL
+							SELECT t.id, t.parentId, a.depth + 1 FROM teams t
+							inner join ancestors a on t.id = a.parentId
+						)
+						SELECT id FROM ancestors WHERE depth > 0 ORDER BY depth`, teamId)
+}
+
+// GetDescendantIds returns all units below the team
+func (s *Store) GetDescendantIds(teamId string) ([]string, error) {
+	return s.queryIds(`WITH RECURSIVE descendants (id, depth) AS (
+							SELECT id, 0 FROM teams WHERE id = ?
+							UNION ALL
+							SELECT t.id, d.depth + 1 FROM teams t
+							inner join descendants d on t.parentId = d.id
+						)
+						SELECT id FROM descendants WHERE depth > 0 ORDER BY depth`, teamId)
+}
+
+func (s *Store) queryIds(query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (s *Store) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	_, err := utils.Exec(execable, "UPDATE teams SET parentId = ? WHERE id = ?", parentId, teamId)
+	return err
+}
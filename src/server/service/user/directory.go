@@ -0,0 +1,101 @@
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+const directoryPageSize = 20
+
+// handleSearchUsers lists the users which can be invited. Everybody sees the members
+// of their own teams, administrators also the members of the units below the teams
+// they run. People outside of them are invited by email. Only superadmins can search
+// the whole instance.
+func (h *Handler) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	page := 1
+	if value := params.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("page must be a positive number"))
+			return
+		}
+		page = parsed
+	}
+
+	teamId := params.Get("teamId")
+	if teamId != "" && !utils.IsValidUUID(teamId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("team id is not valid"))
+		return
+	}
+
+	query := params.Get("query")
+	if len(query) > 100 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("query must not be longer than 100 characters"))
+		return
+	}
+
+	userId := auth.GetUserIdFromContext(r.Context())
+	filter := types.UserSearchFilter{
+		Query:     query,
+		TeamId:    teamId,
+		VisibleTo: userId,
+		// one more user is loaded to know whether there is a next page
+		Limit:  directoryPageSize + 1,
+		Offset: (page - 1) * directoryPageSize,
+	}
+
+	if auth.IsSuperadmin(h.store, userId) {
+		filter.VisibleTo = ""
+	} else {
+		administered, err := h.administeredTeamIds(userId)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		filter.AdministeredTeamIds = administered
+	}
+
+	users, err := h.store.SearchUsers(filter)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hasMore := len(users) > directoryPageSize
+	if hasMore {
+		users = users[:directoryPageSize]
+	}
+
+	utils.WriteJson(w, http.StatusOK, map[string]any{"users": users, "page": page, "hasMore": hasMore})
+}
+
+// administeredTeamIds returns the teams the user administers with all units below them
+func (h *Handler) administeredTeamIds(userId string) ([]string, error) {
+	teams, err := h.teamStore.GetTeamsOfUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	teamIds := make([]string, 0)
+	for _, team := range teams {
+		if team.RoleType != types.Administrator {
+			continue
+		}
+
+		descendantIds, err := h.teamStore.GetDescendantIds(team.TeamId)
+		if err != nil {
+			return nil, err
+		}
+		teamIds = append(teamIds, team.TeamId)
+		teamIds = append(teamIds, descendantIds...)
+	}
+
+	return teamIds, nil
+}
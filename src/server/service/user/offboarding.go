@@ -0,0 +1,164 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/team"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// handleDeactivateUser offboards a user who left. The user can't be deleted, the
+// requests, invites and approvals of the user are still part of the history.
+func (h *Handler) handleDeactivateUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, ok := vars["userId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing user id"))
+		return
+	}
+
+	if !utils.IsValidUUID(userId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return
+	}
+
+	adminId := auth.GetUserIdFromContext(r.Context())
+	if adminId == userId {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you can't deactivate your own account"))
+		return
+	}
+
+	isAdmin, err := h.isAdministratorOf(adminId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !isAdmin {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of a team of the user can deactivate the account"))
+		return
+	}
+
+	u, err := h.store.GetUserById(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if u.DeactivatedAt != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user is already deactivated"))
+		return
+	}
+
+	// the successors are chosen before anything is changed, so a team without
+	// another administrator doesn't leave the offboarding half done
+	successors, err := h.findApprovalSuccessors(userId, adminId)
+	if err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+
+	teams, err := h.teamStore.GetTeamsOfUser(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.DeactivateUser(tx, userId); err != nil {
+			return err
+		}
+
+		if err := h.store.DeleteAccessTokensOfUser(tx, userId); err != nil {
+			return err
+		}
+
+		if err := h.store.RevokeSessionsOfUser(tx, userId); err != nil {
+			return err
+		}
+
+		for teamId, successorId := range successors {
+			if err := h.vacationStore.ReassignOpenApprovals(tx, teamId, userId, successorId); err != nil {
+				return err
+			}
+		}
+
+		if err := h.vacationStore.CancelFutureRequestsOfUser(tx, userId, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		for _, membership := range teams {
+			if membership.RoleType != types.Administrator {
+				continue
+			}
+
+			if err := team.KeepAnAdministrator(h.teamStore, tx, membership.TeamId, userId); err != nil {
+				return err
+			}
+		}
+
+		if err := h.teamStore.RemoveUserFromAllTeams(tx, userId); err != nil {
+			return err
+		}
+
+		// the history keeps the former teams, their administrators may erase the data later
+		for _, membership := range teams {
+			err := h.teamStore.RecordTeamHistory(tx, types.TeamHistoryEntry{
+				Id:      uuid.NewString(),
+				TeamId:  membership.TeamId,
+				ActorId: adminId,
+				Action:  types.TeamHistoryMemberRemoved,
+				UserId:  &userId,
+				Details: "null",
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		auth.ForgetSessionsOfUser(userId)
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// findApprovalSuccessors returns for every team, in which the user has open approvals,
+// the administrator who takes them over. The deactivating administrator is preferred.
+func (h *Handler) findApprovalSuccessors(userId, adminId string) (map[string]string, error) {
+	teamIds, err := h.vacationStore.GetTeamsWithOpenApprovals(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	successors := make(map[string]string, len(teamIds))
+	for _, teamId := range teamIds {
+		admins, err := h.teamStore.GetTeamAdministrators(teamId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, admin := range admins {
+			if admin.Id == userId {
+				continue
+			}
+
+			if _, found := successors[teamId]; !found || admin.Id == adminId {
+				successors[teamId] = admin.Id
+			}
+		}
+
+		if _, found := successors[teamId]; !found {
+			return nil, fmt.Errorf("team %s has no other administrator who can take over the open approvals", teamId)
+		}
+	}
+
+	return successors, nil
+}
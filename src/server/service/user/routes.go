@@ -1,11 +1,15 @@
 package user
 
 import (
+	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/cebuh/simpleHolidayPlaner/config"
 	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/service/mail"
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
 	"github.com/go-playground/validator/v10"
@@ -13,19 +17,56 @@ import (
 	"github.com/gorilla/mux"
 )
 
+var errInvalidCredentials = fmt.Errorf("invalid credentials")
+
 type Handler struct {
-	store types.UserStore
+	db            *sql.DB
+	store         types.UserStore
+	teamStore     types.TeamStore
+	vacationStore types.VacationStore
+	settingsStore types.SettingsStore
+	mailer        types.Mailer
+	resendLimiter *utils.RateLimiter
+	loginThrottle *auth.LoginThrottle
 }
 
-func NewHandler(store types.UserStore) *Handler {
-	return &Handler{store: store}
+func NewHandler(db *sql.DB, store types.UserStore, teamStore types.TeamStore, vacationStore types.VacationStore,
+	settingsStore types.SettingsStore, mailer types.Mailer) *Handler {
+	return &Handler{
+		db:            db,
+		store:         store,
+		teamStore:     teamStore,
+		vacationStore: vacationStore,
+		settingsStore: settingsStore,
+		mailer:        mailer,
+		resendLimiter: utils.NewRateLimiter(3, time.Hour),
+		loginThrottle: auth.NewLoginThrottle(auth.ClientBackoff),
+	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.handleLogin).Methods("POST")
 	router.HandleFunc("/register", h.handleRegister).Methods("POST")
-	router.HandleFunc("/logout", h.handleLogout).Methods("POST")
-
+	router.HandleFunc("/logout", auth.Require(h.handleLogout, h.store)).Methods("POST")
+	router.HandleFunc("/verify", h.handleVerifyEmail).Methods("GET")
+	router.HandleFunc("/verify/resend", h.handleResendVerification).Methods("POST")
+	router.HandleFunc("/users", auth.Require(h.handleCreateUser, h.store, types.ScopeAdminTeams)).Methods("POST")
+	router.HandleFunc("/users", auth.Require(h.handleSearchUsers, h.store)).Methods("GET")
+	router.HandleFunc("/users/{userId}/unlock", auth.Require(h.handleUnlockUser, h.store, types.ScopeAdminTeams)).Methods("POST")
+	router.HandleFunc("/users/{userId}/deactivate", auth.Require(h.handleDeactivateUser, h.store, types.ScopeAdminTeams)).Methods("POST")
+	router.HandleFunc("/password/change", auth.RequirePasswordChange(h.handleChangePassword, h.store)).Methods("POST")
+	router.HandleFunc("/login/2fa", h.handleTwoFactorLogin).Methods("POST")
+	router.HandleFunc("/2fa/enroll", auth.Require(h.handleEnrollTwoFactor, h.store)).Methods("POST")
+	router.HandleFunc("/2fa/enable", auth.Require(h.handleEnableTwoFactor, h.store)).Methods("POST")
+	router.HandleFunc("/2fa/disable", auth.Require(h.handleDisableTwoFactor, h.store)).Methods("POST")
+	router.HandleFunc("/tokens", auth.Require(h.handleGetAccessTokens, h.store)).Methods("GET")
+	router.HandleFunc("/tokens", auth.Require(h.handleCreateAccessToken, h.store)).Methods("POST")
+	router.HandleFunc("/tokens/{tokenId}", auth.Require(h.handleDeleteAccessToken, h.store)).Methods("DELETE")
+	router.HandleFunc("/sessions", auth.Require(h.handleGetSessions, h.store)).Methods("GET")
+	router.HandleFunc("/sessions", auth.Require(h.handleRevokeSessions, h.store)).Methods("DELETE")
+	router.HandleFunc("/sessions/{sessionId}", auth.Require(h.handleRevokeSession, h.store)).Methods("DELETE")
+	router.HandleFunc("/users/{userId}/sessions", auth.Require(h.handleGetSessionsOfUser, h.store, types.ScopeAdminTeams)).Methods("GET")
+	router.HandleFunc("/users/{userId}/sessions", auth.Require(h.handleRevokeSessionsOfUser, h.store, types.ScopeAdminTeams)).Methods("DELETE")
 }
 
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -41,26 +82,118 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	clientIP := utils.ClientIP(r)
+	if !h.checkLoginThrottle(w, clientIP) {
+		return
+	}
+
+	// unknown addresses, wrong passwords and locked accounts get the same answer,
+	// otherwise the login could be used to find out which accounts exist
 	u, err := h.store.GetUserByEmail(payload.Email)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid email"))
+		auth.CompareDummyPassword([]byte(payload.Password))
+		h.loginThrottle.Fail(clientIP)
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
+		return
+	}
+
+	if isLocked(u) {
+		auth.CompareDummyPassword([]byte(payload.Password))
+		h.loginThrottle.Fail(clientIP)
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
 		return
 	}
 
 	if !auth.ComparePasswords(u.Password, []byte(payload.Password)) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid credentials"))
+		h.recordFailedLogin(u, clientIP)
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
+		return
+	}
+
+	h.resetFailedLogins(u)
+	h.rehashPassword(u, payload.Password)
+
+	if u.EmailVerifiedAt == nil {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("email address is not verified"))
+		return
+	}
+
+	response, err := auth.LoginResponse(h.store, h.db, r, u)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("error while creating token"))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+// rehashPassword upgrades hashes of older algorithms or parameters. The plain password
+// is only known during the login, so this can't be done in a migration.
+func (h *Handler) rehashPassword(u *types.User, password string) {
+	if !auth.NeedsRehash(u.Password) {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("error while rehashing password of user %s: %v", u.Id, err)
 		return
 	}
 
-	secret := []byte(config.Envs.JWTSecret)
-	token, err := auth.CreateJWT(secret, u.Id)
+	if err := h.store.UpdatePasswordHash(u.Id, hashedPassword); err != nil {
+		log.Printf("error while rehashing password of user %s: %v", u.Id, err)
+	}
+}
 
+// writeLoginToken answers a login after the second factor was checked
+func (h *Handler) writeLoginToken(w http.ResponseWriter, r *http.Request, u *types.User) {
+	response, err := auth.LoginTokenResponse(h.store, h.db, r, u)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("error while creating token"))
 		return
 	}
 
-	utils.WriteJson(w, http.StatusOK, map[string]string{"token": token})
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (h *Handler) checkLoginThrottle(w http.ResponseWriter, clientIP string) bool {
+	if wait := h.loginThrottle.RetryAfter(clientIP); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("too many failed login attempts, please try again later"))
+		return false
+	}
+
+	return true
+}
+
+// isLocked reports whether the account can't log in right now, because of
+// failed logins or because the user was deactivated
+func isLocked(u *types.User) bool {
+	return u.DeactivatedAt != nil || u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+func (h *Handler) recordFailedLogin(u *types.User, clientIP string) {
+	h.loginThrottle.Fail(clientIP)
+	failedLogins := u.FailedLogins + 1
+	var lockedUntil *time.Time
+	if wait := auth.AccountBackoff.Duration(failedLogins); wait > 0 {
+		until := time.Now().UTC().Add(wait)
+		lockedUntil = &until
+	}
+
+	if err := h.store.RecordFailedLogin(u.Id, failedLogins, lockedUntil); err != nil {
+		log.Printf("error while recording failed login: %v", err)
+	}
+}
+
+func (h *Handler) resetFailedLogins(u *types.User) {
+	if u.FailedLogins == 0 {
+		return
+	}
+
+	if err := h.store.ResetFailedLogins(u.Id); err != nil {
+		log.Printf("error while resetting failed logins: %v", err)
+	}
 }
 
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -76,31 +209,313 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := h.store.GetUserByEmail(payload.Email)
+	settings, err := h.settingsStore.GetInstanceSettings()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !settings.AllowsRegistration(payload.Email) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("registration is not open for this email address"))
+		return
+	}
+
+	_, err = h.store.GetUserByEmail(payload.Email)
 	if err == nil {
 		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", payload.Email))
 		return
 	}
 
+	if err := auth.ValidatePassword(payload.Password, payload.Email); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(payload.Password)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	err = h.store.CreateUser(types.User{
+	user := types.User{
 		Id:       uuid.NewString(),
 		Name:     payload.Name,
 		Email:    payload.Email,
 		Password: hashedPassword,
+	}
+
+	if err := h.store.CreateUser(h.db, user); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the user can request a new mail, so a failure must not fail the registration
+	if err := mail.SendVerificationMail(h.mailer, &user); err != nil {
+		log.Printf("error while sending verification mail: %v", err)
+	}
+
+	utils.WriteJson(w, http.StatusCreated, nil)
+}
+
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	userId, email, err := auth.ParseEmailVerificationToken(r.URL.Query().Get("token"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired verification link"))
+		return
+	}
+
+	u, err := h.store.GetUserById(userId)
+	if err != nil || u.Email != email {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired verification link"))
+		return
+	}
+
+	if u.EmailVerifiedAt == nil {
+		if err := h.store.VerifyEmail(u.Id); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResendVerificationPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	if !h.resendLimiter.Allow("ip:"+utils.ClientIP(r)) || !h.resendLimiter.Allow("email:"+payload.Email) {
+		utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("too many requests, please try again later"))
+		return
+	}
+
+	// the response is the same for unknown and verified addresses to not reveal registered users
+	u, err := h.store.GetUserByEmail(payload.Email)
+	if err == nil && u.EmailVerifiedAt == nil {
+		if err := mail.SendVerificationMail(h.mailer, u); err != nil {
+			log.Printf("error while sending verification mail: %v", err)
+		}
+	}
+
+	utils.WriteJson(w, http.StatusAccepted, nil)
+}
+
+func (h *Handler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateUserPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	adminId := auth.GetUserIdFromContext(r.Context())
+	role, err := h.teamStore.GetUserRoleInTeam(adminId, payload.TeamId)
+	if (err != nil || role != types.Administrator) && !auth.IsSuperadmin(h.store, adminId) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of the team can create users"))
+		return
+	}
+
+	if _, err := h.store.GetUserByEmail(payload.Email); err == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", payload.Email))
+		return
+	}
+
+	temporaryPassword, err := auth.GeneratePassword(16)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(temporaryPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the temporary password is sent to the address, so receiving it verifies the address
+	verifiedAt := time.Now().UTC()
+	user := types.User{
+		Id:                 uuid.NewString(),
+		Name:               payload.Name,
+		Email:              payload.Email,
+		Password:           hashedPassword,
+		MustChangePassword: true,
+		EmailVerifiedAt:    &verifiedAt,
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.CreateUser(tx, user); err != nil {
+			return err
+		}
+
+		return h.teamStore.AddUserToTeam(tx, user.Id, payload.TeamId, types.Member)
 	})
 
+	if !committed {
+		return
+	}
+
+	subject, body := mail.AccountCreatedMail(user.Name, temporaryPassword)
+	if err := h.mailer.Send(user.Email, subject, body); err != nil {
+		// without the mail nobody knows the temporary password, so the account is removed again
+		if err := h.store.DeleteUser(h.db, user.Id); err != nil {
+			log.Printf("error while removing user %s without account mail: %v", user.Id, err)
+		}
+
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error while sending the account mail: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.NewPublicUser(&user))
+}
+
+func (h *Handler) handleUnlockUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, ok := vars["userId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing user id"))
+		return
+	}
+
+	if !utils.IsValidUUID(userId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return
+	}
+
+	isAdmin, err := h.isAdministratorOf(auth.GetUserIdFromContext(r.Context()), userId)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.WriteJson(w, http.StatusCreated, nil)
+	if !isAdmin {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of a team of the user can unlock the account"))
+		return
+	}
+
+	if err := h.store.ResetFailedLogins(userId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+// isAdministratorOf checks if the admin is an administrator of any team the user is a
+// member of, or of a unit above it. Superadmins administrate all users.
+func (h *Handler) isAdministratorOf(adminId, userId string) (bool, error) {
+	adminTeams, err := h.teamStore.GetTeamsOfUser(adminId)
+	if err != nil {
+		return false, err
+	}
+
+	userTeams, err := h.teamStore.GetTeamsOfUser(userId)
+	if err != nil {
+		return false, err
+	}
+
+	administered := make(map[string]bool)
+	for _, adminTeam := range adminTeams {
+		if adminTeam.RoleType == types.Administrator {
+			administered[adminTeam.TeamId] = true
+		}
+	}
+
+	// the administrators of a unit administer the members of all units below it
+	for _, userTeam := range userTeams {
+		if len(administered) == 0 {
+			break
+		}
+
+		if administered[userTeam.TeamId] {
+			return true, nil
+		}
+
+		ancestorIds, err := h.teamStore.GetAncestorIds(userTeam.TeamId)
+		if err != nil {
+			return false, err
+		}
+
+		for _, ancestorId := range ancestorIds {
+			if administered[ancestorId] {
+				return true, nil
+			}
+		}
+	}
+
+	return auth.IsSuperadmin(h.store, adminId), nil
 }
 
-func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {}
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ChangePasswordPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	u, err := h.store.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !auth.ComparePasswords(u.Password, []byte(payload.OldPassword)) {
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
+		return
+	}
+
+	if payload.OldPassword == payload.NewPassword {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the new password must be different from the old one"))
+		return
+	}
+
+	if err := auth.ValidatePassword(payload.NewPassword, u.Email); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// whoever knew the old password is logged out, only the new session stays
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.ChangePassword(tx, u.Id, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := h.store.RevokeSessionsOfUser(tx, u.Id); err != nil {
+			return err
+		}
+
+		token, err := auth.StartSession(h.store, tx, r, u.Id)
+		if err != nil {
+			return fmt.Errorf("error while creating token")
+		}
+
+		utils.WriteJson(w, http.StatusOK, map[string]string{"token": token})
+		return nil
+	})
+
+	if committed {
+		auth.ForgetSessionsOfUser(u.Id)
+	}
+}
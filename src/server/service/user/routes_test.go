@@ -2,12 +2,23 @@ package user
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
 	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
@@ -15,7 +26,7 @@ import (
 func TestUserServiceHandlers(t *testing.T) {
 	userStore := &mockUser{}
 	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return &types.User{}, nil }
-	handler := NewHandler(userStore)
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, newMockSettings(types.DefaultInstanceSettings()), &mockMailer{})
 
 	t.Run("should fail if the user payload is not valid",
 		func(t *testing.T) {
@@ -61,11 +72,1330 @@ func TestUserServiceHandlers(t *testing.T) {
 		})
 }
 
+func Test_Login_Should_Return_RestrictedToken_IfPasswordMustBeChanged(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("temporary")
+	require.NoError(t, err)
+	userStore := &mockUser{}
+	verifiedAt := time.Now()
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword, MustChangePassword: true, EmailVerifiedAt: &verifiedAt}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	payload := types.LoginUserPayload{
+		Email:    "new@email.com",
+		Password: "temporary",
+	}
+
+	marshalled, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &response))
+	require.Equal(t, true, response["mustChangePassword"])
+}
+
+func Test_CreateUser_Should_Fail_IfCallerIsNoTeamAdministrator(t *testing.T) {
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) { return types.Member, nil }
+	handler := NewHandler(nil, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	payload := types.CreateUserPayload{
+		Name:   "Chris",
+		Email:  "new@email.com",
+		TeamId: uuid.NewString(),
+	}
+
+	marshalled, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users", handler.handleCreateUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusForbidden, testHttp.Code)
+}
+
+func Test_CreateUser_Should_Pass_AndSendMail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var createdUser types.User
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	userStore.CreateUserMock = func(execable interface{}, u types.User) error {
+		createdUser = u
+		return nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) { return types.Administrator, nil }
+	teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error { return nil }
+	mailer := &mockMailer{}
+	handler := NewHandler(db, userStore, teamStore, &mockVacation{}, &mockSettings{}, mailer)
+
+	payload := types.CreateUserPayload{
+		Name:   "Chris",
+		Email:  "new@email.com",
+		TeamId: uuid.NewString(),
+	}
+
+	marshalled, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users", handler.handleCreateUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusCreated, testHttp.Code)
+	require.True(t, createdUser.MustChangePassword)
+	require.Equal(t, []string{"new@email.com"}, mailer.sentTo)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_CreateUser_Should_RemoveUser_IfMailFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var createdUser types.User
+	var deletedId string
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	userStore.CreateUserMock = func(execable interface{}, u types.User) error {
+		createdUser = u
+		return nil
+	}
+	userStore.DeleteUserMock = func(execable interface{}, userId string) error {
+		deletedId = userId
+		return nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetUserRoleInTeamMock = func(userId, teamId string) (types.UserRole, error) { return types.Administrator, nil }
+	teamStore.AddUserToTeamMock = func(execable interface{}, userId, teamId string, role types.UserRole) error { return nil }
+	handler := NewHandler(db, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{err: fmt.Errorf("smtp is down")})
+
+	marshalled, _ := json.Marshal(types.CreateUserPayload{Name: "Chris", Email: "new@email.com", TeamId: uuid.NewString()})
+	req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users", handler.handleCreateUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusInternalServerError, testHttp.Code)
+	require.Equal(t, createdUser.Id, deletedId)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_ChangePassword_Should_Fail_IfOldPasswordIsWrong(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("temporary")
+	require.NoError(t, err)
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Password: hashedPassword, MustChangePassword: true}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	payload := types.ChangePasswordPayload{
+		OldPassword: "wrong",
+		NewPassword: "a new password",
+	}
+
+	marshalled, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, "/password/change", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/password/change", handler.handleChangePassword).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+}
+
+func Test_ChangePassword_Should_RevokeOtherSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	hashedPassword, err := auth.HashPassword("temporary")
+	require.NoError(t, err)
+	calls := []string{}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Email: "chris@email.com", Password: hashedPassword, MustChangePassword: true}, nil
+	}
+	userStore.ChangePasswordMock = func(execable interface{}, userId, hashedPassword string) error {
+		// the password only changes together with the sessions
+		require.IsType(t, &sql.Tx{}, execable)
+		calls = append(calls, "password")
+		return nil
+	}
+	userStore.RevokeSessionsOfUserMock = func(execable interface{}, userId string) error {
+		calls = append(calls, "revoke")
+		return nil
+	}
+	userStore.CreateSessionMock = func(execable interface{}, session types.Session) error {
+		calls = append(calls, "session")
+		return nil
+	}
+	handler := NewHandler(db, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	marshalled, _ := json.Marshal(types.ChangePasswordPayload{OldPassword: "temporary", NewPassword: "a much better passphrase"})
+	req, err := http.NewRequest(http.MethodPost, "/password/change", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, uuid.NewString()))
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/password/change", handler.handleChangePassword).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	require.Equal(t, []string{"password", "revoke", "session"}, calls)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Login_Should_Fail_IfEmailIsNotVerified(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	payload := types.LoginUserPayload{
+		Email:    "new@email.com",
+		Password: "password",
+	}
+
+	marshalled, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusForbidden, testHttp.Code)
+}
+
+func Test_VerifyEmail_Should_Pass_WithValidToken(t *testing.T) {
+	userId := uuid.NewString()
+	verified := false
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Email: "new@email.com"}, nil
+	}
+	userStore.VerifyEmailMock = func(id string) error {
+		verified = id == userId
+		return nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	token, err := auth.CreateEmailVerificationToken([]byte(config.Envs.JWTSecret), userId, "new@email.com")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "/verify?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/verify", handler.handleVerifyEmail).Methods(http.MethodGet)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.True(t, verified)
+}
+
+func Test_VerifyEmail_Should_Fail_IfEmailWasChanged(t *testing.T) {
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, Email: "changed@email.com"}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	token, err := auth.CreateEmailVerificationToken([]byte(config.Envs.JWTSecret), uuid.NewString(), "new@email.com")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "/verify?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/verify", handler.handleVerifyEmail).Methods(http.MethodGet)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+}
+
+func Test_ResendVerification_Should_BeRateLimited(t *testing.T) {
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email}, nil
+	}
+	mailer := &mockMailer{}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, mailer)
+	router := mux.NewRouter()
+	router.HandleFunc("/verify/resend", handler.handleResendVerification).Methods(http.MethodPost)
+
+	marshalled, _ := json.Marshal(types.ResendVerificationPayload{Email: "new@email.com"})
+	codes := make([]int, 0)
+	for range 4 {
+		req, err := http.NewRequest(http.MethodPost, "/verify/resend", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testHttp := httptest.NewRecorder()
+		router.ServeHTTP(testHttp, req)
+		codes = append(codes, testHttp.Code)
+	}
+
+	require.Equal(t, []int{http.StatusAccepted, http.StatusAccepted, http.StatusAccepted, http.StatusTooManyRequests}, codes)
+	require.Len(t, mailer.sentTo, 3)
+}
+
+func Test_Login_Should_ReturnSameError_ForUnknownEmailAndWrongPassword(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	var recordedFailures int
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		if email != "known@email.com" {
+			return nil, fmt.Errorf("user not found")
+		}
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword, FailedLogins: 3}, nil
+	}
+	userStore.RecordFailedLoginMock = func(userId string, failedLogins int, lockedUntil *time.Time) error {
+		recordedFailures = failedLogins
+		require.NotNil(t, lockedUntil)
+		return nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+
+	bodies := make([]string, 0)
+	for _, email := range []string{"unknown@email.com", "known@email.com"} {
+		marshalled, _ := json.Marshal(types.LoginUserPayload{Email: email, Password: "wrong"})
+		req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testHttp := httptest.NewRecorder()
+		router.ServeHTTP(testHttp, req)
+		require.Equal(t, http.StatusBadRequest, testHttp.Code)
+		bodies = append(bodies, testHttp.Body.String())
+	}
+
+	require.Equal(t, bodies[0], bodies[1])
+	require.Equal(t, 4, recordedFailures)
+}
+
+func Test_Login_Should_Fail_IfAccountIsLocked(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	lockedUntil := time.Now().Add(time.Minute)
+	verifiedAt := time.Now()
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword, LockedUntil: &lockedUntil, EmailVerifiedAt: &verifiedAt}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	marshalled, _ := json.Marshal(types.LoginUserPayload{Email: "locked@email.com", Password: "password"})
+	req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+}
+
+func Test_Login_Should_BeThrottled_PerClient(t *testing.T) {
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+
+	var lastCode int
+	for range auth.ClientBackoff.FreeAttempts + 2 {
+		marshalled, _ := json.Marshal(types.LoginUserPayload{Email: "unknown@email.com", Password: "wrong"})
+		req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testHttp := httptest.NewRecorder()
+		router.ServeHTTP(testHttp, req)
+		lastCode = testHttp.Code
+	}
+
+	require.Equal(t, http.StatusTooManyRequests, lastCode)
+}
+
+func Test_UnlockUser_Should_Fail_IfCallerIsNoAdministratorOfTheUser(t *testing.T) {
+	teamStore := &mockTeam{}
+	adminTeamId := uuid.NewString()
+	teamStore.GetAncestorIdsMock = func(teamId string) ([]string, error) { return []string{}, nil }
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		if userId == "" {
+			return []types.UserTeam{{TeamId: adminTeamId, RoleType: types.Administrator}}, nil
+		}
+		return []types.UserTeam{{TeamId: uuid.NewString(), RoleType: types.Member}}, nil
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+	}
+	handler := NewHandler(nil, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/users/"+uuid.NewString()+"/unlock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userId}/unlock", handler.handleUnlockUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusForbidden, testHttp.Code)
+}
+
+func Test_UnlockUser_Should_Pass_ForAdministratorOfTheUser(t *testing.T) {
+	teamId := uuid.NewString()
+	targetId := uuid.NewString()
+	unlocked := ""
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		if userId == targetId {
+			return []types.UserTeam{{TeamId: teamId, RoleType: types.Member}}, nil
+		}
+		return []types.UserTeam{{TeamId: teamId, RoleType: types.Administrator}}, nil
+	}
+	userStore := &mockUser{}
+	userStore.ResetFailedLoginsMock = func(userId string) error {
+		unlocked = userId
+		return nil
+	}
+	handler := NewHandler(nil, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/users/"+targetId+"/unlock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userId}/unlock", handler.handleUnlockUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.Equal(t, targetId, unlocked)
+}
+
+func Test_Login_Should_Return_Challenge_IfTwoFactorIsEnabled(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	verifiedAt := time.Now()
+	secret, err := auth.GenerateTOTPSecret()
+	require.NoError(t, err)
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword, EmailVerifiedAt: &verifiedAt, TotpSecret: &secret, TotpEnabled: true}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	marshalled, _ := json.Marshal(types.LoginUserPayload{Email: "user@email.com", Password: "password"})
+	req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &response))
+	require.Equal(t, true, response["twoFactorRequired"])
+	require.Nil(t, response["token"])
+}
+
+func Test_TwoFactorLogin(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	require.NoError(t, err)
+	userId := uuid.NewString()
+	userStore := &mockUser{}
+	userStore.CreateSessionMock = func(execable interface{}, session types.Session) error { return nil }
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, TotpSecret: &secret, TotpEnabled: true}, nil
+	}
+	userStore.UseRecoveryCodeMock = func(userId, codeHash string) (bool, error) { return false, nil }
+	userStore.RecordFailedLoginMock = func(userId string, failedLogins int, lockedUntil *time.Time) error { return nil }
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+	router := mux.NewRouter()
+	router.HandleFunc("/login/2fa", handler.handleTwoFactorLogin).Methods(http.MethodPost)
+
+	challengeToken, err := auth.CreateTwoFactorChallengeToken([]byte(config.Envs.JWTSecret), userId)
+	require.NoError(t, err)
+	code, err := auth.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		payload  types.TwoFactorLoginPayload
+		expected int
+	}{
+		{"valid code", types.TwoFactorLoginPayload{ChallengeToken: challengeToken, Code: code}, http.StatusOK},
+		{"used recovery code", types.TwoFactorLoginPayload{ChallengeToken: challengeToken, RecoveryCode: "abcd-efgh"}, http.StatusBadRequest},
+		{"access token as challenge", types.TwoFactorLoginPayload{ChallengeToken: "invalid", Code: code}, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			marshalled, _ := json.Marshal(c.payload)
+			req, err := http.NewRequest(http.MethodPost, "/login/2fa", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testHttp := httptest.NewRecorder()
+			router.ServeHTTP(testHttp, req)
+			require.Equal(t, c.expected, testHttp.Code)
+		})
+	}
+}
+
+func Test_CreateAccessToken_Should_ReturnToken_AndStoreHash(t *testing.T) {
+	var stored types.PersonalAccessToken
+	userStore := &mockUser{}
+	userStore.CreateAccessTokenMock = func(token types.PersonalAccessToken) error {
+		stored = token
+		return nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	marshalled, _ := json.Marshal(types.CreateAccessTokenPayload{Name: "script", Scopes: []string{types.ScopeReadVacations}, ExpiresInDays: 30})
+	req, err := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/tokens", handler.handleCreateAccessToken).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusCreated, testHttp.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &response))
+	token, _ := response["token"].(string)
+	require.True(t, auth.IsAccessToken(token))
+	require.Equal(t, auth.HashAccessToken(token), stored.TokenHash)
+	require.NotContains(t, testHttp.Body.String(), stored.TokenHash)
+	require.NotNil(t, stored.ExpiresAt)
+}
+
+func Test_Register_Should_Fail_IfPasswordViolatesPolicy(t *testing.T) {
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, newMockSettings(types.DefaultInstanceSettings()), &mockMailer{})
+
+	for _, password := range []string{"short", "password123", "chris-at-work-2026"} {
+		marshalled, _ := json.Marshal(types.RegisterUserPayload{Name: "Chris", Email: "chris@email.com", Password: password})
+		req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testHttp := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/register", handler.handleRegister).Methods(http.MethodPost)
+		router.ServeHTTP(testHttp, req)
+
+		require.Equal(t, http.StatusBadRequest, testHttp.Code, password)
+	}
+}
+
+func Test_Register_Should_Respect_InstanceSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings types.InstanceSettings
+		email    string
+		status   int
+	}{
+		{"should fail if registration is disabled", types.InstanceSettings{RegistrationEnabled: false}, "chris@email.com", http.StatusForbidden},
+		{"should fail for other domains", types.InstanceSettings{RegistrationEnabled: true, AllowedEmailDomains: []string{"company.com"}}, "chris@email.com", http.StatusForbidden},
+		{"should pass for allowed domains", types.InstanceSettings{RegistrationEnabled: true, AllowedEmailDomains: []string{"company.com"}}, "chris@Company.com", http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &mockUser{}
+			userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return nil, fmt.Errorf("user not found") }
+			userStore.CreateUserMock = func(execable interface{}, user types.User) error { return nil }
+			handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, newMockSettings(&tt.settings), &mockMailer{})
+
+			marshalled, _ := json.Marshal(types.RegisterUserPayload{Name: "Chris", Email: tt.email, Password: "a-long-and-unusual-passphrase"})
+			req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/register", handler.handleRegister).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+		})
+	}
+}
+
+func Test_Login_Should_Rehash_OutdatedPasswordHash(t *testing.T) {
+	hasher, err := auth.NewPasswordHasher(auth.HashAlgorithmBcrypt)
+	require.NoError(t, err)
+	hashedPassword, err := hasher.Hash("password")
+	require.NoError(t, err)
+	verifiedAt := time.Now()
+	rehashed := ""
+	userStore := &mockUser{}
+	userStore.CreateSessionMock = func(execable interface{}, session types.Session) error { return nil }
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword, EmailVerifiedAt: &verifiedAt}, nil
+	}
+	userStore.UpdatePasswordHashMock = func(userId, hash string) error {
+		rehashed = hash
+		return nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	marshalled, _ := json.Marshal(types.LoginUserPayload{Email: "user@email.com", Password: "password"})
+	req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.NotEmpty(t, rehashed)
+	require.False(t, auth.NeedsRehash(rehashed))
+	require.True(t, auth.ComparePasswords(rehashed, []byte("password")))
+}
+
+func Test_DeactivateUser_Should_Offboard_AndReassignApprovals(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	teamId := uuid.NewString()
+	adminId := uuid.NewString()
+	targetId := uuid.NewString()
+	calls := []string{}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: teamId, RoleType: types.Administrator}}, nil
+	}
+	teamStore.GetTeamAdministratorsMock = func(teamId string) ([]types.TeamUser, error) {
+		return []types.TeamUser{{Id: targetId}, {Id: uuid.NewString()}, {Id: adminId}}, nil
+	}
+	teamStore.LockTeamAdministratorsMock = func(execable interface{}, teamId string) ([]string, error) {
+		return []string{targetId, adminId}, nil
+	}
+	teamStore.RemoveUserFromAllTeamsMock = func(execable interface{}, userId string) error {
+		calls = append(calls, "memberships")
+		return nil
+	}
+	teamStore.RecordTeamHistoryMock = func(execable interface{}, entry types.TeamHistoryEntry) error {
+		require.Equal(t, teamId, entry.TeamId)
+		require.Equal(t, types.TeamHistoryMemberRemoved, entry.Action)
+		calls = append(calls, "history")
+		return nil
+	}
+	userStore := &mockUser{}
+	userStore.RevokeSessionsOfUserMock = func(execable interface{}, userId string) error { return nil }
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+	userStore.DeactivateUserMock = func(execable interface{}, userId string) error {
+		calls = append(calls, "deactivate")
+		return nil
+	}
+	userStore.DeleteAccessTokensOfUserMock = func(execable interface{}, userId string) error {
+		calls = append(calls, "tokens")
+		return nil
+	}
+	vacationStore := &mockVacation{}
+	vacationStore.GetTeamsWithOpenApprovalsMock = func(approverId string) ([]string, error) { return []string{teamId}, nil }
+	vacationStore.ReassignOpenApprovalsMock = func(execable interface{}, team, fromApproverId, toApproverId string) error {
+		require.Equal(t, targetId, fromApproverId)
+		require.Equal(t, adminId, toApproverId)
+		calls = append(calls, "approvals")
+		return nil
+	}
+	vacationStore.CancelFutureRequestsOfUserMock = func(execable interface{}, userId string, from time.Time) error {
+		calls = append(calls, "requests")
+		return nil
+	}
+	handler := NewHandler(db, userStore, teamStore, vacationStore, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/users/"+targetId+"/deactivate", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, adminId))
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userId}/deactivate", handler.handleDeactivateUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	require.Equal(t, []string{"deactivate", "tokens", "approvals", "requests", "memberships", "history"}, calls)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_DeactivateUser_Should_Fail_IfNoOtherAdministratorCanTakeOverApprovals(t *testing.T) {
+	teamId := uuid.NewString()
+	sharedTeamId := uuid.NewString()
+	targetId := uuid.NewString()
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: sharedTeamId, RoleType: types.Administrator}}, nil
+	}
+	teamStore.GetTeamAdministratorsMock = func(teamId string) ([]types.TeamUser, error) {
+		return []types.TeamUser{{Id: targetId}}, nil
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+	vacationStore := &mockVacation{}
+	vacationStore.GetTeamsWithOpenApprovalsMock = func(approverId string) ([]string, error) { return []string{teamId}, nil }
+	handler := NewHandler(nil, userStore, teamStore, vacationStore, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/users/"+targetId+"/deactivate", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userId}/deactivate", handler.handleDeactivateUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+}
+
+func Test_DeactivateUser_Should_Fail_ForTheLastAdministratorOfATeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	teamId := uuid.NewString()
+	targetId := uuid.NewString()
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: teamId, RoleType: types.Administrator}}, nil
+	}
+	teamStore.LockTeamAdministratorsMock = func(execable interface{}, teamId string) ([]string, error) {
+		return []string{targetId}, nil
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+	userStore.DeactivateUserMock = func(execable interface{}, userId string) error { return nil }
+	userStore.DeleteAccessTokensOfUserMock = func(execable interface{}, userId string) error { return nil }
+	userStore.RevokeSessionsOfUserMock = func(execable interface{}, userId string) error { return nil }
+	vacationStore := &mockVacation{}
+	vacationStore.GetTeamsWithOpenApprovalsMock = func(approverId string) ([]string, error) { return []string{}, nil }
+	vacationStore.CancelFutureRequestsOfUserMock = func(execable interface{}, userId string, from time.Time) error { return nil }
+	handler := NewHandler(db, userStore, teamStore, vacationStore, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/users/"+targetId+"/deactivate", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, uuid.NewString()))
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userId}/deactivate", handler.handleDeactivateUser).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Login_Should_Fail_IfUserIsDeactivated(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	verifiedAt := time.Now()
+	userStore := &mockUser{}
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) {
+		return &types.User{Id: uuid.NewString(), Email: email, Password: hashedPassword, EmailVerifiedAt: &verifiedAt, DeactivatedAt: &verifiedAt}, nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	marshalled, _ := json.Marshal(types.LoginUserPayload{Email: "user@email.com", Password: "password"})
+	req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(marshalled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHttp := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/login", handler.handleLogin).Methods(http.MethodPost)
+	router.ServeHTTP(testHttp, req)
+
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+	require.Contains(t, testHttp.Body.String(), errInvalidCredentials.Error())
+}
+
+func Test_SearchUsers(t *testing.T) {
+	callerId := uuid.NewString()
+	search := func(t *testing.T, systemRole types.SystemRole, url string) (*httptest.ResponseRecorder, types.UserSearchFilter) {
+		var filter types.UserSearchFilter
+		teamStore := &mockTeam{}
+		teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) { return []types.UserTeam{}, nil }
+		userStore := &mockUser{}
+		userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+			return &types.User{Id: id, SystemRole: systemRole}, nil
+		}
+		userStore.SearchUsersMock = func(f types.UserSearchFilter) ([]types.PublicUser, error) {
+			filter = f
+			users := make([]types.PublicUser, f.Limit)
+			for i := range users {
+				users[i] = types.PublicUser{Id: uuid.NewString(), Name: "Chris"}
+			}
+			return users, nil
+		}
+		handler := NewHandler(nil, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, callerId))
+
+		testHttp := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users", handler.handleSearchUsers).Methods(http.MethodGet)
+		router.ServeHTTP(testHttp, req)
+		return testHttp, filter
+	}
+
+	t.Run("should only show members of shared teams", func(t *testing.T) {
+		testHttp, filter := search(t, types.SystemRoleUser, "/users?query=chr&page=2")
+
+		require.Equal(t, http.StatusOK, testHttp.Code)
+		require.Equal(t, callerId, filter.VisibleTo)
+		require.Equal(t, "chr", filter.Query)
+		require.Equal(t, directoryPageSize, filter.Offset)
+
+		var response struct {
+			Users   []types.PublicUser `json:"users"`
+			HasMore bool               `json:"hasMore"`
+		}
+		require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &response))
+		require.Len(t, response.Users, directoryPageSize)
+		require.True(t, response.HasMore)
+	})
+
+	t.Run("should show all users to superadmins", func(t *testing.T) {
+		testHttp, filter := search(t, types.SystemRoleSuperadmin, "/users")
+
+		require.Equal(t, http.StatusOK, testHttp.Code)
+		require.Empty(t, filter.VisibleTo)
+	})
+
+	t.Run("should fail for invalid page", func(t *testing.T) {
+		testHttp, _ := search(t, types.SystemRoleUser, "/users?page=0")
+
+		require.Equal(t, http.StatusBadRequest, testHttp.Code)
+	})
+}
+
+func Test_SearchUsers_Should_Find_MembersOfAdministeredUnits(t *testing.T) {
+	adminId := uuid.NewString()
+	departmentId := uuid.NewString()
+	teamId := uuid.NewString()
+	otherTeamId := uuid.NewString()
+	member := types.PublicUser{Id: uuid.NewString(), Name: "Chris"}
+	stranger := types.PublicUser{Id: uuid.NewString(), Name: "Christine"}
+	memberships := map[string]string{member.Id: teamId, stranger.Id: otherTeamId}
+
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) {
+		return []types.UserTeam{{TeamId: departmentId, RoleType: types.Administrator}}, nil
+	}
+	teamStore.GetDescendantIdsMock = func(id string) ([]string, error) {
+		require.Equal(t, departmentId, id)
+		return []string{teamId}, nil
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		return &types.User{Id: id, SystemRole: types.SystemRoleUser}, nil
+	}
+	userStore.SearchUsersMock = func(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+		require.Equal(t, adminId, filter.VisibleTo)
+		found := make([]types.PublicUser, 0)
+		for _, u := range []types.PublicUser{member, stranger} {
+			if slices.Contains(filter.AdministeredTeamIds, memberships[u.Id]) {
+				found = append(found, u)
+			}
+		}
+		return found, nil
+	}
+	handler := NewHandler(nil, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{})
+
+	req, err := http.NewRequest(http.MethodGet, "/users?query=chr", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, adminId))
+
+	testHttp := httptest.NewRecorder()
+	handler.handleSearchUsers(testHttp, req)
+
+	require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	var response struct {
+		Users []types.PublicUser `json:"users"`
+	}
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &response))
+	require.Equal(t, []types.PublicUser{member}, response.Users)
+}
+
+func Test_Sessions_Should_BeListed_AndRevokedOnLogout(t *testing.T) {
+	userId := uuid.NewString()
+	sessions := map[string]*types.Session{}
+	for _, userAgent := range []string{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0", "curl/8.5.0"} {
+		id := uuid.NewString()
+		sessions[id] = &types.Session{Id: id, UserId: userId, UserAgent: userAgent}
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return &types.User{Id: id}, nil }
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return sessions[id], nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	userStore.GetSessionsOfUserMock = func(userId string, includeRevoked bool) ([]types.Session, error) {
+		active := []types.Session{}
+		for _, session := range sessions {
+			if session.RevokedAt == nil {
+				active = append(active, *session)
+			}
+		}
+		return active, nil
+	}
+	userStore.RevokeSessionMock = func(userId, id string) error {
+		revokedAt := time.Now()
+		sessions[id].RevokedAt = &revokedAt
+		return nil
+	}
+	handler := NewHandler(nil, userStore, &mockTeam{}, &mockVacation{}, &mockSettings{}, &mockMailer{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	var currentId string
+	for id, session := range sessions {
+		if strings.HasPrefix(session.UserAgent, "Mozilla") {
+			currentId = id
+		}
+	}
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), userId, currentId)
+	require.NoError(t, err)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		testHttp := httptest.NewRecorder()
+		router.ServeHTTP(testHttp, req)
+		return testHttp
+	}
+
+	testHttp := serve(http.MethodGet, "/sessions")
+	require.Equal(t, http.StatusOK, testHttp.Code)
+	var listed []types.Session
+	require.NoError(t, json.Unmarshal(testHttp.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	for _, session := range listed {
+		if session.Id == currentId {
+			require.True(t, session.Current)
+			require.Equal(t, "Firefox on Windows", session.Device)
+		} else {
+			require.False(t, session.Current)
+			require.Equal(t, "curl", session.Device)
+		}
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, "/logout").Code)
+	require.NotNil(t, sessions[currentId].RevokedAt)
+	require.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/sessions").Code)
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	secret := "JBSWY3DPEHPK3PXP"
+	u := &types.User{Id: uuid.NewString(), Name: "Chris", Email: "chris@email.com", Password: hashedPassword, TotpSecret: &secret}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) { return u, nil }
+	userStore.GetUserByEmailMock = func(email string) (*types.User, error) { return u, nil }
+	userStore.GetAccessTokensOfUserMock = func(userId string) ([]types.PersonalAccessToken, error) {
+		return []types.PersonalAccessToken{{Id: uuid.NewString(), UserId: userId, Name: "script", TokenHash: auth.HashAccessToken("shp_token")}}, nil
+	}
+	userStore.SetTotpSecretMock = func(userId string, secret *string) error { return nil }
+	userStore.DeleteAccessTokenMock = func(userId, id string) error { return nil }
+	userStore.SearchUsersMock = func(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+		return []types.PublicUser{types.NewPublicUser(u)}, nil
+	}
+	userStore.GetSessionsOfUserMock = func(userId string, includeRevoked bool) ([]types.Session, error) {
+		return []types.Session{{Id: uuid.NewString(), UserId: userId, UserAgent: "curl/8.0"}}, nil
+	}
+	userStore.RevokeSessionMock = func(userId, id string) error { return nil }
+	userStore.RevokeSessionsOfUserMock = func(execable interface{}, userId string) error { return nil }
+	teamStore := &mockTeam{}
+	teamStore.GetTeamsOfUserMock = func(userId string) ([]types.UserTeam, error) { return nil, nil }
+	handler := NewHandler(nil, userStore, teamStore, &mockVacation{}, &mockSettings{}, &mockMailer{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: u.Id}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), u.Id, uuid.NewString())
+	require.NoError(t, err)
+
+	routecheck.CheckResponses(t, router, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+type mockVacation struct {
+	GetVacationRequestsFromUserIdMock func(requestedFromId string) ([]types.VacationRequest, error)
+	GetTeamsWithOpenApprovalsMock     func(approverId string) ([]string, error)
+	ReassignOpenApprovalsMock         func(execable interface{}, teamId, fromApproverId, toApproverId string) error
+	CancelFutureRequestsOfUserMock    func(execable interface{}, userId string, from time.Time) error
+	GetApprovalsOfApproverMock        func(approverId string) ([]types.VacationApproval, error)
+	ClearRequestInfosOfUserMock       func(execable interface{}, userId string) error
+	GetVacationRequestByIdMock        func(id string) (*types.VacationRequest, error)
+	CancelUndecidedRequestsInTeamMock func(execable interface{}, userId, teamId string) error
+	GetAbsencesOfTeamsMock            func(teamIds []string, from, to time.Time) ([]types.Absence, error)
+	LockApprovalsForRequestMock       func(execable interface{}, requestId string) ([]types.VacationApproval, error)
+	DecideRequestMock                 func(execable interface{}, requestId string, status types.RequestStatus) (bool, error)
+	ClearApprovalReasonsOfUserMock    func(execable interface{}, userId string) error
+}
+
+func (m *mockVacation) CreateVacationRequest(execable interface{}, request types.VacationRequest) error {
+	return nil
+}
+
+func (m *mockVacation) GetVacationRequestsForUser(toUserId string) ([]types.VacationRequest, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) GetVacationRequestsFromUserId(requestedFromId string) ([]types.VacationRequest, error) {
+	return m.GetVacationRequestsFromUserIdMock(requestedFromId)
+}
+
+func (m *mockVacation) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error {
+	return nil
+}
+
+func (m *mockVacation) GetApprovalsForRequest(requestId string) ([]types.VacationApproval, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) CreateApprovalEntry(execable interface{}, requestId string, approverId string) error {
+	return nil
+}
+
+func (m *mockVacation) GetTeamsWithOpenApprovals(approverId string) ([]string, error) {
+	return m.GetTeamsWithOpenApprovalsMock(approverId)
+}
+
+func (m *mockVacation) ReassignOpenApprovals(execable interface{}, teamId, fromApproverId, toApproverId string) error {
+	return m.ReassignOpenApprovalsMock(execable, teamId, fromApproverId, toApproverId)
+}
+
+func (m *mockVacation) CancelFutureRequestsOfUser(execable interface{}, userId string, from time.Time) error {
+	return m.CancelFutureRequestsOfUserMock(execable, userId, from)
+}
+
+func (m *mockVacation) GetApprovalsOfApprover(approverId string) ([]types.VacationApproval, error) {
+	return m.GetApprovalsOfApproverMock(approverId)
+}
+
+func (m *mockVacation) ClearRequestInfosOfUser(execable interface{}, userId string) error {
+	return m.ClearRequestInfosOfUserMock(execable, userId)
+}
+
+func (m *mockVacation) GetVacationRequestById(id string) (*types.VacationRequest, error) {
+	return m.GetVacationRequestByIdMock(id)
+}
+
+func (m *mockVacation) CancelUndecidedRequestsInTeam(execable interface{}, userId, teamId string) error {
+	return m.CancelUndecidedRequestsInTeamMock(execable, userId, teamId)
+}
+
+func (m *mockVacation) GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]types.Absence, error) {
+	return m.GetAbsencesOfTeamsMock(teamIds, from, to)
+}
+
+func (m *mockVacation) LockApprovalsForRequest(execable interface{}, requestId string) ([]types.VacationApproval, error) {
+	return m.LockApprovalsForRequestMock(execable, requestId)
+}
+
+func (m *mockVacation) DecideRequest(execable interface{}, requestId string, status types.RequestStatus) (bool, error) {
+	return m.DecideRequestMock(execable, requestId, status)
+}
+
+func (m *mockVacation) ClearApprovalReasonsOfUser(execable interface{}, userId string) error {
+	return m.ClearApprovalReasonsOfUserMock(execable, userId)
+}
+
+type mockMailer struct {
+	sentTo []string
+	err    error
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.sentTo = append(m.sentTo, to)
+	return nil
+}
+
+type mockTeam struct {
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
+}
+
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
+}
+
+func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
+	return m.GetTeamByIdMock(id)
+}
+
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
+}
+
+func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
+	return m.GetTeamByNameMock(name)
+}
+
+func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.AddUserToTeamMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
+}
+
+func (m *mockTeam) RenameTeam(name, teamId string) error {
+	return nil
+}
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
 type mockUser struct {
-	GetUserByEmailMock   func(email string) (*types.User, error)
-	GetUserByIdMock      func(id string) (*types.User, error)
-	CreateUserMock       func(types.User) error
-	GetUsersFromTeamMock func(teamId string) ([]types.TeamUser, error)
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
 }
 
 func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
@@ -74,10 +1404,163 @@ func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
 func (m *mockUser) GetUserById(id string) (*types.User, error) {
 	return m.GetUserByIdMock(id)
 }
-func (m *mockUser) CreateUser(u types.User) error {
-	return m.CreateUserMock(u)
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
 }
 
 func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
 	return m.GetUsersFromTeamMock(teamId)
 }
+
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
+}
+
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
+}
+
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
+}
+
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
+}
+
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
+}
+
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
+}
+
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
+}
+
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
+
+func newMockSettings(settings *types.InstanceSettings) *mockSettings {
+	return &mockSettings{GetInstanceSettingsMock: func() (*types.InstanceSettings, error) { return settings, nil }}
+}
+
+type mockSettings struct {
+	GetInstanceSettingsMock  func() (*types.InstanceSettings, error)
+	SaveInstanceSettingsMock func(execable interface{}, settings types.InstanceSettings) error
+}
+
+func (m *mockSettings) GetInstanceSettings() (*types.InstanceSettings, error) {
+	return m.GetInstanceSettingsMock()
+}
+
+func (m *mockSettings) SaveInstanceSettings(execable interface{}, settings types.InstanceSettings) error {
+	return m.SaveInstanceSettingsMock(execable, settings)
+}
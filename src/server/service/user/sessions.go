@@ -0,0 +1,172 @@
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/gorilla/mux"
+)
+
+// handleLogout revokes the session of the token, so it can't be used anymore
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIdFromContext(r.Context())
+	sessionId := auth.GetSessionIdFromContext(r.Context())
+	if sessionId == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("token is not bound to a session"))
+		return
+	}
+
+	if err := h.store.RevokeSession(userId, sessionId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	auth.ForgetSession(sessionId)
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	h.writeSessions(w, auth.GetUserIdFromContext(r.Context()), auth.GetSessionIdFromContext(r.Context()))
+}
+
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["sessionId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing session id"))
+		return
+	}
+
+	if !utils.IsValidUUID(id) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return
+	}
+
+	if err := h.store.RevokeSession(auth.GetUserIdFromContext(r.Context()), id); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	auth.ForgetSession(id)
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+// handleRevokeSessions logs the user out everywhere, including the current session
+func (h *Handler) handleRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	h.revokeSessionsOfUser(w, auth.GetUserIdFromContext(r.Context()))
+}
+
+func (h *Handler) handleGetSessionsOfUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.administeredUserId(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeSessions(w, userId, "")
+}
+
+func (h *Handler) handleRevokeSessionsOfUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.administeredUserId(w, r)
+	if !ok {
+		return
+	}
+
+	h.revokeSessionsOfUser(w, userId)
+}
+
+func (h *Handler) writeSessions(w http.ResponseWriter, userId, currentSessionId string) {
+	sessions, err := h.store.GetSessionsOfUser(userId, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Device = describeDevice(sessions[i].UserAgent)
+		sessions[i].Current = sessions[i].Id == currentSessionId
+	}
+
+	utils.WriteJson(w, http.StatusOK, sessions)
+}
+
+func (h *Handler) revokeSessionsOfUser(w http.ResponseWriter, userId string) {
+	if err := h.store.RevokeSessionsOfUser(h.db, userId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	auth.ForgetSessionsOfUser(userId)
+	utils.WriteJson(w, http.StatusOK, nil)
+}
+
+// administeredUserId returns the user of the route, if the caller is an administrator
+// of one of the teams of the user
+func (h *Handler) administeredUserId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	vars := mux.Vars(r)
+	userId, ok := vars["userId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing user id"))
+		return "", false
+	}
+
+	if !utils.IsValidUUID(userId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return "", false
+	}
+
+	isAdmin, err := h.isAdministratorOf(auth.GetUserIdFromContext(r.Context()), userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return "", false
+	}
+
+	if !isAdmin {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only administrators of a team of the user can manage the sessions"))
+		return "", false
+	}
+
+	return userId, true
+}
+
+var (
+	browsers = []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	systems = []struct{ token, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	}
+)
+
+// describeDevice turns the user agent into a name users recognize, e.g. "Firefox on Windows"
+func describeDevice(userAgent string) string {
+	browser, system := "", ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
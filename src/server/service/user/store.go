@@ -3,11 +3,15 @@ package user
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
 )
 
+const userColumns = "id, name, email, password, mustChangePassword, emailVerifiedAt, failedLoginAttempts, lockedUntil, totpSecret, totpEnabled, oidcSubject, avatarKey, deactivatedAt, systemRole, createdAt"
+
 type Store struct {
 	db *sql.DB
 }
@@ -17,7 +21,7 @@ func NewStore(db *sql.DB) *Store {
 }
 
 func (s *Store) GetUserByEmail(email string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE email = ?", email)
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE email = ?", email)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +49,16 @@ func scanUserRow(rows *sql.Rows) (*types.User, error) {
 		&user.Name,
 		&user.Email,
 		&user.Password,
+		&user.MustChangePassword,
+		&user.EmailVerifiedAt,
+		&user.FailedLogins,
+		&user.LockedUntil,
+		&user.TotpSecret,
+		&user.TotpEnabled,
+		&user.OidcSubject,
+		&user.AvatarKey,
+		&user.DeactivatedAt,
+		&user.SystemRole,
 		&user.CreatedAt,
 	)
 
@@ -61,6 +75,7 @@ func scanTeamUserRow(rows *sql.Rows) (*types.TeamUser, error) {
 		&user.Name,
 		&user.Email,
 		&user.AddedAt,
+		&user.RoleType,
 	)
 
 	if err != nil {
@@ -70,7 +85,7 @@ func scanTeamUserRow(rows *sql.Rows) (*types.TeamUser, error) {
 }
 
 func (s *Store) GetUserById(id string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE id = ?", id)
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE id = ?", id)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +106,7 @@ func (s *Store) GetUserById(id string) (*types.User, error) {
 }
 
 func (s *Store) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
-	rows, err := s.db.Query("select users.id, users.name, users.email, ut.AddedAt  from users inner join users_teams ut ON ut.user_id  = users.Id where ut.team_id = ?", teamId)
+	rows, err := s.db.Query("select users.id, users.name, users.email, ut.AddedAt, ut.roletype from users inner join users_teams ut ON ut.user_id  = users.Id where ut.team_id = ?", teamId)
 	if err != nil {
 		return nil, err
 	}
@@ -108,13 +123,495 @@ func (s *Store) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
 	return userList, nil
 }
 
-func (s *Store) CreateUser(user types.User) error {
-	_, err := s.db.Exec("INSERT INTO users (Id, name, email, password) VALUES(?, ?, ?, ?)",
-		user.Id, user.Name, user.Email, user.Password)
+func (s *Store) CreateUser(execable interface{}, user types.User) error {
+	_, err := utils.Exec(execable, "INSERT INTO users (Id, name, email, password, mustChangePassword, emailVerifiedAt, oidcSubject) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		user.Id, user.Name, user.Email, user.Password, user.MustChangePassword, user.EmailVerifiedAt, user.OidcSubject)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET password = ?, mustChangePassword = FALSE WHERE id = ?",
+		hashedPassword, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateName(execable interface{}, userId, name string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET name = ? WHERE id = ?", name, userId)
+	return err
+}
+
+// UpdateEmail changes the address and resets the verification, the new address has to be verified again
+func (s *Store) UpdateEmail(execable interface{}, userId, email string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET email = ?, emailVerifiedAt = NULL WHERE id = ?", email, userId)
+	return err
+}
+
+func (s *Store) SetAvatarKey(userId string, key *string) error {
+	_, err := s.db.Exec("UPDATE users SET avatarKey = ? WHERE id = ?", key, userId)
+	return err
+}
+
+// DeactivateUser keeps the user for the history, but the account can't be used anymore
+func (s *Store) DeactivateUser(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET deactivatedAt = UTC_TIMESTAMP WHERE id = ?", userId)
+	return err
+}
+
+// DeleteUser removes a user who never used the account, together with the memberships
+func (s *Store) DeleteUser(execable interface{}, userId string) error {
+	if _, err := utils.Exec(execable, "DELETE FROM users_teams WHERE user_id = ?", userId); err != nil {
+		return err
+	}
+
+	_, err := utils.Exec(execable, "DELETE FROM users WHERE id = ?", userId)
+	return err
+}
+
+// UpdatePasswordHash replaces the hash of the unchanged password
+func (s *Store) UpdatePasswordHash(userId, hashedPassword string) error {
+	_, err := s.db.Exec("UPDATE users SET password = ? WHERE id = ?", hashedPassword, userId)
+	return err
+}
+
+func (s *Store) VerifyEmail(userId string) error {
+	_, err := s.db.Exec("UPDATE users SET emailVerifiedAt = UTC_TIMESTAMP WHERE id = ?", userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	_, err := s.db.Exec("UPDATE users SET failedLoginAttempts = ?, lockedUntil = ? WHERE id = ?",
+		failedLogins, lockedUntil, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) ResetFailedLogins(userId string) error {
+	_, err := s.db.Exec("UPDATE users SET failedLoginAttempts = 0, lockedUntil = NULL WHERE id = ?", userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetTotpSecret stores the secret of a pending enrollment, it is used after EnableTotp
+func (s *Store) SetTotpSecret(userId string, secret *string) error {
+	_, err := s.db.Exec("UPDATE users SET totpSecret = ?, totpEnabled = FALSE WHERE id = ?", secret, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) EnableTotp(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET totpEnabled = TRUE WHERE id = ? AND totpSecret IS NOT NULL", userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) DisableTotp(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET totpSecret = NULL, totpEnabled = FALSE WHERE id = ?", userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	if _, err := utils.Exec(execable, "DELETE FROM recovery_codes WHERE user_id = ?", userId); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := utils.Exec(execable, "INSERT INTO recovery_codes (user_id, codeHash) VALUES (?, ?)", userId, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the code as used and reports if it was valid and unused
+func (s *Store) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	result, err := s.db.Exec("UPDATE recovery_codes SET usedAt = UTC_TIMESTAMP WHERE user_id = ? AND codeHash = ? AND usedAt IS NULL",
+		userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *Store) GetUserByOidcSubject(subject string) (*types.User, error) {
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE oidcSubject = ?", subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	u := new(types.User)
+	for rows.Next() {
+		u, err = scanUserRow(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !utils.IsValidUUID(u.Id) {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return u, nil
+}
+
+func (s *Store) SetOidcSubject(execable interface{}, userId, subject string) error {
+	_, err := utils.Exec(execable, "UPDATE users SET oidcSubject = ? WHERE id = ?", subject, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const accessTokenColumns = "id, user_id, name, tokenHash, scopes, expiresAt, lastUsedAt, createdAt"
+
+func (s *Store) CreateAccessToken(token types.PersonalAccessToken) error {
+	_, err := s.db.Exec("INSERT INTO personal_access_tokens (id, user_id, name, tokenHash, scopes, expiresAt) VALUES (?, ?, ?, ?, ?, ?)",
+		token.Id, token.UserId, token.Name, token.TokenHash, strings.Join(token.Scopes, ","), token.ExpiresAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	rows, err := s.db.Query("SELECT "+accessTokenColumns+" FROM personal_access_tokens WHERE user_id = ? ORDER BY createdAt", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := make([]types.PersonalAccessToken, 0)
+	for rows.Next() {
+		t, err := scanAccessTokenRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+
+	return tokens, nil
+}
+
+func (s *Store) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	rows, err := s.db.Query("SELECT "+accessTokenColumns+" FROM personal_access_tokens WHERE tokenHash = ?", hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	t := new(types.PersonalAccessToken)
+	for rows.Next() {
+		t, err = scanAccessTokenRow(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !utils.IsValidUUID(t.Id) {
+		return nil, fmt.Errorf("token not found")
+	}
+
+	return t, nil
+}
+
+func (s *Store) TouchAccessToken(id string) error {
+	_, err := s.db.Exec("UPDATE personal_access_tokens SET lastUsedAt = UTC_TIMESTAMP WHERE id = ?", id)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteAccessToken(userId, id string) error {
+	result, err := s.db.Exec("DELETE FROM personal_access_tokens WHERE id = ? AND user_id = ?", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("token not found")
+	}
+
+	return nil
+}
+
+func scanAccessTokenRow(rows *sql.Rows) (*types.PersonalAccessToken, error) {
+	token := new(types.PersonalAccessToken)
+	var scopes string
+	err := rows.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.TokenHash,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Split(scopes, ",")
+	return token, nil
+}
+
+func (s *Store) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "DELETE FROM personal_access_tokens WHERE user_id = ?", userId)
+	return err
+}
+
+// PseudonymizeUser replaces the personal data of the user. The id stays, so the
+// vacation records of the user can still be counted.
+func (s *Store) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	_, err := utils.Exec(execable, `UPDATE users SET name = ?, email = ?, password = '', mustChangePassword = FALSE,
+						emailVerifiedAt = NULL, failedLoginAttempts = 0, lockedUntil = NULL, totpSecret = NULL,
+						totpEnabled = FALSE, oidcSubject = NULL, avatarKey = NULL WHERE id = ?`,
+		name, email, userId)
+	return err
+}
+
+// SearchUsers finds active users whose name or email address starts with the query
+func (s *Store) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	query := "SELECT u.id, u.name, u.email FROM users u"
+	args := []any{}
+	if filter.TeamId != "" {
+		query += " inner join users_teams tf on tf.user_id = u.id and tf.team_id = ?"
+		args = append(args, filter.TeamId)
+	}
+
+	query += " WHERE u.deactivatedAt IS NULL"
+	if filter.Query != "" {
+		prefix := utils.EscapeLike(filter.Query) + "%"
+		query += " AND (u.name LIKE ? OR u.email LIKE ?)"
+		args = append(args, prefix, prefix)
+	}
+
+	if filter.VisibleTo != "" {
+		visible := `EXISTS (SELECT 1 FROM users_teams own
+					inner join users_teams other on other.team_id = own.team_id
+					where own.user_id = ? and other.user_id = u.id)`
+		args = append(args, filter.VisibleTo)
+		if len(filter.AdministeredTeamIds) > 0 {
+			teams := strings.TrimSuffix(strings.Repeat("?, ", len(filter.AdministeredTeamIds)), ", ")
+			visible += ` OR EXISTS (SELECT 1 FROM users_teams administered
+					where administered.user_id = u.id and administered.team_id IN (` + teams + `))`
+			for _, teamId := range filter.AdministeredTeamIds {
+				args = append(args, teamId)
+			}
+		}
+		query += " AND (" + visible + ")"
+	}
+
+	query += " ORDER BY u.name, u.id LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]types.PublicUser, 0)
+	for rows.Next() {
+		u := types.PublicUser{}
+		if err := rows.Scan(&u.Id, &u.Name, &u.Email); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+// ListUsers returns all users including the deactivated ones, for the administration of the instance
+func (s *Store) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	statement := "SELECT " + userColumns + " FROM users"
+	args := []any{}
+	if query != "" {
+		prefix := utils.EscapeLike(query) + "%"
+		statement += " WHERE name LIKE ? OR email LIKE ?"
+		args = append(args, prefix, prefix)
+	}
+
+	statement += " ORDER BY name, id LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]types.User, 0)
+	for rows.Next() {
+		u, err := scanUserRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+
+	return users, nil
+}
+
+func (s *Store) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	_, err := utils.Exec(execable, "UPDATE users SET systemRole = ? WHERE id = ?", role, userId)
+	return err
+}
+
+const sessionColumns = "id, user_id, userAgent, ip, createdAt, lastSeenAt, revokedAt"
+
+func (s *Store) CreateSession(execable interface{}, session types.Session) error {
+	_, err := utils.Exec(execable, "INSERT INTO sessions (id, user_id, userAgent, ip) VALUES (?, ?, ?, ?)",
+		session.Id, session.UserId, session.UserAgent, session.IP)
+	return err
+}
+
+func (s *Store) GetSession(id string) (*types.Session, error) {
+	rows, err := s.db.Query("SELECT "+sessionColumns+" FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	session := new(types.Session)
+	for rows.Next() {
+		session, err = scanSessionRow(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !utils.IsValidUUID(session.Id) {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	return session, nil
+}
+
+// GetSessionsOfUser returns the sessions of the user, the revoked ones only if includeRevoked is set
+func (s *Store) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	query := "SELECT " + sessionColumns + " FROM sessions WHERE user_id = ?"
+	if !includeRevoked {
+		query += " AND revokedAt IS NULL"
+	}
+
+	rows, err := s.db.Query(query+" ORDER BY lastSeenAt DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := make([]types.Session, 0)
+	for rows.Next() {
+		session, err := scanSessionRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, nil
+}
+
+func (s *Store) TouchSession(id string) error {
+	_, err := s.db.Exec("UPDATE sessions SET lastSeenAt = UTC_TIMESTAMP WHERE id = ?", id)
+	return err
+}
 
+func (s *Store) RevokeSession(userId, id string) error {
+	result, err := s.db.Exec("UPDATE sessions SET revokedAt = UTC_TIMESTAMP WHERE id = ? AND user_id = ? AND revokedAt IS NULL", id, userId)
 	if err != nil {
 		return err
 	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("session not found")
+	}
+
 	return nil
 }
+
+func (s *Store) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "UPDATE sessions SET revokedAt = UTC_TIMESTAMP WHERE user_id = ? AND revokedAt IS NULL", userId)
+	return err
+}
+
+// DeleteSessionsOfUser removes the sessions together with the addresses and devices they recorded
+func (s *Store) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "DELETE FROM sessions WHERE user_id = ?", userId)
+	return err
+}
+
+func scanSessionRow(rows *sql.Rows) (*types.Session, error) {
+	session := new(types.Session)
+	err := rows.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
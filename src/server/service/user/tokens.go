@@ -0,0 +1,84 @@
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) handleGetAccessTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.store.GetAccessTokensOfUser(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, tokens)
+}
+
+func (h *Handler) handleCreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateAccessTokenPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	plainToken, hash, err := auth.GenerateAccessToken()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	token := types.PersonalAccessToken{
+		Id:        uuid.NewString(),
+		UserId:    auth.GetUserIdFromContext(r.Context()),
+		Name:      payload.Name,
+		TokenHash: hash,
+		Scopes:    payload.Scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if payload.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, payload.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := h.store.CreateAccessToken(token); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// only the hash is stored, so the token can't be shown again
+	utils.WriteJson(w, http.StatusCreated, map[string]any{"token": plainToken, "accessToken": token})
+}
+
+func (h *Handler) handleDeleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["tokenId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing token id"))
+		return
+	}
+
+	if !utils.IsValidUUID(id) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id is not valid"))
+		return
+	}
+
+	if err := h.store.DeleteAccessToken(auth.GetUserIdFromContext(r.Context()), id); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, nil)
+}
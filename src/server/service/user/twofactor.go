@@ -0,0 +1,206 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+)
+
+const (
+	totpIssuer        = "simpleHolidayPlaner"
+	recoveryCodeCount = 10
+)
+
+// handleEnrollTwoFactor creates a new secret. Two factor authentication stays
+// disabled until the user proves with a code that the authenticator works.
+func (h *Handler) handleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	u, err := h.store.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if u.TotpEnabled {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("two factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.store.SetTotpSecret(u.Id, &secret); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, map[string]string{
+		"secret":          secret,
+		"provisioningUri": auth.TOTPProvisioningURI(totpIssuer, u.Email, secret),
+	})
+}
+
+func (h *Handler) handleEnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload types.EnableTwoFactorPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	u, err := h.store.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if u.TotpEnabled {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("two factor authentication is already enabled"))
+		return
+	}
+
+	if u.TotpSecret == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("two factor authentication was not enrolled"))
+		return
+	}
+
+	if !auth.ValidateTOTP(*u.TotpSecret, payload.Code, time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid code"))
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.EnableTotp(tx, u.Id); err != nil {
+			return err
+		}
+
+		if err := h.store.ReplaceRecoveryCodes(tx, u.Id, hashes); err != nil {
+			return err
+		}
+
+		// the codes are only stored hashed, so this is the only time the user can see them
+		utils.WriteJson(w, http.StatusOK, map[string][]string{"recoveryCodes": codes})
+		return nil
+	})
+}
+
+func (h *Handler) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload types.DisableTwoFactorPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	u, err := h.store.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !u.TotpEnabled {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("two factor authentication is not enabled"))
+		return
+	}
+
+	if !auth.ComparePasswords(u.Password, []byte(payload.Password)) || !auth.ValidateTOTP(*u.TotpSecret, payload.Code, time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
+		return
+	}
+
+	ctx := r.Context()
+	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		if err := h.store.DisableTotp(tx, u.Id); err != nil {
+			return err
+		}
+
+		if err := h.store.ReplaceRecoveryCodes(tx, u.Id, nil); err != nil {
+			return err
+		}
+
+		utils.WriteJson(w, http.StatusOK, nil)
+		return nil
+	})
+}
+
+// handleTwoFactorLogin is the second step of a login for users with two factor
+// authentication. It exchanges the challenge token of the first step for a token.
+func (h *Handler) handleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var payload types.TwoFactorLoginPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !utils.ValidatePayload(w, payload) {
+		return
+	}
+
+	clientIP := utils.ClientIP(r)
+	if !h.checkLoginThrottle(w, clientIP) {
+		return
+	}
+
+	userId, err := auth.ParseTwoFactorChallengeToken(payload.ChallengeToken)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired challenge"))
+		return
+	}
+
+	u, err := h.store.GetUserById(userId)
+	if err != nil || !u.TotpEnabled || u.TotpSecret == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired challenge"))
+		return
+	}
+
+	if isLocked(u) {
+		h.loginThrottle.Fail(clientIP)
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
+		return
+	}
+
+	valid := false
+	if payload.Code != "" {
+		valid = auth.ValidateTOTP(*u.TotpSecret, payload.Code, time.Now())
+	} else {
+		valid, err = h.store.UseRecoveryCode(u.Id, auth.HashRecoveryCode(payload.RecoveryCode))
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if !valid {
+		h.recordFailedLogin(u, clientIP)
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
+		return
+	}
+
+	h.resetFailedLogins(u)
+	h.writeLoginToken(w, r, u)
+}
@@ -0,0 +1,45 @@
+package vacation
+
+import (
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// SummarizeBalance counts the working days of the requests in the year of now.
+// Approved days in the past are taken, approved days from today on are planned
+// and days of requests which aren't decided yet are pending.
+func SummarizeBalance(requests []types.VacationRequest, now time.Time) types.VacationBalance {
+	now = now.UTC()
+	balance := types.VacationBalance{Year: now.Year()}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, request := range requests {
+		switch request.Status {
+		case types.REQUEST_APPROVED:
+			balance.TakenDays += countWorkingDays(request.FromDate, request.ToDate, now.Year(), func(day time.Time) bool { return day.Before(today) })
+			balance.PlannedDays += countWorkingDays(request.FromDate, request.ToDate, now.Year(), func(day time.Time) bool { return !day.Before(today) })
+		case types.REQUEST_OPEN, types.REQUEST_SUBSTITUTED_MEMBER, types.REQUEST_SUBSTITUTED_TEAMLEAD:
+			balance.PendingDays += countWorkingDays(request.FromDate, request.ToDate, now.Year(), nil)
+		}
+	}
+
+	return balance
+}
+
+// countWorkingDays counts the days from Monday to Friday between both dates
+// (inclusive) which are in the given year and match the filter
+func countWorkingDays(from, to time.Time, year int, filter func(time.Time) bool) int {
+	days := 0
+	from = from.UTC()
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for !day.After(to.UTC()) {
+		weekday := day.Weekday()
+		if day.Year() == year && weekday != time.Saturday && weekday != time.Sunday && (filter == nil || filter(day)) {
+			days++
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return days
+}
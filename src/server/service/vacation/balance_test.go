@@ -0,0 +1,86 @@
+package vacation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeBalance(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC) // Wednesday
+	date := func(month time.Month, day int) time.Time { return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC) }
+
+	requests := map[string]*types.VacationRequest{
+		// Monday to Friday of the week before
+		"last-week": {FromDate: date(time.October, 5), ToDate: date(time.October, 9)},
+		// Monday to Sunday of this week, two days are in the past
+		"this-week": {FromDate: date(time.October, 12), ToDate: date(time.October, 18)},
+		"new-year":  {FromDate: date(time.December, 28), ToDate: time.Date(2027, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		"november":  {FromDate: date(time.November, 2), ToDate: date(time.November, 6)},
+	}
+	approvals := make(map[string][]types.VacationApproval)
+	for id, request := range requests {
+		request.Id, request.RequestedFrom, request.Status = id, requesterId, types.REQUEST_OPEN
+		approvals[id] = []types.VacationApproval{{RequestId: id, ApproverId: approverId, Status: types.APPROVAL_OPEN}}
+	}
+
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+		request := *requests[id]
+		return &request, nil
+	}
+	vacationStore.GetApprovalsForRequestMock = func(id string) ([]types.VacationApproval, error) { return slices.Clone(approvals[id]), nil }
+	vacationStore.LockApprovalsForRequestMock = func(execable interface{}, id string) ([]types.VacationApproval, error) {
+		return slices.Clone(approvals[id]), nil
+	}
+	vacationStore.UpdateVacationStatusMock = func(execable interface{}, id string, approver string, status types.ApprovalStatus, reason string) error {
+		for i := range approvals[id] {
+			if approvals[id][i].ApproverId == approver {
+				approvals[id][i].Status = status
+			}
+		}
+		return nil
+	}
+	vacationStore.DecideRequestMock = func(execable interface{}, id string, status types.RequestStatus) (bool, error) {
+		requests[id].Status = status
+		return true, nil
+	}
+	handler, db, mock := newTestHandler(t, vacationStore, nil, &mockMailer{})
+	defer db.Close()
+
+	decide := func(requestId string, status types.ApprovalStatus) {
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		payload := fmt.Sprintf(`{"requestId":%q,"status":%d,"reason":"team event"}`, requestId, status)
+		req, err := http.NewRequest(http.MethodPost, "/vacations/requests/updateApproval", strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, approverId))
+
+		testHttp := httptest.NewRecorder()
+		handler.UpdateRequestApproval(testHttp, req)
+		require.Equal(t, http.StatusOK, testHttp.Code, testHttp.Body.String())
+	}
+	decide("last-week", types.APPROVAL_APPROVED)
+	decide("this-week", types.APPROVAL_APPROVED)
+	decide("november", types.APPROVAL_DECLINED)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	decided := make([]types.VacationRequest, 0, len(requests))
+	for _, request := range requests {
+		decided = append(decided, *request)
+	}
+	balance := SummarizeBalance(decided, now)
+
+	require.Equal(t, types.VacationBalance{Year: 2026, TakenDays: 7, PlannedDays: 3, PendingDays: 4}, balance)
+}
@@ -0,0 +1,137 @@
+package vacation
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils"
+	"github.com/gorilla/mux"
+)
+
+// handleEscalateRequest hands the decision up to the administrators of the next unit
+// above the team. Every escalation goes one level higher, the former approvers can still decide.
+func (h *Handler) handleEscalateRequest(w http.ResponseWriter, r *http.Request) {
+	requestId, ok := mux.Vars(r)["requestId"]
+	if !ok || !utils.IsValidUUID(requestId) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("request id is not valid"))
+		return
+	}
+
+	request, err := h.vacationStore.GetVacationRequestById(requestId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if !isUndecided(request.Status) {
+		utils.WriteError(w, http.StatusConflict, errRequestDecided)
+		return
+	}
+
+	approvals, err := h.vacationStore.GetApprovalsForRequest(requestId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	callerId := auth.GetUserIdFromContext(r.Context())
+	approvers := make(map[string]bool, len(approvals))
+	isApprover := false
+	for _, approval := range approvals {
+		approvers[approval.ApproverId] = true
+		if approval.ApproverId == callerId && approval.Status == types.APPROVAL_OPEN {
+			isApprover = true
+		}
+	}
+
+	if !isApprover {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only open approvers of the request can escalate it"))
+		return
+	}
+
+	escalation, err := h.nextEscalation(request, approvers)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if escalation == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("there is no unit above with administrators to escalate to"))
+		return
+	}
+
+	requester, err := h.userStore.GetUserById(request.RequestedFrom)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		for _, approverId := range escalation.ApproverIds {
+			if err := h.vacationStore.CreateApprovalEntry(tx, request.Id, approverId); err != nil {
+				return err
+			}
+		}
+
+		utils.WriteJson(w, http.StatusOK, escalation)
+		return nil
+	})
+
+	if !committed {
+		return
+	}
+
+	for _, approverId := range escalation.ApproverIds {
+		if approver, err := h.userStore.GetUserById(approverId); err == nil {
+			h.notifyRequestCreated(approver, requester, *request)
+		}
+	}
+}
+
+// nextEscalation walks up from the team of the request and returns the administrators
+// of the first unit which wasn't asked yet, or nil at the top of the tree
+func (h *Handler) nextEscalation(request *types.VacationRequest, approvers map[string]bool) (*types.EscalationResult, error) {
+	ancestorIds, err := h.teamStore.GetAncestorIds(request.TeamId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ancestorId := range ancestorIds {
+		admins, err := h.teamStore.GetTeamAdministrators(ancestorId)
+		if err != nil {
+			return nil, err
+		}
+
+		asked := false
+		approverIds := make([]string, 0, len(admins))
+		for _, admin := range admins {
+			if approvers[admin.Id] {
+				asked = true
+			} else if admin.Id != request.RequestedFrom {
+				approverIds = append(approverIds, admin.Id)
+			}
+		}
+
+		// a unit whose administrators were asked already was an earlier escalation
+		if asked || len(approverIds) == 0 {
+			continue
+		}
+
+		return &types.EscalationResult{TeamId: ancestorId, ApproverIds: approverIds}, nil
+	}
+
+	return nil, nil
+}
+
+func isUndecided(status types.RequestStatus) bool {
+	switch status {
+	case types.REQUEST_OPEN, types.REQUEST_SUBSTITUTED_MEMBER, types.REQUEST_SUBSTITUTED_TEAMLEAD:
+		return true
+	}
+
+	return false
+}
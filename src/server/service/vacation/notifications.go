@@ -0,0 +1,61 @@
+package vacation
+
+import (
+	"log"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/service/mail"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// calendarDate returns the day of t in the location as midnight UTC, which is how
+// the days of a request are stored
+func calendarDate(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func (h *Handler) notifyRequestCreated(approver, requester *types.User, request types.VacationRequest) {
+	h.notify(approver, types.NotificationRequestCreated, func(preferences *types.UserPreferences) (string, string) {
+		return mail.VacationRequestCreatedMail(approver.Name, requester.Name, mail.FormatDate(request.FromDate, preferences),
+			mail.FormatDate(request.ToDate, preferences), request.Info)
+	})
+}
+
+func (h *Handler) notifyRequestDecided(requestId string, approved bool, reason string) {
+	request, err := h.vacationStore.GetVacationRequestById(requestId)
+	if err != nil {
+		log.Printf("error while loading request %s for the notification: %v", requestId, err)
+		return
+	}
+
+	requester, err := h.userStore.GetUserById(request.RequestedFrom)
+	if err != nil {
+		log.Printf("error while loading requester of %s for the notification: %v", requestId, err)
+		return
+	}
+
+	h.notify(requester, types.NotificationRequestDecided, func(preferences *types.UserPreferences) (string, string) {
+		return mail.VacationRequestDecidedMail(requester.Name, mail.FormatDate(request.FromDate, preferences),
+			mail.FormatDate(request.ToDate, preferences), approved, reason)
+	})
+}
+
+// notify sends the message if the user wants to get the event. A notification which
+// can't be sent doesn't fail the request, the state can always be looked up.
+func (h *Handler) notify(u *types.User, event string, message func(*types.UserPreferences) (string, string)) {
+	preferences, err := h.preferenceStore.GetPreferences(u.Id)
+	if err != nil {
+		log.Printf("error while loading preferences of %s: %v", u.Id, err)
+		return
+	}
+
+	if !preferences.Wants(event, types.ChannelEmail) {
+		return
+	}
+
+	subject, body := message(preferences)
+	if err := h.mailer.Send(u.Email, subject, body); err != nil {
+		log.Printf("error while sending %s notification to %s: %v", event, u.Id, err)
+	}
+}
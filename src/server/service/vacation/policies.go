@@ -0,0 +1,104 @@
+package vacation
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// checkPolicies reports why the team doesn't allow an absence of this type in this
+// period. Sick leave isn't planned, so only the length limit applies to it.
+func checkPolicies(settings *types.TeamSettings, absenceType types.AbsenceType, today, fromDate, toDate time.Time) error {
+	// without a notice period vacations can be entered afterwards as well
+	noticeDays := int(fromDate.Sub(today).Hours() / 24)
+	if absenceType != types.AbsenceSick && settings.MinimumNoticeDays > 0 && noticeDays < settings.MinimumNoticeDays {
+		return fmt.Errorf("the vacation has to be requested at least %d days before it starts", settings.MinimumNoticeDays)
+	}
+
+	days := int(toDate.Sub(fromDate).Hours()/24) + 1
+	if settings.MaximumConsecutiveDays > 0 && days > settings.MaximumConsecutiveDays {
+		return fmt.Errorf("the vacation must not be longer than %d days", settings.MaximumConsecutiveDays)
+	}
+
+	return nil
+}
+
+// approversAlongChain returns who has to approve a new request of the requester. Nobody
+// approves own requests, if the chain leaves nobody the chosen approver decides.
+func (h *Handler) approversAlongChain(settings *types.TeamSettings, teamId, chosenApproverId, requesterId string) ([]string, error) {
+	approverIds := make([]string, 0)
+	add := func(id string) bool {
+		if id == requesterId || slices.Contains(approverIds, id) {
+			return false
+		}
+		approverIds = append(approverIds, id)
+		return true
+	}
+
+	for _, step := range settings.ApprovalChain {
+		switch step {
+		case types.ApprovalStepApprover:
+			add(chosenApproverId)
+		case types.ApprovalStepTeamAdministrators:
+			admins, err := h.teamStore.GetTeamAdministrators(teamId)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, admin := range admins {
+				add(admin.Id)
+			}
+		case types.ApprovalStepParentUnit:
+			// the administrators of the nearest unit above which has any
+			ancestorIds, err := h.teamStore.GetAncestorIds(teamId)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, ancestorId := range ancestorIds {
+				admins, err := h.teamStore.GetTeamAdministrators(ancestorId)
+				if err != nil {
+					return nil, err
+				}
+
+				added := false
+				for _, admin := range admins {
+					added = add(admin.Id) || added
+				}
+
+				if added {
+					break
+				}
+			}
+		}
+	}
+
+	if len(approverIds) == 0 {
+		approverIds = append(approverIds, chosenApproverId)
+	}
+
+	return approverIds, nil
+}
+
+// checkDeclineReason makes sure teams which want to know why a request was declined get a reason
+func (h *Handler) checkDeclineReason(payload types.VacationApprovalPayload) (int, error) {
+	request, err := h.vacationStore.GetVacationRequestById(payload.RequestId)
+	if err != nil {
+		return http.StatusNotFound, err
+	}
+
+	settings, err := h.teamStore.GetTeamSettings(request.TeamId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if settings.DeclineRequiresReason && strings.TrimSpace(payload.Reason) == "" {
+		return http.StatusBadRequest, fmt.Errorf("the team requires a reason to decline a request")
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,79 @@
+package vacation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPolicies(t *testing.T) {
+	today := time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC)
+	date := func(month time.Month, day int) time.Time { return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name        string
+		settings    types.TeamSettings
+		absenceType types.AbsenceType
+		from        time.Time
+		to          time.Time
+		valid       bool
+	}{
+		{"should allow past vacations without notice period", types.TeamSettings{}, types.AbsenceVacation, date(time.October, 1), date(time.October, 2), true},
+		{"should allow vacations after the notice period", types.TeamSettings{MinimumNoticeDays: 14}, types.AbsenceVacation, date(time.October, 28), date(time.October, 30), true},
+		{"should reject vacations within the notice period", types.TeamSettings{MinimumNoticeDays: 14}, types.AbsenceVacation, date(time.October, 27), date(time.October, 30), false},
+		{"should allow vacations up to the maximum length", types.TeamSettings{MaximumConsecutiveDays: 14}, types.AbsenceVacation, date(time.November, 2), date(time.November, 15), true},
+		{"should reject vacations longer than the maximum", types.TeamSettings{MaximumConsecutiveDays: 14}, types.AbsenceVacation, date(time.November, 2), date(time.November, 16), false},
+		{"should allow sick leave within the notice period", types.TeamSettings{MinimumNoticeDays: 14}, types.AbsenceSick, date(time.October, 14), date(time.October, 16), true},
+		{"should reject sick leave longer than the maximum", types.TeamSettings{MaximumConsecutiveDays: 14}, types.AbsenceSick, date(time.November, 2), date(time.November, 16), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPolicies(&tt.settings, tt.absenceType, today, tt.from, tt.to)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestApproversAlongChain(t *testing.T) {
+	teamId := uuid.NewString()
+	departmentId := uuid.NewString()
+	teamAdminId := uuid.NewString()
+	departmentAdminId := uuid.NewString()
+	admins := map[string][]types.TeamUser{
+		teamId:       {{Id: teamAdminId}, {Id: requesterId}},
+		departmentId: {{Id: departmentAdminId}},
+	}
+
+	tests := []struct {
+		name      string
+		chain     []string
+		approvers []string
+	}{
+		{"should ask the chosen approver by default", []string{types.ApprovalStepApprover}, []string{approverId}},
+		{"should ask all administrators except the requester", []string{types.ApprovalStepTeamAdministrators}, []string{teamAdminId}},
+		{"should ask every approver once along the chain", []string{types.ApprovalStepApprover, types.ApprovalStepTeamAdministrators, types.ApprovalStepParentUnit},
+			[]string{approverId, teamAdminId, departmentAdminId}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teamStore := &mockTeam{}
+			teamStore.GetTeamAdministratorsMock = func(id string) ([]types.TeamUser, error) { return admins[id], nil }
+			teamStore.GetAncestorIdsMock = func(id string) ([]string, error) { return []string{departmentId}, nil }
+			handler := &Handler{teamStore: teamStore}
+
+			approvers, err := handler.approversAlongChain(&types.TeamSettings{ApprovalChain: tt.chain}, teamId, approverId, requesterId)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.approvers, approvers)
+		})
+	}
+}
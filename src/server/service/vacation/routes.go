@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
 	"github.com/go-playground/validator/v10"
@@ -12,27 +15,37 @@ import (
 	"github.com/gorilla/mux"
 )
 
+var errRequestDecided = fmt.Errorf("the request is decided already")
+
 type Handler struct {
-	db            *sql.DB
-	userStore     types.UserStore
-	teamStore     types.TeamStore
-	vacationStore types.VacationStore
+	db              *sql.DB
+	userStore       types.UserStore
+	teamStore       types.TeamStore
+	vacationStore   types.VacationStore
+	preferenceStore types.PreferenceStore
+	mailer          types.Mailer
 }
 
-func NewHandler(db *sql.DB, userStore types.UserStore, teamStore types.TeamStore, vacationStore types.VacationStore) *Handler {
-	return &Handler{db: db, userStore: userStore, teamStore: teamStore, vacationStore: vacationStore}
+func NewHandler(db *sql.DB, userStore types.UserStore, teamStore types.TeamStore, vacationStore types.VacationStore,
+	preferenceStore types.PreferenceStore, mailer types.Mailer) *Handler {
+	return &Handler{db: db, userStore: userStore, teamStore: teamStore, vacationStore: vacationStore,
+		preferenceStore: preferenceStore, mailer: mailer}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/vacations/request", h.CreateVacationRequest).Methods(http.MethodPost)
-	router.HandleFunc("/vacations/requests/updateApproval", h.UpdateRequestApproval).Methods(http.MethodPost)
-	router.HandleFunc("/vacations/requests/open", h.UpdateRequestApproval).Methods(http.MethodPost)
+	router.HandleFunc("/vacations/request", auth.Require(h.CreateVacationRequest, h.userStore, types.ScopeWriteVacations)).Methods(http.MethodPost)
+	router.HandleFunc("/vacations/requests/updateApproval", auth.Require(h.UpdateRequestApproval, h.userStore, types.ScopeWriteVacations)).Methods(http.MethodPost)
+	router.HandleFunc("/vacations/requests/{requestId}/escalate", auth.Require(h.handleEscalateRequest, h.userStore, types.ScopeWriteVacations)).Methods(http.MethodPost)
 }
 
+// UpdateRequestApproval stores the decision of the caller, only approvers of the
+// request can decide on it. The request is decided in the same transaction once its
+// approvals allow it, and only then the requester is notified.
 func (h *Handler) UpdateRequestApproval(w http.ResponseWriter, r *http.Request) {
 	var payload types.VacationApprovalPayload
 	if err := utils.ParseJson(r, &payload); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -40,23 +53,84 @@ func (h *Handler) UpdateRequestApproval(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	request, err := h.vacationStore.GetVacationRequestById(payload.RequestId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if !isUndecided(request.Status) {
+		utils.WriteError(w, http.StatusConflict, errRequestDecided)
+		return
+	}
+
+	approverId := auth.GetUserIdFromContext(r.Context())
+	approvals, err := h.vacationStore.GetApprovalsForRequest(payload.RequestId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !slices.ContainsFunc(approvals, func(approval types.VacationApproval) bool { return approval.ApproverId == approverId }) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only approvers of the request can decide on it"))
+		return
+	}
+
+	if payload.Status == types.APPROVAL_DECLINED {
+		if status, err := h.checkDeclineReason(payload); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	resolved := types.REQUEST_OPEN
 	ctx := r.Context()
-	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
+		approvals, err := h.vacationStore.LockApprovalsForRequest(tx, payload.RequestId)
+		if err != nil {
+			return err
+		}
 
-		if err := h.vacationStore.UpdateVacationStatus(tx, payload.RequestId, payload.ApproverId, payload.Status); err != nil {
+		if err := h.vacationStore.UpdateVacationStatus(tx, payload.RequestId, approverId, payload.Status, payload.Reason); err != nil {
 			return err
 		}
 
-		utils.WriteJson(w, http.StatusOK, nil)
+		for i := range approvals {
+			if approvals[i].ApproverId == approverId {
+				approvals[i].Status = payload.Status
+			}
+		}
+
+		resolved = types.ResolveRequestStatus(approvals)
+		if resolved != types.REQUEST_OPEN {
+			decided, err := h.vacationStore.DecideRequest(tx, payload.RequestId, resolved)
+			if err != nil {
+				return err
+			}
+
+			if !decided {
+				return utils.NewStatusError(http.StatusConflict, errRequestDecided)
+			}
+		}
+
 		return nil
 	})
 
+	if !committed {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, nil)
+	if resolved != types.REQUEST_OPEN {
+		h.notifyRequestDecided(payload.RequestId, resolved == types.REQUEST_APPROVED, payload.Reason)
+	}
 }
 
 func (h *Handler) CreateVacationRequest(w http.ResponseWriter, r *http.Request) {
 	var payload types.CreateVacationRequestPayload
 	if err := utils.ParseJson(r, &payload); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -64,45 +138,104 @@ func (h *Handler) CreateVacationRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if _, err := h.teamStore.GetTeamById(payload.TeamId); err != nil {
+	team, err := h.teamStore.GetTeamById(payload.TeamId)
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("team with id %s does not exists", payload.TeamId))
 		return
 	}
 
-	if _, err := h.userStore.GetUserById(payload.ToUserId); err != nil {
+	if team.IsArchived() {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("vacations can't be requested in an archived team"))
+		return
+	}
+
+	approver, err := h.userStore.GetUserById(payload.ToUserId)
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("userToId with id %s does not exists", payload.ToUserId))
 		return
 	}
 
-	if _, err := h.userStore.GetUserById(payload.RequestedFrom); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("requested from user with id %s does not exists", payload.RequestedFrom))
+	// everybody requests their own absences
+	requester, err := h.userStore.GetUserById(auth.GetUserIdFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx := r.Context()
-	utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
-
-		request := types.VacationRequest{
-			Id:            uuid.NewString(),
-			RequestedFrom: payload.RequestedFrom,
-			ToUserId:      payload.ToUserId,
-			TeamId:        payload.TeamId,
-			Info:          payload.Info,
-			Status:        types.REQUEST_OPEN,
-			FromDate:      payload.FromDate,
-			ToDate:        payload.ToDate,
-		}
+	// the days of a vacation are calendar days in the timezone of the requester
+	preferences, err := h.preferenceStore.GetPreferences(requester.Id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	fromDate := calendarDate(payload.FromDate, preferences.Location())
+	toDate := calendarDate(payload.ToDate, preferences.Location())
+	if toDate.Before(fromDate) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the vacation must not end before it starts"))
+		return
+	}
+
+	settings, err := h.teamStore.GetTeamSettings(team.Id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
+	absenceType := payload.Type
+	if absenceType == "" {
+		absenceType = types.AbsenceVacation
+	}
+
+	today := calendarDate(time.Now(), preferences.Location())
+	if err := checkPolicies(settings, absenceType, today, fromDate, toDate); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	approverIds, err := h.approversAlongChain(settings, team.Id, approver.Id, requester.Id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	request := types.VacationRequest{
+		Id:            uuid.NewString(),
+		RequestedFrom: requester.Id,
+		ToUserId:      payload.ToUserId,
+		TeamId:        payload.TeamId,
+		Info:          payload.Info,
+		Type:          absenceType,
+		Status:        types.REQUEST_OPEN,
+		FromDate:      fromDate,
+		ToDate:        toDate,
+	}
+
+	ctx := r.Context()
+	committed := utils.WithTransaction(ctx, h.db, w, func(tx *sql.Tx) error {
 		if err := h.vacationStore.CreateVacationRequest(tx, request); err != nil {
 			return err
 		}
 
-		// Create ApprovalItem for teamlead, here should also item be created for the
-		if err := h.vacationStore.CreateApprovalEntry(tx, request.Id, request.ToUserId); err != nil {
-			return err
+		for _, approverId := range approverIds {
+			if err := h.vacationStore.CreateApprovalEntry(tx, request.Id, approverId); err != nil {
+				return err
+			}
 		}
 
 		utils.WriteJson(w, http.StatusOK, nil)
 		return nil
 	})
+
+	if !committed {
+		return
+	}
+
+	for _, approverId := range approverIds {
+		if approverId == approver.Id {
+			h.notifyRequestCreated(approver, requester, request)
+		} else if u, err := h.userStore.GetUserById(approverId); err == nil {
+			h.notifyRequestCreated(u, requester, request)
+		}
+	}
 }
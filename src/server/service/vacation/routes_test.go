@@ -1 +1,841 @@
 package vacation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cebuh/simpleHolidayPlaner/config"
+	"github.com/cebuh/simpleHolidayPlaner/service/auth"
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/cebuh/simpleHolidayPlaner/utils/routecheck"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	requesterId = uuid.NewString()
+	approverId  = uuid.NewString()
+)
+
+func newTestHandler(t *testing.T, vacationStore *mockVacation, preferences map[string]*types.UserPreferences, mailer *mockMailer) (*Handler, *sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	users := map[string]*types.User{
+		requesterId: {Id: requesterId, Name: "Chris", Email: "chris@email.com"},
+		approverId:  {Id: approverId, Name: "Alex", Email: "alex@email.com"},
+	}
+	userStore := &mockUser{}
+	userStore.GetUserByIdMock = func(id string) (*types.User, error) {
+		u, ok := users[id]
+		if !ok {
+			return nil, fmt.Errorf("user not found")
+		}
+		return u, nil
+	}
+	teamStore := &mockTeam{}
+	teamStore.GetTeamByIdMock = func(id string) (*types.Team, error) { return &types.Team{Id: id, Name: "Support"}, nil }
+	teamStore.GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) { return types.DefaultTeamSettings(), nil }
+	preferenceStore := &mockPreferences{}
+	preferenceStore.GetPreferencesMock = func(userId string) (*types.UserPreferences, error) {
+		if p, ok := preferences[userId]; ok {
+			return p, nil
+		}
+		return types.DefaultPreferences(userId), nil
+	}
+
+	return NewHandler(db, userStore, teamStore, vacationStore, preferenceStore, mailer), db, mock
+}
+
+func Test_CreateVacationRequest(t *testing.T) {
+	berlin := types.DefaultPreferences(requesterId)
+	berlin.Timezone = "Europe/Berlin"
+	muted := types.DefaultPreferences(approverId)
+	muted.Notifications = map[string][]string{}
+
+	tests := []struct {
+		name        string
+		from        string
+		to          string
+		preferences map[string]*types.UserPreferences
+		status      int
+		fromDate    time.Time
+		notified    bool
+	}{
+		{"should store the calendar days of the requester", "2026-10-20T00:00:00+02:00", "2026-10-23T00:00:00+02:00",
+			map[string]*types.UserPreferences{requesterId: berlin}, http.StatusOK, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), true},
+		{"should not notify approvers who turned the mails off", "2026-10-20T00:00:00Z", "2026-10-23T00:00:00Z",
+			map[string]*types.UserPreferences{approverId: muted}, http.StatusOK, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), false},
+		{"should reject requests which end before they start", "2026-10-23T00:00:00Z", "2026-10-20T00:00:00Z",
+			nil, http.StatusBadRequest, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *types.VacationRequest
+			vacationStore := &mockVacation{}
+			vacationStore.CreateVacationRequestMock = func(execable interface{}, request types.VacationRequest) error {
+				created = &request
+				return nil
+			}
+			mailer := &mockMailer{}
+			handler, db, mock := newTestHandler(t, vacationStore, tt.preferences, mailer)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			payload := fmt.Sprintf(`{"toUserId":%q,"teamId":%q,"info":"family trip","fromDate":%q,"toDate":%q}`,
+				approverId, uuid.NewString(), tt.from, tt.to)
+			req, err := http.NewRequest(http.MethodPost, "/vacations/request", strings.NewReader(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, requesterId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/vacations/request", handler.CreateVacationRequest).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tt.status != http.StatusOK {
+				require.Nil(t, created)
+				return
+			}
+
+			require.Equal(t, requesterId, created.RequestedFrom)
+			require.Equal(t, tt.fromDate, created.FromDate)
+			require.Equal(t, types.AbsenceVacation, created.Type)
+			if tt.notified {
+				require.Equal(t, []string{"alex@email.com"}, mailer.sentTo)
+			} else {
+				require.Empty(t, mailer.sentTo)
+			}
+		})
+	}
+}
+
+func Test_UpdateRequestApproval(t *testing.T) {
+	otherApproverId := uuid.NewString()
+	tests := []struct {
+		name      string
+		others    types.ApprovalStatus
+		decision  types.ApprovalStatus
+		decided   bool
+		commitErr error
+		status    int
+		resolved  types.RequestStatus
+		sentTo    []string
+	}{
+		{"should keep the request open until every approver approved", types.APPROVAL_OPEN, types.APPROVAL_APPROVED, true, nil,
+			http.StatusOK, types.REQUEST_OPEN, nil},
+		{"should approve the request with the last approval", types.APPROVAL_APPROVED, types.APPROVAL_APPROVED, true, nil,
+			http.StatusOK, types.REQUEST_APPROVED, []string{"chris@email.com"}},
+		{"should decline the request with the first decline", types.APPROVAL_OPEN, types.APPROVAL_DECLINED, true, nil,
+			http.StatusOK, types.REQUEST_DECLINED, []string{"chris@email.com"}},
+		{"should not notify if the commit fails", types.APPROVAL_APPROVED, types.APPROVAL_APPROVED, true, fmt.Errorf("connection lost"),
+			http.StatusInternalServerError, types.REQUEST_APPROVED, nil},
+		{"should fail if the request was decided meanwhile", types.APPROVAL_APPROVED, types.APPROVAL_APPROVED, false, nil,
+			http.StatusConflict, types.REQUEST_APPROVED, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestId := uuid.NewString()
+			approvals := []types.VacationApproval{
+				{RequestId: requestId, ApproverId: approverId, Status: types.APPROVAL_OPEN},
+				{RequestId: requestId, ApproverId: otherApproverId, Status: tt.others},
+			}
+			resolved := types.REQUEST_OPEN
+			vacationStore := &mockVacation{}
+			vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+				return &types.VacationRequest{Id: id, RequestedFrom: requesterId, Status: types.REQUEST_OPEN, FromDate: time.Now(), ToDate: time.Now()}, nil
+			}
+			vacationStore.GetApprovalsForRequestMock = func(id string) ([]types.VacationApproval, error) { return approvals, nil }
+			vacationStore.LockApprovalsForRequestMock = func(execable interface{}, id string) ([]types.VacationApproval, error) {
+				return slices.Clone(approvals), nil
+			}
+			vacationStore.UpdateVacationStatusMock = func(execable interface{}, id string, approver string, status types.ApprovalStatus, reason string) error {
+				require.Equal(t, approverId, approver)
+				return nil
+			}
+			vacationStore.DecideRequestMock = func(execable interface{}, id string, status types.RequestStatus) (bool, error) {
+				resolved = status
+				return tt.decided, nil
+			}
+			mailer := &mockMailer{}
+			handler, db, mock := newTestHandler(t, vacationStore, nil, mailer)
+			defer db.Close()
+			mock.ExpectBegin()
+			if tt.decided {
+				mock.ExpectCommit().WillReturnError(tt.commitErr)
+			} else {
+				mock.ExpectRollback()
+			}
+
+			payload := fmt.Sprintf(`{"requestId":%q,"status":%d,"reason":"team event"}`, requestId, tt.decision)
+			req, err := http.NewRequest(http.MethodPost, "/vacations/requests/updateApproval", strings.NewReader(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, approverId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/vacations/requests/updateApproval", handler.UpdateRequestApproval).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.Equal(t, tt.resolved, resolved)
+			require.Equal(t, tt.sentTo, mailer.sentTo)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_UpdateRequestApproval_Should_Fail_IfRequestIsDecided(t *testing.T) {
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+		return &types.VacationRequest{Id: id, RequestedFrom: requesterId, Status: types.REQUEST_CANCELLED}, nil
+	}
+	handler, db, mock := newTestHandler(t, vacationStore, nil, &mockMailer{})
+	defer db.Close()
+
+	payload := fmt.Sprintf(`{"requestId":%q,"status":%d}`, uuid.NewString(), types.APPROVAL_APPROVED)
+	req, err := http.NewRequest(http.MethodPost, "/vacations/requests/updateApproval", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, approverId))
+
+	testHttp := httptest.NewRecorder()
+	handler.UpdateRequestApproval(testHttp, req)
+
+	require.Equal(t, http.StatusConflict, testHttp.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_DeclineRequest_Should_Require_Reason_IfTeamWantsOne(t *testing.T) {
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+		return &types.VacationRequest{Id: id, RequestedFrom: requesterId, TeamId: uuid.NewString()}, nil
+	}
+	vacationStore.UpdateVacationStatusMock = func(execable interface{}, id string, approver string, status types.ApprovalStatus, reason string) error {
+		t.Fatal("the decline must not be stored without a reason")
+		return nil
+	}
+	vacationStore.GetApprovalsForRequestMock = func(id string) ([]types.VacationApproval, error) {
+		return []types.VacationApproval{{RequestId: id, ApproverId: approverId}}, nil
+	}
+	handler, db, mock := newTestHandler(t, vacationStore, nil, &mockMailer{})
+	defer db.Close()
+	handler.teamStore.(*mockTeam).GetTeamSettingsMock = func(teamId string) (*types.TeamSettings, error) {
+		settings := types.DefaultTeamSettings()
+		settings.DeclineRequiresReason = true
+		return settings, nil
+	}
+
+	payload := fmt.Sprintf(`{"requestId":%q,"status":%d,"reason":"  "}`, uuid.NewString(), types.APPROVAL_DECLINED)
+	req, err := http.NewRequest(http.MethodPost, "/vacations/requests/updateApproval", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, approverId))
+
+	testHttp := httptest.NewRecorder()
+	handler.UpdateRequestApproval(testHttp, req)
+
+	require.Equal(t, http.StatusBadRequest, testHttp.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_UpdateRequestApproval_Should_Fail_IfCallerIsNoApprover(t *testing.T) {
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+		return &types.VacationRequest{Id: id, RequestedFrom: requesterId, Status: types.REQUEST_OPEN}, nil
+	}
+	vacationStore.GetApprovalsForRequestMock = func(id string) ([]types.VacationApproval, error) {
+		return []types.VacationApproval{{RequestId: id, ApproverId: approverId}}, nil
+	}
+	vacationStore.UpdateVacationStatusMock = func(execable interface{}, id string, approver string, status types.ApprovalStatus, reason string) error {
+		t.Fatal("only approvers can decide")
+		return nil
+	}
+	handler, db, mock := newTestHandler(t, vacationStore, nil, &mockMailer{})
+	defer db.Close()
+
+	payload := fmt.Sprintf(`{"requestId":%q,"status":%d}`, uuid.NewString(), types.APPROVAL_APPROVED)
+	req, err := http.NewRequest(http.MethodPost, "/vacations/requests/updateApproval", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, requesterId))
+
+	testHttp := httptest.NewRecorder()
+	handler.UpdateRequestApproval(testHttp, req)
+
+	require.Equal(t, http.StatusForbidden, testHttp.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_EscalateRequest(t *testing.T) {
+	teamId := uuid.NewString()
+	departmentId := uuid.NewString()
+	companyId := uuid.NewString()
+	departmentAdminId := uuid.NewString()
+	companyAdminId := uuid.NewString()
+
+	tests := []struct {
+		name      string
+		callerId  string
+		approvers []string
+		status    int
+		teamId    string
+		mailedTo  []string
+	}{
+		{"should escalate to the parent unit", approverId, []string{approverId}, http.StatusOK, departmentId, []string{"dana@email.com"}},
+		{"should skip units which were asked already", approverId, []string{approverId, departmentAdminId}, http.StatusOK, companyId, []string{"sam@email.com"}},
+		{"should fail at the top of the tree", approverId, []string{approverId, departmentAdminId, companyAdminId}, http.StatusConflict, "", nil},
+		{"should fail if the caller is no approver", requesterId, []string{approverId}, http.StatusForbidden, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestId := uuid.NewString()
+			vacationStore := &mockVacation{}
+			vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+				return &types.VacationRequest{Id: id, RequestedFrom: requesterId, TeamId: teamId, Status: types.REQUEST_OPEN}, nil
+			}
+			vacationStore.GetApprovalsForRequestMock = func(id string) ([]types.VacationApproval, error) {
+				approvals := make([]types.VacationApproval, 0)
+				for _, approver := range tt.approvers {
+					approvals = append(approvals, types.VacationApproval{RequestId: id, ApproverId: approver})
+				}
+				return approvals, nil
+			}
+			mailer := &mockMailer{}
+			handler, db, mock := newTestHandler(t, vacationStore, nil, mailer)
+			defer db.Close()
+			if tt.status == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			users := map[string]*types.User{
+				requesterId:       {Id: requesterId, Name: "Chris", Email: "chris@email.com"},
+				departmentAdminId: {Id: departmentAdminId, Name: "Dana", Email: "dana@email.com"},
+				companyAdminId:    {Id: companyAdminId, Name: "Sam", Email: "sam@email.com"},
+			}
+			handler.userStore.(*mockUser).GetUserByIdMock = func(id string) (*types.User, error) {
+				if u, ok := users[id]; ok {
+					return u, nil
+				}
+				return nil, fmt.Errorf("user not found")
+			}
+			teamStore := handler.teamStore.(*mockTeam)
+			teamStore.GetAncestorIdsMock = func(id string) ([]string, error) { return []string{departmentId, companyId}, nil }
+			teamStore.GetTeamAdministratorsMock = func(id string) ([]types.TeamUser, error) {
+				admins := map[string]string{departmentId: departmentAdminId, companyId: companyAdminId}
+				return []types.TeamUser{{Id: admins[id]}}, nil
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/vacations/requests/"+requestId+"/escalate", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, tt.callerId))
+
+			testHttp := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/vacations/requests/{requestId}/escalate", handler.handleEscalateRequest).Methods(http.MethodPost)
+			router.ServeHTTP(testHttp, req)
+
+			require.Equal(t, tt.status, testHttp.Code, testHttp.Body.String())
+			require.Equal(t, tt.mailedTo, mailer.sentTo)
+			if tt.status == http.StatusOK {
+				var result types.EscalationResult
+				require.NoError(t, json.NewDecoder(testHttp.Body).Decode(&result))
+				require.Equal(t, tt.teamId, result.TeamId)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_Routes_Should_Not_Return_SensitiveFields(t *testing.T) {
+	vacationStore := &mockVacation{}
+	vacationStore.GetVacationRequestByIdMock = func(id string) (*types.VacationRequest, error) {
+		return &types.VacationRequest{Id: id, RequestedFrom: requesterId, TeamId: uuid.NewString(), Status: types.REQUEST_OPEN}, nil
+	}
+	vacationStore.GetApprovalsForRequestMock = func(id string) ([]types.VacationApproval, error) {
+		return []types.VacationApproval{{RequestId: id, ApproverId: approverId, Status: types.APPROVAL_OPEN}}, nil
+	}
+	handler, db, mock := newTestHandler(t, vacationStore, nil, &mockMailer{})
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	// the request can be escalated to the administrator of the department
+	teamStore := handler.teamStore.(*mockTeam)
+	teamStore.GetAncestorIdsMock = func(id string) ([]string, error) { return []string{uuid.NewString()}, nil }
+	teamStore.GetTeamAdministratorsMock = func(id string) ([]types.TeamUser, error) {
+		return []types.TeamUser{{Id: requesterId, Name: "Chris", Email: "chris@email.com"}, {Id: uuid.NewString()}}, nil
+	}
+	userStore := handler.userStore.(*mockUser)
+	userStore.GetSessionMock = func(id string) (*types.Session, error) { return &types.Session{Id: id, UserId: approverId}, nil }
+	userStore.TouchSessionMock = func(id string) error { return nil }
+	token, err := auth.CreateSessionJWT([]byte(config.Envs.JWTSecret), approverId, uuid.NewString())
+	require.NoError(t, err)
+
+	routecheck.CheckResponses(t, router, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+type mockVacation struct {
+	GetApprovalsForRequestMock        func(requestId string) ([]types.VacationApproval, error)
+	CreateVacationRequestMock         func(execable interface{}, request types.VacationRequest) error
+	UpdateVacationStatusMock          func(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error
+	GetVacationRequestByIdMock        func(id string) (*types.VacationRequest, error)
+	CancelUndecidedRequestsInTeamMock func(execable interface{}, userId, teamId string) error
+	GetAbsencesOfTeamsMock            func(teamIds []string, from, to time.Time) ([]types.Absence, error)
+	LockApprovalsForRequestMock       func(execable interface{}, requestId string) ([]types.VacationApproval, error)
+	DecideRequestMock                 func(execable interface{}, requestId string, status types.RequestStatus) (bool, error)
+	ClearApprovalReasonsOfUserMock    func(execable interface{}, userId string) error
+}
+
+func (m *mockVacation) CreateVacationRequest(execable interface{}, request types.VacationRequest) error {
+	return m.CreateVacationRequestMock(execable, request)
+}
+
+func (m *mockVacation) GetVacationRequestsForUser(toUserId string) ([]types.VacationRequest, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) GetVacationRequestsFromUserId(requestedFromId string) ([]types.VacationRequest, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error {
+	return m.UpdateVacationStatusMock(execable, requestId, approverId, status, reason)
+}
+
+func (m *mockVacation) GetApprovalsForRequest(requestId string) ([]types.VacationApproval, error) {
+	return m.GetApprovalsForRequestMock(requestId)
+}
+
+func (m *mockVacation) CreateApprovalEntry(execable interface{}, requestId string, approverId string) error {
+	return nil
+}
+
+func (m *mockVacation) GetTeamsWithOpenApprovals(approverId string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) ReassignOpenApprovals(execable interface{}, teamId, fromApproverId, toApproverId string) error {
+	return nil
+}
+
+func (m *mockVacation) CancelFutureRequestsOfUser(execable interface{}, userId string, from time.Time) error {
+	return nil
+}
+
+func (m *mockVacation) GetApprovalsOfApprover(approverId string) ([]types.VacationApproval, error) {
+	return nil, nil
+}
+
+func (m *mockVacation) ClearRequestInfosOfUser(execable interface{}, userId string) error {
+	return nil
+}
+
+func (m *mockVacation) GetVacationRequestById(id string) (*types.VacationRequest, error) {
+	return m.GetVacationRequestByIdMock(id)
+}
+
+func (m *mockVacation) CancelUndecidedRequestsInTeam(execable interface{}, userId, teamId string) error {
+	return m.CancelUndecidedRequestsInTeamMock(execable, userId, teamId)
+}
+
+func (m *mockVacation) GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]types.Absence, error) {
+	return m.GetAbsencesOfTeamsMock(teamIds, from, to)
+}
+
+func (m *mockVacation) LockApprovalsForRequest(execable interface{}, requestId string) ([]types.VacationApproval, error) {
+	return m.LockApprovalsForRequestMock(execable, requestId)
+}
+
+func (m *mockVacation) DecideRequest(execable interface{}, requestId string, status types.RequestStatus) (bool, error) {
+	return m.DecideRequestMock(execable, requestId, status)
+}
+
+func (m *mockVacation) ClearApprovalReasonsOfUser(execable interface{}, userId string) error {
+	return m.ClearApprovalReasonsOfUserMock(execable, userId)
+}
+
+type mockPreferences struct {
+	GetPreferencesMock    func(userId string) (*types.UserPreferences, error)
+	SavePreferencesMock   func(preferences types.UserPreferences) error
+	DeletePreferencesMock func(execable interface{}, userId string) error
+}
+
+func (m *mockPreferences) GetPreferences(userId string) (*types.UserPreferences, error) {
+	return m.GetPreferencesMock(userId)
+}
+
+func (m *mockPreferences) SavePreferences(preferences types.UserPreferences) error {
+	return m.SavePreferencesMock(preferences)
+}
+
+func (m *mockPreferences) DeletePreferences(execable interface{}, userId string) error {
+	return m.DeletePreferencesMock(execable, userId)
+}
+
+type mockMailer struct {
+	sentTo []string
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.sentTo = append(m.sentTo, to)
+	return nil
+}
+
+type mockTeam struct {
+	GetAllTeamsMock             func(includeArchived bool) ([]types.Team, error)
+	CreateTeamMock              func(execable interface{}, team types.Team) error
+	GetTeamByIdMock             func(id string) (*types.Team, error)
+	GetTeamByNameMock           func(name string) (*types.Team, error)
+	AddUserToTeamMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	RemoveUserFromTeamMock      func(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeamMock       func(userId, teamId string) (types.UserRole, error)
+	GetTeamsOfUserMock          func(userId string) ([]types.UserTeam, error)
+	GetTeamAdministratorsMock   func(teamId string) ([]types.TeamUser, error)
+	RemoveUserFromAllTeamsMock  func(execable interface{}, userId string) error
+	SetMemberRoleMock           func(execable interface{}, userId, teamId string, role types.UserRole) error
+	LockTeamAdministratorsMock  func(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistoryMock       func(execable interface{}, entry types.TeamHistoryEntry) error
+	GetTeamHistoryMock          func(teamId string) ([]types.TeamHistoryEntry, error)
+	GetTeamSettingsMock         func(teamId string) (*types.TeamSettings, error)
+	SaveTeamSettingsMock        func(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error
+	SetTeamArchivedMock         func(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeamMock func(execable interface{}, teamId string) error
+	GetTeamDeletionPreviewMock  func(teamId string) (*types.TeamDeletionPreview, error)
+	DeleteTeamMock              func(execable interface{}, teamId string) error
+	GetAncestorIdsMock          func(teamId string) ([]string, error)
+	GetDescendantIdsMock        func(teamId string) ([]string, error)
+	MoveTeamMock                func(execable interface{}, teamId string, parentId *string) error
+	GetTeamSettingsVersionsMock func(teamId string) ([]types.TeamSettingsVersion, error)
+	ListTeamsMock               func(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error)
+	GetFormerTeamIdsOfUserMock  func(userId string) ([]string, error)
+}
+
+func (m *mockTeam) GetAllTeams(includeArchived bool) ([]types.Team, error) {
+	return m.GetAllTeamsMock(includeArchived)
+}
+
+func (m *mockTeam) GetTeamById(id string) (*types.Team, error) {
+	return m.GetTeamByIdMock(id)
+}
+
+func (m *mockTeam) CreateTeam(execable interface{}, t types.Team) error {
+	return m.CreateTeamMock(execable, t)
+}
+
+func (m *mockTeam) GetTeamByName(name string) (*types.Team, error) {
+	return m.GetTeamByNameMock(name)
+}
+
+func (m *mockTeam) AddUserToTeam(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.AddUserToTeamMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) RemoveUserFromTeam(execable interface{}, userId, teamId string) error {
+	return m.RemoveUserFromTeamMock(execable, userId, teamId)
+}
+
+func (m *mockTeam) RenameTeam(name, teamId string) error {
+	return nil
+}
+
+func (m *mockTeam) GetUserRoleInTeam(userId, teamId string) (types.UserRole, error) {
+	return m.GetUserRoleInTeamMock(userId, teamId)
+}
+
+func (m *mockTeam) GetTeamsOfUser(userId string) ([]types.UserTeam, error) {
+	return m.GetTeamsOfUserMock(userId)
+}
+
+func (m *mockTeam) GetTeamAdministrators(teamId string) ([]types.TeamUser, error) {
+	return m.GetTeamAdministratorsMock(teamId)
+}
+
+func (m *mockTeam) RemoveUserFromAllTeams(execable interface{}, userId string) error {
+	return m.RemoveUserFromAllTeamsMock(execable, userId)
+}
+
+func (m *mockTeam) SetMemberRole(execable interface{}, userId, teamId string, role types.UserRole) error {
+	return m.SetMemberRoleMock(execable, userId, teamId, role)
+}
+
+func (m *mockTeam) LockTeamAdministrators(execable interface{}, teamId string) ([]string, error) {
+	return m.LockTeamAdministratorsMock(execable, teamId)
+}
+
+func (m *mockTeam) RecordTeamHistory(execable interface{}, entry types.TeamHistoryEntry) error {
+	return m.RecordTeamHistoryMock(execable, entry)
+}
+
+func (m *mockTeam) GetTeamHistory(teamId string) ([]types.TeamHistoryEntry, error) {
+	return m.GetTeamHistoryMock(teamId)
+}
+
+func (m *mockTeam) GetTeamSettings(teamId string) (*types.TeamSettings, error) {
+	return m.GetTeamSettingsMock(teamId)
+}
+
+func (m *mockTeam) SaveTeamSettings(execable interface{}, teamId string, settings types.TeamSettings, changedBy string) error {
+	return m.SaveTeamSettingsMock(execable, teamId, settings, changedBy)
+}
+
+func (m *mockTeam) SetTeamArchived(execable interface{}, teamId string, archived bool) error {
+	return m.SetTeamArchivedMock(execable, teamId, archived)
+}
+
+func (m *mockTeam) CancelOpenInvitesOfTeam(execable interface{}, teamId string) error {
+	return m.CancelOpenInvitesOfTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetTeamDeletionPreview(teamId string) (*types.TeamDeletionPreview, error) {
+	return m.GetTeamDeletionPreviewMock(teamId)
+}
+
+func (m *mockTeam) DeleteTeam(execable interface{}, teamId string) error {
+	return m.DeleteTeamMock(execable, teamId)
+}
+
+func (m *mockTeam) GetAncestorIds(teamId string) ([]string, error) {
+	return m.GetAncestorIdsMock(teamId)
+}
+
+func (m *mockTeam) GetDescendantIds(teamId string) ([]string, error) {
+	return m.GetDescendantIdsMock(teamId)
+}
+
+func (m *mockTeam) MoveTeam(execable interface{}, teamId string, parentId *string) error {
+	return m.MoveTeamMock(execable, teamId, parentId)
+}
+
+func (m *mockTeam) GetTeamSettingsVersions(teamId string) ([]types.TeamSettingsVersion, error) {
+	return m.GetTeamSettingsVersionsMock(teamId)
+}
+
+func (m *mockTeam) ListTeams(filter types.TeamFilter, page types.PageRequest) (*types.Page[types.TeamListEntry], error) {
+	return m.ListTeamsMock(filter, page)
+}
+
+func (m *mockTeam) GetFormerTeamIdsOfUser(userId string) ([]string, error) {
+	return m.GetFormerTeamIdsOfUserMock(userId)
+}
+
+type mockUser struct {
+	GetUserByEmailMock           func(email string) (*types.User, error)
+	GetUserByIdMock              func(id string) (*types.User, error)
+	CreateUserMock               func(execable interface{}, u types.User) error
+	ChangePasswordMock           func(execable interface{}, userId, hashedPassword string) error
+	VerifyEmailMock              func(userId string) error
+	RecordFailedLoginMock        func(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLoginsMock        func(userId string) error
+	SetTotpSecretMock            func(userId string, secret *string) error
+	EnableTotpMock               func(execable interface{}, userId string) error
+	DisableTotpMock              func(execable interface{}, userId string) error
+	ReplaceRecoveryCodesMock     func(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCodeMock          func(userId, codeHash string) (bool, error)
+	GetUsersFromTeamMock         func(teamId string) ([]types.TeamUser, error)
+	GetUserByOidcSubjectMock     func(subject string) (*types.User, error)
+	SetOidcSubjectMock           func(execable interface{}, userId, subject string) error
+	CreateAccessTokenMock        func(token types.PersonalAccessToken) error
+	GetAccessTokensOfUserMock    func(userId string) ([]types.PersonalAccessToken, error)
+	GetAccessTokenByHashMock     func(hash string) (*types.PersonalAccessToken, error)
+	TouchAccessTokenMock         func(id string) error
+	DeleteAccessTokenMock        func(userId, id string) error
+	UpdatePasswordHashMock       func(userId, hashedPassword string) error
+	UpdateNameMock               func(execable interface{}, userId, name string) error
+	UpdateEmailMock              func(execable interface{}, userId, email string) error
+	SetAvatarKeyMock             func(userId string, key *string) error
+	DeactivateUserMock           func(execable interface{}, userId string) error
+	DeleteAccessTokensOfUserMock func(execable interface{}, userId string) error
+	PseudonymizeUserMock         func(execable interface{}, userId, name, email string) error
+	SearchUsersMock              func(filter types.UserSearchFilter) ([]types.PublicUser, error)
+	CreateSessionMock            func(execable interface{}, session types.Session) error
+	GetSessionMock               func(id string) (*types.Session, error)
+	GetSessionsOfUserMock        func(userId string, includeRevoked bool) ([]types.Session, error)
+	TouchSessionMock             func(id string) error
+	RevokeSessionMock            func(userId, id string) error
+	RevokeSessionsOfUserMock     func(execable interface{}, userId string) error
+	SetSystemRoleMock            func(execable interface{}, userId string, role types.SystemRole) error
+	ListUsersMock                func(query string, limit, offset int) ([]types.User, error)
+	DeleteUserMock               func(execable interface{}, userId string) error
+	DeleteSessionsOfUserMock     func(execable interface{}, userId string) error
+}
+
+func (m *mockUser) GetUserByEmail(email string) (*types.User, error) {
+	return m.GetUserByEmailMock(email)
+}
+func (m *mockUser) GetUserById(id string) (*types.User, error) {
+	return m.GetUserByIdMock(id)
+}
+func (m *mockUser) CreateUser(execable interface{}, u types.User) error {
+	return m.CreateUserMock(execable, u)
+}
+
+func (m *mockUser) ChangePassword(execable interface{}, userId, hashedPassword string) error {
+	return m.ChangePasswordMock(execable, userId, hashedPassword)
+}
+
+func (m *mockUser) VerifyEmail(userId string) error {
+	return m.VerifyEmailMock(userId)
+}
+
+func (m *mockUser) RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error {
+	return m.RecordFailedLoginMock(userId, failedLogins, lockedUntil)
+}
+
+func (m *mockUser) ResetFailedLogins(userId string) error {
+	return m.ResetFailedLoginsMock(userId)
+}
+
+func (m *mockUser) SetTotpSecret(userId string, secret *string) error {
+	return m.SetTotpSecretMock(userId, secret)
+}
+
+func (m *mockUser) EnableTotp(execable interface{}, userId string) error {
+	return m.EnableTotpMock(execable, userId)
+}
+
+func (m *mockUser) DisableTotp(execable interface{}, userId string) error {
+	return m.DisableTotpMock(execable, userId)
+}
+
+func (m *mockUser) ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error {
+	return m.ReplaceRecoveryCodesMock(execable, userId, codeHashes)
+}
+
+func (m *mockUser) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	return m.UseRecoveryCodeMock(userId, codeHash)
+}
+
+func (m *mockUser) GetUserByOidcSubject(subject string) (*types.User, error) {
+	return m.GetUserByOidcSubjectMock(subject)
+}
+
+func (m *mockUser) SetOidcSubject(execable interface{}, userId, subject string) error {
+	return m.SetOidcSubjectMock(execable, userId, subject)
+}
+
+func (m *mockUser) GetUsersFromTeam(teamId string) ([]types.TeamUser, error) {
+	return m.GetUsersFromTeamMock(teamId)
+}
+
+func (m *mockUser) CreateAccessToken(token types.PersonalAccessToken) error {
+	return m.CreateAccessTokenMock(token)
+}
+
+func (m *mockUser) GetAccessTokensOfUser(userId string) ([]types.PersonalAccessToken, error) {
+	return m.GetAccessTokensOfUserMock(userId)
+}
+
+func (m *mockUser) GetAccessTokenByHash(hash string) (*types.PersonalAccessToken, error) {
+	return m.GetAccessTokenByHashMock(hash)
+}
+
+func (m *mockUser) TouchAccessToken(id string) error {
+	return m.TouchAccessTokenMock(id)
+}
+
+func (m *mockUser) DeleteAccessToken(userId, id string) error {
+	return m.DeleteAccessTokenMock(userId, id)
+}
+
+func (m *mockUser) UpdatePasswordHash(userId, hashedPassword string) error {
+	return m.UpdatePasswordHashMock(userId, hashedPassword)
+}
+
+func (m *mockUser) UpdateName(execable interface{}, userId, name string) error {
+	return m.UpdateNameMock(execable, userId, name)
+}
+
+func (m *mockUser) UpdateEmail(execable interface{}, userId, email string) error {
+	return m.UpdateEmailMock(execable, userId, email)
+}
+
+func (m *mockUser) SetAvatarKey(userId string, key *string) error {
+	return m.SetAvatarKeyMock(userId, key)
+}
+
+func (m *mockUser) DeactivateUser(execable interface{}, userId string) error {
+	return m.DeactivateUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteAccessTokensOfUser(execable interface{}, userId string) error {
+	return m.DeleteAccessTokensOfUserMock(execable, userId)
+}
+
+func (m *mockUser) PseudonymizeUser(execable interface{}, userId, name, email string) error {
+	return m.PseudonymizeUserMock(execable, userId, name, email)
+}
+
+func (m *mockUser) SearchUsers(filter types.UserSearchFilter) ([]types.PublicUser, error) {
+	return m.SearchUsersMock(filter)
+}
+
+func (m *mockUser) CreateSession(execable interface{}, session types.Session) error {
+	return m.CreateSessionMock(execable, session)
+}
+
+func (m *mockUser) GetSession(id string) (*types.Session, error) {
+	return m.GetSessionMock(id)
+}
+
+func (m *mockUser) GetSessionsOfUser(userId string, includeRevoked bool) ([]types.Session, error) {
+	return m.GetSessionsOfUserMock(userId, includeRevoked)
+}
+
+func (m *mockUser) TouchSession(id string) error {
+	return m.TouchSessionMock(id)
+}
+
+func (m *mockUser) RevokeSession(userId, id string) error {
+	return m.RevokeSessionMock(userId, id)
+}
+
+func (m *mockUser) RevokeSessionsOfUser(execable interface{}, userId string) error {
+	return m.RevokeSessionsOfUserMock(execable, userId)
+}
+
+func (m *mockUser) SetSystemRole(execable interface{}, userId string, role types.SystemRole) error {
+	return m.SetSystemRoleMock(execable, userId, role)
+}
+
+func (m *mockUser) ListUsers(query string, limit, offset int) ([]types.User, error) {
+	return m.ListUsersMock(query, limit, offset)
+}
+
+func (m *mockUser) DeleteUser(execable interface{}, userId string) error {
+	return m.DeleteUserMock(execable, userId)
+}
+
+func (m *mockUser) DeleteSessionsOfUser(execable interface{}, userId string) error {
+	return m.DeleteSessionsOfUserMock(execable, userId)
+}
@@ -2,6 +2,9 @@ package vacation
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cebuh/simpleHolidayPlaner/types"
 	"github.com/cebuh/simpleHolidayPlaner/utils"
@@ -16,8 +19,8 @@ func NewStore(db *sql.DB) *Store {
 }
 
 func (s *Store) CreateVacationRequest(execable interface{}, request types.VacationRequest) error {
-	_, err := utils.Exec(execable, "INSERT INTO vacation_requests (id, requestedFrom, toUserId, teamId, fromDate, toDate, info, requestStatus) VALUES (?,?,?,?,?,?,?,?)",
-		request.Id, request.RequestedFrom, request.ToUserId, request.TeamId, request.FromDate, request.ToDate, request.Info, request.Status)
+	_, err := utils.Exec(execable, "INSERT INTO vacation_requests (id, requestedFrom, toUserId, teamId, fromDate, toDate, info, absenceType, requestStatus) VALUES (?,?,?,?,?,?,?,?,?)",
+		request.Id, request.RequestedFrom, request.ToUserId, request.TeamId, request.FromDate, request.ToDate, request.Info, request.Type, request.Status)
 
 	if err != nil {
 		return err
@@ -29,13 +32,55 @@ func (s *Store) GetVacationRequestsForUser(toUserId string) ([]types.VacationReq
 	return nil, nil
 }
 
+const requestColumns = "id, requestedFrom, toUserId, teamId, info, absenceType, requestStatus, fromDate, toDate, changedAt, createdAt"
+
 func (s *Store) GetVacationRequestsFromUserId(requestedFromId string) ([]types.VacationRequest, error) {
-	return nil, nil
+	rows, err := s.db.Query("SELECT "+requestColumns+" FROM vacation_requests WHERE requestedFrom = ? ORDER BY fromDate", requestedFromId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]types.VacationRequest, 0)
+	for rows.Next() {
+		request, err := scanRequestRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+
+	return requests, nil
 }
 
-func (s *Store) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus) error {
-	_, err := utils.Exec(execable, "UPDATE vacation_approvals SET status = ?, changedAt = UTC_TIMESTAMP WHERE request_Id = ? and approver_id = ?",
-		status, requestId, approverId)
+func scanRequestRow(rows *sql.Rows) (*types.VacationRequest, error) {
+	request := new(types.VacationRequest)
+	var changedAt sql.NullTime
+	err := rows.Scan(
+		&request.Id,
+		&request.RequestedFrom,
+		&request.ToUserId,
+		&request.TeamId,
+		&request.Info,
+		&request.Type,
+		&request.Status,
+		&request.FromDate,
+		&request.ToDate,
+		&changedAt,
+		&request.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.ChangedAt = changedAt.Time
+	return request, nil
+}
+
+func (s *Store) UpdateVacationStatus(execable interface{}, requestId string, approverId string, status types.ApprovalStatus, reason string) error {
+	_, err := utils.Exec(execable, "UPDATE vacation_approvals SET status = ?, reason = NULLIF(?, ''), changedAt = UTC_TIMESTAMP WHERE request_Id = ? and approver_id = ?",
+		status, reason, requestId, approverId)
 
 	if err != nil {
 		return err
@@ -44,7 +89,31 @@ func (s *Store) UpdateVacationStatus(execable interface{}, requestId string, app
 }
 
 func (s *Store) GetApprovalsForRequest(requestId string) ([]types.VacationApproval, error) {
-	return nil, nil
+	return queryApprovals(s.db, "SELECT request_id, approver_id, status, reason, changedAt FROM vacation_approvals WHERE request_id = ?", requestId)
+}
+
+// LockApprovalsForRequest returns the approvals and locks them until the transaction
+// ends, so concurrent decisions resolve the request one after the other
+func (s *Store) LockApprovalsForRequest(execable interface{}, requestId string) ([]types.VacationApproval, error) {
+	return queryApprovals(execable, "SELECT request_id, approver_id, status, reason, changedAt FROM vacation_approvals WHERE request_id = ? FOR UPDATE", requestId)
+}
+
+// DecideRequest sets the final status of a request which wasn't decided yet. It
+// reports false if the request was decided or cancelled meanwhile.
+func (s *Store) DecideRequest(execable interface{}, requestId string, status types.RequestStatus) (bool, error) {
+	args := append([]any{status, requestId}, undecidedStatuses...)
+	result, err := utils.Exec(execable, `UPDATE vacation_requests SET requestStatus = ?, changedAt = UTC_TIMESTAMP
+							WHERE id = ? AND requestStatus IN (?, ?, ?)`, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
 }
 
 func (s *Store) CreateApprovalEntry(execable interface{}, requestId string, approverId string) error {
@@ -56,3 +125,163 @@ func (s *Store) CreateApprovalEntry(execable interface{}, requestId string, appr
 	}
 	return nil
 }
+
+// the states in which a request still waits for a decision
+var undecidedStatuses = []any{types.REQUEST_OPEN, types.REQUEST_SUBSTITUTED_MEMBER, types.REQUEST_SUBSTITUTED_TEAMLEAD}
+
+func (s *Store) GetTeamsWithOpenApprovals(approverId string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT vr.teamId FROM vacation_approvals va
+							inner join vacation_requests vr on vr.id = va.request_id
+							where va.approver_id = ? and va.status = ?`, approverId, types.APPROVAL_OPEN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	teamIds := make([]string, 0)
+	for rows.Next() {
+		var teamId string
+		if err := rows.Scan(&teamId); err != nil {
+			return nil, err
+		}
+		teamIds = append(teamIds, teamId)
+	}
+
+	return teamIds, nil
+}
+
+// ReassignOpenApprovals hands the open approvals of requests of the team over to another approver.
+// Approvals which the new approver already has are dropped instead of duplicated.
+func (s *Store) ReassignOpenApprovals(execable interface{}, teamId, fromApproverId, toApproverId string) error {
+	_, err := utils.Exec(execable, `UPDATE IGNORE vacation_approvals va
+							inner join vacation_requests vr on vr.id = va.request_id
+							SET va.approver_id = ?, va.changedAt = UTC_TIMESTAMP
+							where va.approver_id = ? and va.status = ? and vr.teamId = ?`,
+		toApproverId, fromApproverId, types.APPROVAL_OPEN, teamId)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.Exec(execable, `DELETE va FROM vacation_approvals va
+							inner join vacation_requests vr on vr.id = va.request_id
+							where va.approver_id = ? and va.status = ? and vr.teamId = ?`,
+		fromApproverId, types.APPROVAL_OPEN, teamId)
+	if err != nil {
+		return err
+	}
+
+	args := append([]any{toApproverId, fromApproverId, teamId}, undecidedStatuses...)
+	_, err = utils.Exec(execable, "UPDATE vacation_requests SET toUserId = ? WHERE toUserId = ? AND teamId = ? AND requestStatus IN (?, ?, ?)", args...)
+	return err
+}
+
+// CancelFutureRequestsOfUser cancels the requests which start after the given time
+// and aren't declined yet. Requests which already started stay in the history.
+func (s *Store) CancelFutureRequestsOfUser(execable interface{}, userId string, from time.Time) error {
+	args := append([]any{types.REQUEST_CANCELLED, userId, from}, undecidedStatuses...)
+	args = append(args, types.REQUEST_APPROVED)
+	_, err := utils.Exec(execable, `UPDATE vacation_requests SET requestStatus = ?, changedAt = UTC_TIMESTAMP
+							WHERE requestedFrom = ? AND fromDate > ? AND requestStatus IN (?, ?, ?, ?)`, args...)
+	return err
+}
+
+// CancelUndecidedRequestsInTeam cancels the requests of the user in the team which
+// aren't decided yet. Approved requests stay, the vacation was granted already.
+func (s *Store) CancelUndecidedRequestsInTeam(execable interface{}, userId, teamId string) error {
+	args := append([]any{types.REQUEST_CANCELLED, userId, teamId}, undecidedStatuses...)
+	_, err := utils.Exec(execable, `UPDATE vacation_requests SET requestStatus = ?, changedAt = UTC_TIMESTAMP
+							WHERE requestedFrom = ? AND teamId = ? AND requestStatus IN (?, ?, ?)`, args...)
+	return err
+}
+
+func (s *Store) GetApprovalsOfApprover(approverId string) ([]types.VacationApproval, error) {
+	return queryApprovals(s.db, "SELECT request_id, approver_id, status, reason, changedAt FROM vacation_approvals WHERE approver_id = ?", approverId)
+}
+
+func queryApprovals(execable interface{}, query string, args ...any) ([]types.VacationApproval, error) {
+	rows, err := utils.Query(execable, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	approvals := make([]types.VacationApproval, 0)
+	for rows.Next() {
+		approval := types.VacationApproval{}
+		var reason sql.NullString
+		var changedAt sql.NullTime
+		if err := rows.Scan(&approval.RequestId, &approval.ApproverId, &approval.Status, &reason, &changedAt); err != nil {
+			return nil, err
+		}
+		approval.Reason = reason.String
+		approval.ChangedAt = changedAt.Time
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, nil
+}
+
+// ClearRequestInfosOfUser removes the free texts of the requests, the dates stay for the accounting
+func (s *Store) ClearRequestInfosOfUser(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, "UPDATE vacation_requests SET info = '' WHERE requestedFrom = ?", userId)
+	return err
+}
+
+// ClearApprovalReasonsOfUser removes the reasons given on the requests of the user
+// and the ones the user gave as approver
+func (s *Store) ClearApprovalReasonsOfUser(execable interface{}, userId string) error {
+	_, err := utils.Exec(execable, `UPDATE vacation_approvals SET reason = NULL
+		WHERE approver_id = ? OR request_id IN (SELECT id FROM vacation_requests WHERE requestedFrom = ?)`, userId, userId)
+	return err
+}
+
+func (s *Store) GetVacationRequestById(id string) (*types.VacationRequest, error) {
+	rows, err := s.db.Query("SELECT "+requestColumns+" FROM vacation_requests WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, fmt.Errorf("request not found")
+	}
+
+	return scanRequestRow(rows)
+}
+
+// GetAbsencesOfTeams returns the approved and undecided requests in the teams which
+// overlap the period
+func (s *Store) GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]types.Absence, error) {
+	absences := make([]types.Absence, 0)
+	if len(teamIds) == 0 {
+		return absences, nil
+	}
+
+	args := []any{from, to, types.REQUEST_APPROVED}
+	args = append(args, undecidedStatuses...)
+	for _, teamId := range teamIds {
+		args = append(args, teamId)
+	}
+
+	teams := strings.TrimSuffix(strings.Repeat("?, ", len(teamIds)), ", ")
+	rows, err := s.db.Query(`SELECT vr.id, vr.requestedFrom, u.name, vr.teamId, vr.absenceType, vr.requestStatus, vr.fromDate, vr.toDate
+							FROM vacation_requests vr
+							inner join users u on u.id = vr.requestedFrom
+							WHERE vr.toDate >= ? AND vr.fromDate <= ? AND vr.requestStatus IN (?, ?, ?, ?)
+							AND vr.teamId IN (`+teams+`) ORDER BY vr.fromDate`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var absence types.Absence
+		var status types.RequestStatus
+		err := rows.Scan(&absence.RequestId, &absence.UserId, &absence.UserName, &absence.TeamId, &absence.Type, &status, &absence.FromDate, &absence.ToDate)
+		if err != nil {
+			return nil, err
+		}
+		absence.Status = &status
+		absences = append(absences, absence)
+	}
+
+	return absences, nil
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// SystemRole is the role of a user in the whole instance, independent of the teams
+type SystemRole string
+
+const (
+	SystemRoleUser       SystemRole = "user"
+	SystemRoleSuperadmin SystemRole = "superadmin"
+)
+
+// Actions which are written to the audit log
+const (
+	AuditUserUpdated       = "user_updated"
+	AuditUserImpersonated  = "user_impersonated"
+	AuditTeamCreated       = "team_created"
+	AuditTeamRenamed       = "team_renamed"
+	AuditTeamMemberAdded   = "team_member_added"
+	AuditTeamMemberRemoved = "team_member_removed"
+	AuditSettingsChanged   = "settings_changed"
+)
+
+type AuditEntry struct {
+	Id        string    `json:"id"`
+	ActorId   string    `json:"actorId"`
+	Action    string    `json:"action"`
+	TargetId  *string   `json:"targetId"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// InstanceSettings are changed by superadmins and apply to all users
+type InstanceSettings struct {
+	RegistrationEnabled bool `json:"registrationEnabled"`
+	// registrations are limited to these domains, everybody can register if it is empty
+	AllowedEmailDomains []string `json:"allowedEmailDomains" validate:"dive,fqdn"`
+}
+
+func DefaultInstanceSettings() *InstanceSettings {
+	return &InstanceSettings{RegistrationEnabled: true, AllowedEmailDomains: []string{}}
+}
+
+// AllowsRegistration reports whether somebody with the email address can register
+func (s *InstanceSettings) AllowsRegistration(email string) bool {
+	if !s.RegistrationEnabled {
+		return false
+	}
+
+	if len(s.AllowedEmailDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain := strings.ToLower(email[at+1:])
+	return slices.ContainsFunc(s.AllowedEmailDomains, func(allowed string) bool { return strings.EqualFold(allowed, domain) })
+}
+
+type AdminUpdateUserPayload struct {
+	Name       *string     `json:"name" validate:"omitempty,min=1,max=255"`
+	Email      *string     `json:"email" validate:"omitempty,email"`
+	SystemRole *SystemRole `json:"systemRole" validate:"omitempty,oneof=user superadmin"`
+}
+
+type AdminCreateTeamPayload struct {
+	Name            string `json:"name" validate:"required,max=255"`
+	AdministratorId string `json:"administratorId" validate:"required,uuid4"`
+}
+
+type AdminTeamMemberPayload struct {
+	RoleType *UserRole `json:"userRole" validate:"required,min=0,max=1"`
+}
@@ -15,17 +15,41 @@ const (
 	INVITE_OPEN InviteStatus = iota
 	INVITE_ACCEPTED
 	INVITE_DECLINED
+	INVITE_CANCELLED
+	INVITE_EXPIRED
+	INVITE_REVOKED
 )
 
+// DefaultInviteExpiryDays is used by teams which didn't choose another expiry
+const DefaultInviteExpiryDays = 14
+
 type Invite struct {
 	Id         string       `json:"id"`
 	InviteType InviteType   `json:"inviteType"`
 	FromUserId string       `json:"fromUserId"`
-	ToUserId   string       `json:"toUserId"`
+	ToUserId   string       `json:"toUserId"` // empty until people invited by email register
+	Email      *string      `json:"email,omitempty"`
 	TeamId     string       `json:"teamId"`
 	Status     InviteStatus `json:"status"`
 	CreatedAt  time.Time    `json:"createdAt"`
 	ChangedAt  *time.Time   `json:"changedAt"`
+	ExpiresAt  *time.Time   `json:"expiresAt"`
+}
+
+// Invitee is the user id or, for people without account, the email address
+// which at most one open invite to a team can exist for
+func (i *Invite) Invitee() string {
+	if i.ToUserId == "" && i.Email != nil {
+		return *i.Email
+	}
+
+	return i.ToUserId
+}
+
+// IsOpen reports whether the invite can still be answered. Invites are marked
+// expired lazily, so an open invite past its expiry isn't open anymore.
+func (i *Invite) IsOpen(now time.Time) bool {
+	return i.Status == INVITE_OPEN && (i.ExpiresAt == nil || now.Before(*i.ExpiresAt))
 }
 
 type InviteInfo struct {
@@ -35,12 +59,21 @@ type InviteInfo struct {
 	TeamName     string       `json:"teamName"`
 	Status       InviteStatus `json:"status"`
 	CreatedAt    time.Time    `json:"createdAt"`
+	ExpiresAt    *time.Time   `json:"expiresAt"`
 }
 
 type CreateInvitePayload struct {
 	InviteType InviteType   `json:"inviteType"`
-	FromUserId string       `json:"fromUserId" validate:"required"`
-	ToUserId   string       `json:"toUserId" validate:"required"`
+	ToUserId   string       `json:"toUserId" validate:"required_without=Email,excluded_with=Email"` // or the email of somebody without account
+	Email      string       `json:"email" validate:"omitempty,email,max=255"`
 	TeamId     string       `json:"teamId" validate:"required"`
 	Status     InviteStatus `json:"status"`
 }
+
+// RegisterWithInvitePayload creates the account of somebody who was invited by
+// email, the address is taken from the invite
+type RegisterWithInvitePayload struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Password string `json:"password" validate:"required,max=100"`
+}
@@ -0,0 +1,5 @@
+package types
+
+type Mailer interface {
+	Send(to, subject, body string) error
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// PageRequest describes one page of a listing. Listings are paginated by keyset,
+// the cursor points behind the last entry of the previous page, so entries don't
+// shift between pages when other entries are added or removed meanwhile.
+type PageRequest struct {
+	Limit      int
+	Sort       string
+	Column     string
+	Descending bool
+	After      *Cursor
+}
+
+// Cursor is the position of an entry in a listing, the id breaks ties between
+// entries with the same sort value
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v"`
+	Id    string `json:"id"`
+}
+
+// Page is the answer of a paginated listing, NextCursor is empty on the last page
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil || cursor.Id == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+// Keyset returns the condition which skips every entry up to the cursor, it is
+// empty on the first page
+func (p PageRequest) Keyset(idColumn string) (string, []any) {
+	if p.After == nil {
+		return "", nil
+	}
+
+	operator := ">"
+	if p.Descending {
+		operator = "<"
+	}
+
+	clause := fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", p.Column, idColumn, operator)
+	return clause, []any{p.After.Value, p.After.Value, p.After.Id}
+}
+
+// OrderBy returns the order matching Keyset
+func (p PageRequest) OrderBy(idColumn string) string {
+	direction := "ASC"
+	if p.Descending {
+		direction = "DESC"
+	}
+
+	return fmt.Sprintf("%s %s, %s %s", p.Column, direction, idColumn, direction)
+}
+
+// QueryLimit is one more than the limit, the additional entry tells whether
+// there is a next page
+func (p PageRequest) QueryLimit() int {
+	return p.Limit + 1
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+// Events a user can be notified about
+const (
+	NotificationRequestCreated string = "request_created"
+	NotificationRequestDecided string = "request_decided"
+)
+
+// Channels notifications can be sent on
+const (
+	ChannelEmail string = "email"
+)
+
+type UserPreferences struct {
+	UserId         string       `json:"-"`
+	Timezone       string       `json:"timezone" validate:"required,timezone"`
+	Locale         string       `json:"locale" validate:"required,bcp47_language_tag"`
+	FirstDayOfWeek time.Weekday `json:"firstDayOfWeek" validate:"min=0,max=6"`
+	// the region whose public holidays apply to the user, e.g. DE-BY
+	HolidayRegion string `json:"holidayRegion" validate:"omitempty,max=16"`
+	// the channels per event, an event without channels isn't sent
+	Notifications map[string][]string `json:"notifications" validate:"required,dive,keys,oneof=request_created request_decided,endkeys,dive,oneof=email"`
+}
+
+// DefaultPreferences are used for users who didn't save their preferences yet
+func DefaultPreferences(userId string) *UserPreferences {
+	return &UserPreferences{
+		UserId:         userId,
+		Timezone:       "UTC",
+		Locale:         "en",
+		FirstDayOfWeek: time.Monday,
+		Notifications: map[string][]string{
+			NotificationRequestCreated: {ChannelEmail},
+			NotificationRequestDecided: {ChannelEmail},
+		},
+	}
+}
+
+// Location returns the timezone of the user, unknown zones fall back to UTC
+func (p *UserPreferences) Location() *time.Location {
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+func (p *UserPreferences) Wants(event, channel string) bool {
+	return slices.Contains(p.Notifications[event], channel)
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"io"
+	"time"
+)
+
+// Profile is the own user as returned by /me
+type Profile struct {
+	Id              string          `json:"id"`
+	Name            string          `json:"name"`
+	Email           string          `json:"email"`
+	EmailVerifiedAt *time.Time      `json:"emailVerifiedAt"`
+	AvatarUrl       *string         `json:"avatarUrl"`
+	TotpEnabled     bool            `json:"totpEnabled"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	Teams           []UserTeam      `json:"teams"`
+	Balance         VacationBalance `json:"balance"`
+}
+
+// VacationBalance sums up the vacation days of the current year
+type VacationBalance struct {
+	Year        int `json:"year"`
+	TakenDays   int `json:"takenDays"`
+	PlannedDays int `json:"plannedDays"`
+	PendingDays int `json:"pendingDays"`
+}
+
+type UpdateProfilePayload struct {
+	Name  *string `json:"name" validate:"omitempty,min=1,max=255"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
+// FileStorage stores uploaded files, e.g. on the local disk or in an object storage
+type FileStorage interface {
+	Save(key string, content io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// Session is a login of a user. The access tokens of a login carry the id of the
+// session, so they stop working once the session is revoked.
+type Session struct {
+	Id         string     `json:"id"`
+	UserId     string     `json:"-"`
+	Device     string     `json:"device"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"`
+}
@@ -1,36 +1,124 @@
 package types
 
+import "time"
+
 type UserStore interface {
 	GetUserByEmail(email string) (*User, error)
 	GetUserById(id string) (*User, error)
-	CreateUser(User) error
+	CreateUser(execable interface{}, user User) error
+	ChangePassword(execable interface{}, userId, hashedPassword string) error
+	UpdatePasswordHash(userId, hashedPassword string) error
+	VerifyEmail(userId string) error
+	RecordFailedLogin(userId string, failedLogins int, lockedUntil *time.Time) error
+	ResetFailedLogins(userId string) error
+	SetTotpSecret(userId string, secret *string) error
+	EnableTotp(execable interface{}, userId string) error
+	DisableTotp(execable interface{}, userId string) error
+	ReplaceRecoveryCodes(execable interface{}, userId string, codeHashes []string) error
+	UseRecoveryCode(userId, codeHash string) (bool, error)
+	GetUserByOidcSubject(subject string) (*User, error)
+	SetOidcSubject(execable interface{}, userId, subject string) error
+	CreateAccessToken(token PersonalAccessToken) error
+	GetAccessTokensOfUser(userId string) ([]PersonalAccessToken, error)
+	GetAccessTokenByHash(hash string) (*PersonalAccessToken, error)
+	TouchAccessToken(id string) error
+	DeleteAccessToken(userId, id string) error
 	GetUsersFromTeam(teamId string) ([]TeamUser, error)
+	UpdateName(execable interface{}, userId, name string) error
+	UpdateEmail(execable interface{}, userId, email string) error
+	SetAvatarKey(userId string, key *string) error
+	DeactivateUser(execable interface{}, userId string) error
+	DeleteUser(execable interface{}, userId string) error
+	DeleteAccessTokensOfUser(execable interface{}, userId string) error
+	PseudonymizeUser(execable interface{}, userId, name, email string) error
+	SearchUsers(filter UserSearchFilter) ([]PublicUser, error)
+	CreateSession(execable interface{}, session Session) error
+	GetSession(id string) (*Session, error)
+	GetSessionsOfUser(userId string, includeRevoked bool) ([]Session, error)
+	TouchSession(id string) error
+	RevokeSession(userId, id string) error
+	RevokeSessionsOfUser(execable interface{}, userId string) error
+	DeleteSessionsOfUser(execable interface{}, userId string) error
+	SetSystemRole(execable interface{}, userId string, role SystemRole) error
+	ListUsers(query string, limit, offset int) ([]User, error)
 }
 
 type TeamStore interface {
-	GetAllTeams() ([]Team, error)
-	CreateTeam(Team) error
+	GetAllTeams(includeArchived bool) ([]Team, error)
+	ListTeams(filter TeamFilter, page PageRequest) (*Page[TeamListEntry], error)
+	CreateTeam(execable interface{}, team Team) error
 	RenameTeam(name, teamId string) error
 	GetTeamById(id string) (*Team, error)
 	GetTeamByName(name string) (*Team, error)
 	AddUserToTeam(execable interface{}, userId, teamId string, role UserRole) error
-	RemoveUserFromTeam(userId, teamId string) error
+	RemoveUserFromTeam(execable interface{}, userId, teamId string) error
+	GetUserRoleInTeam(userId, teamId string) (UserRole, error)
+	GetTeamsOfUser(userId string) ([]UserTeam, error)
+	GetTeamAdministrators(teamId string) ([]TeamUser, error)
+	RemoveUserFromAllTeams(execable interface{}, userId string) error
+	SetMemberRole(execable interface{}, userId, teamId string, role UserRole) error
+	LockTeamAdministrators(execable interface{}, teamId string) ([]string, error)
+	RecordTeamHistory(execable interface{}, entry TeamHistoryEntry) error
+	GetTeamHistory(teamId string) ([]TeamHistoryEntry, error)
+	GetFormerTeamIdsOfUser(userId string) ([]string, error)
+	GetTeamSettings(teamId string) (*TeamSettings, error)
+	SaveTeamSettings(execable interface{}, teamId string, settings TeamSettings, changedBy string) error
+	GetTeamSettingsVersions(teamId string) ([]TeamSettingsVersion, error)
+	SetTeamArchived(execable interface{}, teamId string, archived bool) error
+	CancelOpenInvitesOfTeam(execable interface{}, teamId string) error
+	GetTeamDeletionPreview(teamId string) (*TeamDeletionPreview, error)
+	DeleteTeam(execable interface{}, teamId string) error
+	GetAncestorIds(teamId string) ([]string, error)
+	GetDescendantIds(teamId string) ([]string, error)
+	MoveTeam(execable interface{}, teamId string, parentId *string) error
+}
+
+type AuditStore interface {
+	RecordAudit(execable interface{}, entry AuditEntry) error
+	GetAuditLog(limit, offset int) ([]AuditEntry, error)
+}
+
+type SettingsStore interface {
+	GetInstanceSettings() (*InstanceSettings, error)
+	SaveInstanceSettings(execable interface{}, settings InstanceSettings) error
+}
+
+type PreferenceStore interface {
+	GetPreferences(userId string) (*UserPreferences, error)
+	SavePreferences(preferences UserPreferences) error
+	DeletePreferences(execable interface{}, userId string) error
 }
 
 type InviteStore interface {
-	CreateInvite(invite Invite) error
+	CreateInvite(execable interface{}, invite Invite) error
+	HasOpenInvite(execable interface{}, invitee, teamId string) (bool, error)
+	ExpireOverdueInvites(execable interface{}, invitee, teamId string) error
+	AcceptInvite(execable interface{}, id, userId string) (bool, error)
+	RenewInvite(execable interface{}, id string, expiresAt time.Time) error
 	DeleteInvite(execable interface{}, id string) error
 	GetInvite(id string) (*Invite, error)
 	GetInviteInfosFrom(from string) ([]InviteInfo, error)
 	GetInviteInfosTo(to string) ([]InviteInfo, error)
-	UpdateInviteStatus(execable interface{}, id string, status InviteStatus) error
+	CloseInvite(execable interface{}, id string, status InviteStatus) (bool, error)
+	DeleteInvitesToEmail(execable interface{}, email string) error
 }
 
 type VacationStore interface {
 	CreateVacationRequest(execable interface{}, request VacationRequest) error
 	GetVacationRequestsForUser(toUserId string) ([]VacationRequest, error)
 	GetVacationRequestsFromUserId(requestedFromId string) ([]VacationRequest, error)
-	UpdateVacationStatus(execable interface{}, requestId string, approverId string, status ApprovalStatus) error
+	UpdateVacationStatus(execable interface{}, requestId string, approverId string, status ApprovalStatus, reason string) error
 	GetApprovalsForRequest(requestId string) ([]VacationApproval, error)
+	LockApprovalsForRequest(execable interface{}, requestId string) ([]VacationApproval, error)
+	DecideRequest(execable interface{}, requestId string, status RequestStatus) (bool, error)
 	CreateApprovalEntry(execable interface{}, requestId string, approverId string) error
+	GetTeamsWithOpenApprovals(approverId string) ([]string, error)
+	ReassignOpenApprovals(execable interface{}, teamId, fromApproverId, toApproverId string) error
+	CancelFutureRequestsOfUser(execable interface{}, userId string, from time.Time) error
+	CancelUndecidedRequestsInTeam(execable interface{}, userId, teamId string) error
+	GetAbsencesOfTeams(teamIds []string, from, to time.Time) ([]Absence, error)
+	GetApprovalsOfApprover(approverId string) ([]VacationApproval, error)
+	ClearRequestInfosOfUser(execable interface{}, userId string) error
+	ClearApprovalReasonsOfUser(execable interface{}, userId string) error
+	GetVacationRequestById(id string) (*VacationRequest, error)
 }
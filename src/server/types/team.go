@@ -3,15 +3,176 @@ package types
 import "time"
 
 type Team struct {
-	Id        string    `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"createdAt"`
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ArchivedAt *time.Time `json:"archivedAt"`
+	ParentId   *string    `json:"parentId"`
+	UnitType   UnitType   `json:"unitType"`
+}
+
+// UnitType is the level of a team in the hierarchy of the organization
+type UnitType string
+
+const (
+	UnitCompany    UnitType = "company"
+	UnitDepartment UnitType = "department"
+	UnitTeam       UnitType = "team"
+)
+
+var unitRanks = map[UnitType]int{UnitCompany: 0, UnitDepartment: 1, UnitTeam: 2}
+
+// CanContain reports whether a unit of the child type can be placed below this unit
+func (u UnitType) CanContain(child UnitType) bool {
+	return unitRanks[u] < unitRanks[child]
+}
+
+// IsArchived reports whether the team is read-only
+func (t *Team) IsArchived() bool {
+	return t.ArchivedAt != nil
+}
+
+// TeamListEntry is a team in the team listing
+type TeamListEntry struct {
+	Team
+	MemberCount int `json:"memberCount"`
+}
+
+// TeamFilter narrows the team listing, empty fields don't filter
+type TeamFilter struct {
+	Name string
+	// only teams the user is a member of
+	MemberId string
+	// only teams the user administers directly
+	AdministratorId string
+	IncludeArchived bool
+}
+
+// TeamDeletionPreview lists everything which is removed together with the team
+type TeamDeletionPreview struct {
+	Members          int      `json:"members"`
+	Units            int      `json:"units"`
+	Invites          int      `json:"invites"`
+	VacationRequests int      `json:"vacationRequests"`
+	HistoryEntries   int      `json:"historyEntries"`
+	HasSettings      bool     `json:"hasSettings"`
+	SettingsVersions int      `json:"settingsVersions"`
+	Deletable        bool     `json:"deletable"`
+	Blockers         []string `json:"blockers"`
 }
 
 type AddTeamPayload struct {
 	Name string `json:"name" validate:"required"`
+	// the creator becomes administrator and must not be listed, members the creator
+	// doesn't administer yet are invited
+	Members  []InitialMember `json:"members" validate:"omitempty,max=100,dive"`
+	Settings *TeamSettings   `json:"settings"`
+	ParentId *string         `json:"parentId" validate:"omitempty,uuid4"`
+	UnitType UnitType        `json:"unitType" validate:"omitempty,oneof=company department team"`
+}
+
+type InitialMember struct {
+	UserId string `json:"userId" validate:"required,uuid4"`
+	// a pointer, the zero value of the role is Administrator
+	RoleType *UserRole `json:"userRole" validate:"required,min=0,max=1"`
+}
+
+// The steps of an approval chain, every step adds approvers to a new request
+const (
+	ApprovalStepApprover           = "approver"
+	ApprovalStepTeamAdministrators = "team_administrators"
+	ApprovalStepParentUnit         = "parent_unit"
+)
+
+// Whether members see the leave of each other or only that somebody is absent
+const (
+	LeaveVisibilityDetails = "details"
+	LeaveVisibilityAbsent  = "absent"
+)
+
+// TeamSettings is stored as a document, so new settings don't need a migration
+type TeamSettings struct {
+	ApprovalChain         []string `json:"approvalChain" validate:"omitempty,max=3,unique,dive,oneof=approver team_administrators parent_unit"`
+	DeclineRequiresReason bool     `json:"declineRequiresReason"`
+	// 0 allows requests which start today
+	MinimumNoticeDays int `json:"minimumNoticeDays" validate:"min=0,max=365"`
+	// 0 doesn't limit the length of a request
+	MaximumConsecutiveDays int    `json:"maximumConsecutiveDays" validate:"min=0,max=366"`
+	HolidayRegion          string `json:"holidayRegion" validate:"omitempty,max=16"`
+	LeaveVisibility        string `json:"leaveVisibility" validate:"omitempty,oneof=details absent"`
+	// how long invites to the team can be accepted
+	InviteExpiryDays int `json:"inviteExpiryDays" validate:"omitempty,min=1,max=365"`
+}
+
+func DefaultTeamSettings() *TeamSettings {
+	return &TeamSettings{
+		ApprovalChain:    []string{ApprovalStepApprover},
+		LeaveVisibility:  LeaveVisibilityDetails,
+		InviteExpiryDays: DefaultInviteExpiryDays,
+	}
+}
+
+// Normalize fills the settings which were left out with the defaults
+func (s *TeamSettings) Normalize() {
+	defaults := DefaultTeamSettings()
+	if len(s.ApprovalChain) == 0 {
+		s.ApprovalChain = defaults.ApprovalChain
+	}
+
+	if s.LeaveVisibility == "" {
+		s.LeaveVisibility = defaults.LeaveVisibility
+	}
+
+	if s.InviteExpiryDays == 0 {
+		s.InviteExpiryDays = defaults.InviteExpiryDays
+	}
+}
+
+type TeamSettingsVersion struct {
+	Version   int          `json:"version"`
+	Settings  TeamSettings `json:"settings"`
+	ChangedBy *string      `json:"changedBy"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// MoveTeamPayload places the team below another unit, without parent the team becomes a root
+type MoveTeamPayload struct {
+	ParentId *string `json:"parentId" validate:"omitempty,uuid4"`
 }
 
 type RenameTeamPayload struct {
 	Name string `json:"name" validate:"required"`
 }
+
+// Actions which are written to the history of a team
+const (
+	TeamHistoryCreated              = "created"
+	TeamHistoryRoleChanged          = "role_changed"
+	TeamHistoryOwnershipTransferred = "ownership_transferred"
+	TeamHistoryMemberLeft           = "member_left"
+	TeamHistoryMemberRemoved        = "member_removed"
+	TeamHistoryMoved                = "moved"
+	TeamHistorySettingsChanged      = "settings_changed"
+	TeamHistoryArchived             = "archived"
+	TeamHistoryUnarchived           = "unarchived"
+)
+
+type TeamHistoryEntry struct {
+	Id        string    `json:"id"`
+	TeamId    string    `json:"teamId"`
+	ActorId   string    `json:"actorId"`
+	Action    string    `json:"action"`
+	UserId    *string   `json:"userId"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
+	// backfill marks entries which were derived from older data by a migration
+	Source *string `json:"source,omitempty"`
+}
+
+type UpdateMemberRolePayload struct {
+	RoleType *UserRole `json:"userRole" validate:"required,min=0,max=1"`
+}
+
+type TransferOwnershipPayload struct {
+	UserId string `json:"userId" validate:"required,uuid4"`
+}
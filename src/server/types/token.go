@@ -0,0 +1,29 @@
+package types
+
+import "time"
+
+// Scopes of personal access tokens
+const (
+	ScopeReadVacations  = "read:vacations"
+	ScopeWriteVacations = "write:vacations"
+	ScopeAdminTeams     = "admin:teams"
+	ScopeAdminInstance  = "admin:instance"
+)
+
+type PersonalAccessToken struct {
+	Id         string     `json:"id"`
+	UserId     string     `json:"userId"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateAccessTokenPayload struct {
+	Name   string   `json:"name" validate:"required,max=255"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=read:vacations write:vacations admin:teams admin:instance"`
+	// tokens without expiry are valid until they are deleted
+	ExpiresInDays int `json:"expiresInDays" validate:"min=0,max=365"`
+}
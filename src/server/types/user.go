@@ -3,20 +3,96 @@ package types
 import "time"
 
 type User struct {
-	Id        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"createdAt"`
+	Id                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Email              string     `json:"email"`
+	Password           string     `json:"-"`
+	MustChangePassword bool       `json:"-"`
+	EmailVerifiedAt    *time.Time `json:"emailVerifiedAt"`
+	FailedLogins       int        `json:"-"`
+	LockedUntil        *time.Time `json:"-"`
+	TotpSecret         *string    `json:"-"`
+	TotpEnabled        bool       `json:"totpEnabled"`
+	OidcSubject        *string    `json:"-"`
+	DeactivatedAt      *time.Time `json:"deactivatedAt"`
+	AvatarKey          *string    `json:"-"`
+	SystemRole         SystemRole `json:"systemRole"`
+	CreatedAt          time.Time  `json:"createdAt"`
+}
+
+// PublicUser is the data of a user which can be shown to other users. Handlers
+// return read models like this one instead of User, which holds credentials.
+type PublicUser struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func NewPublicUser(u *User) PublicUser {
+	return PublicUser{Id: u.Id, Name: DisplayName(u.Name, u.DeactivatedAt), Email: u.Email}
+}
+
+// DisplayName marks deactivated users, they are still shown in the history of teams and requests
+func DisplayName(name string, deactivatedAt *time.Time) string {
+	if deactivatedAt == nil {
+		return name
+	}
+
+	return name + " (former employee)"
+}
+
+// UserSearchFilter limits the users of the directory. Only users who share a team
+// with VisibleTo or are members of AdministeredTeamIds are returned, an empty
+// VisibleTo returns all users.
+type UserSearchFilter struct {
+	Query               string
+	TeamId              string
+	VisibleTo           string
+	AdministeredTeamIds []string
+	Limit               int
+	Offset              int
 }
 
 type RegisterUserPayload struct {
 	Name     string `json:"name" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=3,max=100"`
+	Password string `json:"password" validate:"required,max=100"`
 }
 
 type LoginUserPayload struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
+
+// CreateUserPayload is used by team administrators to create an account for
+// somebody else. The user is added to the given team as a member.
+type CreateUserPayload struct {
+	Name   string `json:"name" validate:"required"`
+	Email  string `json:"email" validate:"required,email"`
+	TeamId string `json:"teamId" validate:"required,uuid4"`
+}
+
+type ChangePasswordPayload struct {
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,max=100"`
+}
+
+type ResendVerificationPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type EnableTwoFactorPayload struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+type DisableTwoFactorPayload struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// TwoFactorLoginPayload completes a login with either a totp code or a recovery code
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recoveryCode" validate:"required_without=Code"`
+}
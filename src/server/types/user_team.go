@@ -22,3 +22,29 @@ type TeamUser struct {
 	AddedAt  time.Time `json:"addedAt"`
 	RoleType UserRole  `json:"userRole"`
 }
+
+// Presence tells whether a member is at work today. Members who may only see that
+// somebody is absent get PresenceAbsent instead of the reason.
+type Presence string
+
+const (
+	PresencePresent  Presence = "present"
+	PresenceVacation Presence = "vacation"
+	PresenceSick     Presence = "sick"
+	PresenceAbsent   Presence = "absent"
+)
+
+// TeamMember is a member in the member listing of a team
+type TeamMember struct {
+	TeamUser
+	Presence    Presence `json:"presence"`
+	NextAbsence *Absence `json:"nextAbsence"`
+}
+
+// UserTeam is a membership seen from the user
+type UserTeam struct {
+	TeamId   string    `json:"teamId"`
+	TeamName string    `json:"teamName"`
+	RoleType UserRole  `json:"userRole"`
+	AddedAt  time.Time `json:"addedAt"`
+}
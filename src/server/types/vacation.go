@@ -10,6 +10,15 @@ const (
 	REQUEST_SUBSTITUTED_TEAMLEAD
 	REQUEST_APPROVED
 	REQUEST_DECLINED
+	REQUEST_CANCELLED
+)
+
+// AbsenceType tells why a member is away, sick leave is reported like a vacation
+type AbsenceType string
+
+const (
+	AbsenceVacation AbsenceType = "vacation"
+	AbsenceSick     AbsenceType = "sick"
 )
 
 // the internal data to handle logic
@@ -19,6 +28,7 @@ type VacationRequest struct {
 	ToUserId      string        `json:"toUserId"`
 	TeamId        string        `json:"teamId"`
 	Info          string        `json:"info"`
+	Type          AbsenceType   `json:"type"`
 	Status        RequestStatus `json:"status"`
 	FromDate      time.Time     `json:"fromDate"`
 	ToDate        time.Time     `json:"toDate"`
@@ -40,12 +50,13 @@ type VacationRequestInfo struct {
 }
 
 type CreateVacationRequestPayload struct {
-	RequestedFrom string    `json:"requestedFrom" validate:"required,uuid4"`
-	ToUserId      string    `json:"toUserId" validate:"required,uuid4"`
-	TeamId        string    `json:"teamId" validate:"required,uuid4"`
-	Info          string    `json:"info" validate:"required"`
-	FromDate      time.Time `json:"fromDate" validate:"required"`
-	ToDate        time.Time `json:"toDate" validate:"required"`
+	ToUserId string    `json:"toUserId" validate:"required,uuid4"`
+	TeamId   string    `json:"teamId" validate:"required,uuid4"`
+	Info     string    `json:"info" validate:"required"`
+	FromDate time.Time `json:"fromDate" validate:"required"`
+	ToDate   time.Time `json:"toDate" validate:"required"`
+	// a vacation unless given
+	Type AbsenceType `json:"type" validate:"omitempty,oneof=vacation sick"`
 }
 
 type ApprovalStatus int
@@ -60,11 +71,50 @@ type VacationApproval struct {
 	RequestId  string         `json:"requestId"`
 	ApproverId string         `json:"approverId"`
 	Status     ApprovalStatus `json:"status"`
+	Reason     string         `json:"reason"`
 	ChangedAt  time.Time      `json:"changedAt"`
 }
 
+// ResolveRequestStatus derives the status of a request from its approvals. A single
+// decline declines the request, it is approved once every approver approved it.
+func ResolveRequestStatus(approvals []VacationApproval) RequestStatus {
+	approved := len(approvals) > 0
+	for _, approval := range approvals {
+		switch approval.Status {
+		case APPROVAL_DECLINED:
+			return REQUEST_DECLINED
+		case APPROVAL_OPEN:
+			approved = false
+		}
+	}
+
+	if approved {
+		return REQUEST_APPROVED
+	}
+
+	return REQUEST_OPEN
+}
+
 type VacationApprovalPayload struct {
-	RequestId  string         `json:"requestId" validate:"required"`
-	ApproverId string         `json:"approverId" validate:"required"`
-	Status     ApprovalStatus `json:"status" validate:"required"`
+	RequestId string         `json:"requestId" validate:"required"`
+	Status    ApprovalStatus `json:"status" validate:"required"`
+	Reason    string         `json:"reason" validate:"max=255"`
+}
+
+// Absence is an entry of the calendar, the free text of the request is left out.
+// Teams which only show that somebody is absent leave out the request as well.
+type Absence struct {
+	RequestId string         `json:"requestId,omitempty"`
+	UserId    string         `json:"userId"`
+	UserName  string         `json:"userName"`
+	TeamId    string         `json:"teamId"`
+	Type      AbsenceType    `json:"type,omitempty"`
+	Status    *RequestStatus `json:"status,omitempty"`
+	FromDate  time.Time      `json:"fromDate"`
+	ToDate    time.Time      `json:"toDate"`
+}
+
+type EscalationResult struct {
+	TeamId      string   `json:"teamId"`
+	ApproverIds []string `json:"approverIds"`
 }
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+)
+
+// ParsePageRequest reads limit, sort and cursor from the query. Only the sort keys
+// of columns are accepted and mapped to their column, a leading "-" sorts descending.
+func ParsePageRequest(r *http.Request, columns map[string]string, defaultSort string) (types.PageRequest, error) {
+	query := r.URL.Query()
+	page := types.PageRequest{Limit: types.DefaultPageLimit, Sort: defaultSort}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > types.MaxPageLimit {
+			return page, fmt.Errorf("limit has to be between 1 and %d", types.MaxPageLimit)
+		}
+		page.Limit = limit
+	}
+
+	if value := query.Get("sort"); value != "" {
+		page.Sort = value
+	}
+
+	key := strings.TrimPrefix(page.Sort, "-")
+	column, ok := columns[key]
+	if !ok {
+		return page, fmt.Errorf("can't sort by %s", key)
+	}
+	page.Column = column
+	page.Descending = strings.HasPrefix(page.Sort, "-")
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := types.DecodeCursor(value)
+		if err != nil {
+			return page, err
+		}
+
+		if cursor.Sort != page.Sort {
+			return page, fmt.Errorf("the cursor belongs to another sort order")
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// NewPage cuts the entries down to the limit and points the next cursor at the
+// last entry of the page. cursorOf returns the sort value and id of an entry.
+func NewPage[T any](items []T, page types.PageRequest, cursorOf func(T) (any, string)) types.Page[T] {
+	if len(items) <= page.Limit {
+		return types.Page[T]{Items: items}
+	}
+
+	items = items[:page.Limit]
+	value, id := cursorOf(items[len(items)-1])
+	cursor := types.Cursor{Sort: page.Sort, Value: value, Id: id}
+	return types.Page[T]{Items: items, NextCursor: cursor.Encode()}
+}
+
+// Conditions collects the filters of a listing
+type Conditions struct {
+	clauses []string
+	args    []any
+}
+
+func (c *Conditions) Add(clause string, args ...any) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, args...)
+}
+
+// Where returns the WHERE clause of all conditions, it is empty without conditions
+func (c *Conditions) Where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+func (c *Conditions) Args() []any {
+	return c.args
+}
+
+// EscapeLike makes the wildcards of LIKE match literally
+func EscapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cebuh/simpleHolidayPlaner/types"
+	"github.com/stretchr/testify/require"
+)
+
+var testSortColumns = map[string]string{"name": "name", "members": "memberCount"}
+
+func TestParsePageRequest(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/teams?sort=-members&limit=10", nil)
+	page, err := ParsePageRequest(r, testSortColumns, "name")
+	require.NoError(t, err)
+	require.Equal(t, 10, page.Limit)
+	require.Equal(t, "memberCount", page.Column)
+	require.True(t, page.Descending)
+	require.Equal(t, "memberCount DESC, id DESC", page.OrderBy("id"))
+
+	keyset, _ := page.Keyset("id")
+	require.Empty(t, keyset)
+}
+
+func TestPageCursor_Should_ContinueBehindTheLastEntry(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/teams?limit=2", nil)
+	page, err := ParsePageRequest(r, testSortColumns, "name")
+	require.NoError(t, err)
+
+	first := NewPage([]string{"a", "b", "c"}, page, func(s string) (any, string) { return s, "id-" + s })
+	require.Equal(t, []string{"a", "b"}, first.Items)
+	require.NotEmpty(t, first.NextCursor)
+
+	r, _ = http.NewRequest(http.MethodGet, "/teams?limit=2&cursor="+first.NextCursor, nil)
+	page, err = ParsePageRequest(r, testSortColumns, "name")
+	require.NoError(t, err)
+
+	keyset, args := page.Keyset("id")
+	require.Equal(t, "(name > ? OR (name = ? AND id > ?))", keyset)
+	require.Equal(t, []any{"b", "b", "id-b"}, args)
+
+	last := NewPage([]string{"c"}, page, func(s string) (any, string) { return s, "id-" + s })
+	require.Empty(t, last.NextCursor)
+}
+
+func TestParsePageRequest_Should_Reject_CursorOfAnotherSort(t *testing.T) {
+	cursor := types.Cursor{Sort: "name", Value: "b", Id: "id-b"}
+	r, _ := http.NewRequest(http.MethodGet, "/teams?sort=members&cursor="+cursor.Encode(), nil)
+	_, err := ParsePageRequest(r, testSortColumns, "name")
+	require.Error(t, err)
+}
+
+func TestEscapeLike(t *testing.T) {
+	require.Equal(t, `50\% \_off\\`, EscapeLike(`50% _off\`))
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows a fixed number of events per key within a sliding window.
+// The state is kept in memory, so the limits apply per server instance.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[string][]time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		events: make(map[string][]time.Time),
+	}
+}
+
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := make([]time.Time, 0, l.limit)
+	for _, t := range l.events[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.events[key] = recent
+		return false
+	}
+
+	l.events[key] = append(recent, now)
+	return true
+}
@@ -0,0 +1,106 @@
+// Package routecheck contains checks for tests, which are run against every
+// route of a router.
+package routecheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// password hashes, token hashes and the plain passwords must never be part of a response
+var sensitiveField = regexp.MustCompile(`(?i)^password$|hash`)
+
+var pathVariable = regexp.MustCompile(`\{[^}]+\}`)
+
+// FindSensitiveFields returns the paths of all fields of the json document
+// which are named password or contain hash.
+func FindSensitiveFields(body []byte) []string {
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil
+	}
+
+	return findSensitiveFields(document, "")
+}
+
+func findSensitiveFields(value any, path string) []string {
+	var found []string
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := strings.TrimPrefix(path+"."+key, ".")
+			if sensitiveField.MatchString(key) {
+				found = append(found, childPath)
+			}
+			found = append(found, findSensitiveFields(child, childPath)...)
+		}
+	case []any:
+		for i, child := range v {
+			found = append(found, findSensitiveFields(child, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return found
+}
+
+// CheckResponses sends a request with the given header and an empty json object
+// as body to every route of the router. Path variables are filled with random ids.
+// The test fails if a response contains a sensitive field or if a route panics,
+// e.g. because it calls a store method which isn't mocked by the test.
+func CheckResponses(t *testing.T, router *mux.Router, header http.Header) {
+	t.Helper()
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+
+		path := pathVariable.ReplaceAllStringFunc(template, func(string) string { return uuid.NewString() })
+		for _, method := range methods {
+			rec, panicked := serve(router, method, path, header)
+			if panicked != nil {
+				t.Errorf("%s %s panicked: %v", method, template, panicked)
+				continue
+			}
+
+			for _, field := range FindSensitiveFields(rec.Body.Bytes()) {
+				t.Errorf("%s %s returned the sensitive field %s", method, template, field)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serve(router *mux.Router, method, path string, header http.Header) (rec *httptest.ResponseRecorder, panicked any) {
+	defer func() {
+		panicked = recover()
+	}()
+
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec, nil
+}
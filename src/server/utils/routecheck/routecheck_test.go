@@ -0,0 +1,23 @@
+package routecheck
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSensitiveFields(t *testing.T) {
+	body := []byte(`{
+		"id": "1",
+		"mustChangePassword": true,
+		"password": "secret",
+		"tokens": [{"name": "script", "tokenHash": "abc"}],
+		"user": {"passwordHash": "abc"}
+	}`)
+
+	found := FindSensitiveFields(body)
+	sort.Strings(found)
+
+	require.Equal(t, []string{"password", "tokens[0].tokenHash", "user.passwordHash"}, found)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -41,6 +43,17 @@ func ValidatePayload(w http.ResponseWriter, payload any) bool {
 	return true
 }
 
+// ClientIP returns the address of the remote peer. Forwarding headers are ignored
+// on purpose, because they can be set by any client.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
 func IsValidUUID(u string) bool {
 	_, err := uuid.Parse(u)
 	return err == nil
@@ -48,7 +61,28 @@ func IsValidUUID(u string) bool {
 
 type TransactionFunc func(tx *sql.Tx) error
 
-func WithTransaction(ctx context.Context, db *sql.DB, w http.ResponseWriter, fn TransactionFunc) {
+// StatusError can be returned by a TransactionFunc to roll back and answer with
+// the status instead of an internal server error
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func NewStatusError(status int, err error) *StatusError {
+	return &StatusError{Status: status, Err: err}
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// WithTransaction runs fn in a transaction and reports whether it was committed.
+// Side effects which can't be rolled back, like mails, are done after the commit.
+func WithTransaction(ctx context.Context, db *sql.DB, w http.ResponseWriter, fn TransactionFunc) (committed bool) {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, err)
@@ -62,16 +96,24 @@ func WithTransaction(ctx context.Context, db *sql.DB, w http.ResponseWriter, fn
 			panic(p)
 		} else if err != nil {
 			tx.Rollback()
-			WriteError(w, http.StatusInternalServerError, err)
+			status := http.StatusInternalServerError
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) {
+				status = statusErr.Status
+			}
+			WriteError(w, status, err)
 		} else {
 			err = tx.Commit()
 			if err != nil {
 				WriteError(w, http.StatusInternalServerError, err)
+				return
 			}
+			committed = true
 		}
 	}()
 
 	err = fn(tx)
+	return
 }
 
 func Exec(execable interface{}, query string, args ...interface{}) (sql.Result, error) {
@@ -84,3 +126,14 @@ func Exec(execable interface{}, query string, args ...interface{}) (sql.Result,
 		return nil, fmt.Errorf("unsupported execable type")
 	}
 }
+
+func Query(execable interface{}, query string, args ...interface{}) (*sql.Rows, error) {
+	switch e := execable.(type) {
+	case *sql.Tx:
+		return e.Query(query, args...)
+	case *sql.DB:
+		return e.Query(query, args...)
+	default:
+		return nil, fmt.Errorf("unsupported execable type")
+	}
+}